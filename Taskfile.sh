@@ -15,4 +15,15 @@ function initDatabase {
     (helm install db ./db --namespace=judge --create-namespace && echo -e "Database deployed.\nBe patient while the containers are being created.") || echo "Database creation failed."
 }
 
+function test {
+    echo "Testing Judge..."
+    (cd api && go vet ./... && go test ./...)
+}
+
+function fuzz {
+    # Malformed testcase archives, ./Taskfile.sh fuzz 100000 7 for a longer run from another seed
+    echo "Fuzzing testcase archives..."
+    (cd api && go test ./internal/api -run TestMalformedArchives -archive.mutations "${1:-20000}" -archive.seed "${2:-26}")
+}
+
 ${@:-default}
\ No newline at end of file
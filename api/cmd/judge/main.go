@@ -1,10 +1,95 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
 	"judge-two/internal/api"
+	"judge-two/internal/config"
 )
 
 func main() {
-	judgeAPI := api.StartAPI()
-	judgeAPI.Run()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		os.Exit(worker(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		os.Exit(2)
+	}
+
+	judgeAPI, err := api.StartAPI(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Startup failed:", err)
+		os.Exit(1)
+	}
+	if err = judgeAPI.Run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// worker : judge worker, judges queued submissions inside the sandbox
+// until SIGINT or SIGTERM
+func worker(args []string) int {
+	cfg, err := config.LoadCommand("judge worker", args, nil)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		return 2
+	}
+
+	judgeWorker, err := api.StartWorker(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Startup failed:", err)
+		return 1
+	}
+	if err = judgeWorker.RunWorker(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// migrate : judge migrate [-dry-run], applies the pending schema
+// migrations and prints the status of each
+func migrate(args []string) int {
+	var dryRun bool
+	cfg, err := config.LoadCommand("judge migrate", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&dryRun, "dry-run", false, "Only list the pending migrations")
+	})
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		return 2
+	}
+
+	statuses, err := api.Migrate(cfg, dryRun)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	w.Flush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Migration failed:", err)
+		return 1
+	}
+	return 0
 }
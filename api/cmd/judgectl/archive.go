@@ -0,0 +1,75 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"judge-two/pkg/client"
+)
+
+// openTestcases : Archive to upload from path. Archives are sent as they
+// are and folders are zipped on the fly, keeping their input/ and output/
+// layout and any manifest.json. The caller closes the archive.
+func openTestcases(path string) (client.Archive, io.Closer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return client.Archive{}, nil, err
+	}
+
+	if !info.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return client.Archive{}, nil, err
+		}
+		return client.Archive{Name: filepath.Base(path), Content: f}, f, nil
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(zipFolder(writer, path))
+	}()
+	return client.Archive{Name: filepath.Base(filepath.Clean(path)) + ".zip", Content: reader}, reader, nil
+}
+
+// zipFolder : Writes the files under root to w as a zip, leaving out
+// hidden files and folders such as .git
+func zipFolder(w io.Writer, root string) error {
+	zipw := zip.NewWriter(w)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := zipw.Create(filepath.ToSlash(name))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(dst, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zipw.Close()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"judge-two/internal/judge"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// Config : Server to talk to and how to print replies, read in order from
+// the defaults, the YAML file, JUDGECTL_* environment variables and flags
+type Config struct {
+	File   string `yaml:"-"`
+	Server string `yaml:"server"`
+	Token  string `yaml:"token"`
+	Output string `yaml:"output"`
+
+	// Languages used by judgectl test, keyed by file extension such as
+	// ".py", on top of the built-in ones
+	Languages map[string]judge.Language `yaml:"languages"`
+}
+
+// Output modes
+const (
+	OutputHuman = "human"
+	OutputJSON  = "json"
+)
+
+// defaultConfigFile : ~/.config/judgectl/config.yaml on Linux
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "judgectl", "config.yaml")
+}
+
+// loadConfig : Parses the global flags in args and returns the remaining
+// arguments. The default file may be missing, one given explicitly may not.
+func loadConfig(args []string) (Config, []string, error) {
+	cfg := Config{Server: "http://localhost:8080", Output: OutputHuman}
+
+	// A first pass over the flags only looks for -config
+	first := cfg
+	first.File = os.Getenv("JUDGECTL_CONFIG")
+	fs := globalFlags(&first)
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	explicit := len(first.File) > 0
+	if !explicit {
+		first.File = defaultConfigFile()
+	}
+
+	cfg.File = first.File
+	if len(cfg.File) > 0 {
+		content, err := ioutil.ReadFile(cfg.File)
+		if err == nil {
+			err = yaml.UnmarshalStrict(content, &cfg)
+		}
+		if err != nil && (explicit || !os.IsNotExist(err)) {
+			return cfg, nil, fmt.Errorf("%s: %w", cfg.File, err)
+		}
+	}
+
+	envVars := map[string]*string{
+		"JUDGECTL_SERVER": &cfg.Server,
+		"JUDGECTL_TOKEN":  &cfg.Token,
+		"JUDGECTL_OUTPUT": &cfg.Output,
+	}
+	for name, target := range envVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	fs = globalFlags(&cfg)
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	return cfg, fs.Args(), cfg.Validate()
+}
+
+func globalFlags(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("judgectl", flag.ContinueOnError)
+	fs.StringVar(&cfg.File, "config", cfg.File, "YAML configuration file")
+	fs.StringVar(&cfg.Server, "server", cfg.Server, "URL of the judge API")
+	fs.StringVar(&cfg.Token, "token", cfg.Token, "Admin token of the judge API")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "Output mode, human or json")
+	fs.Usage = usage(fs)
+	return fs
+}
+
+var serverURL = regexp.MustCompile(`^https?://`)
+
+// Validate : Checks the configuration before any request is sent
+func (c Config) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Server, validation.Required, validation.Match(serverURL)),
+		validation.Field(&c.Output, validation.Required, validation.In(OutputHuman, OutputJSON)),
+	)
+}
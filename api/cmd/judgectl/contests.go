@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+
+	"judge-two/pkg/client"
+)
+
+// contestFlags : Fields of a contest given as flags
+type contestFlags struct {
+	name      string
+	questions string
+	languages string
+}
+
+func (c *contestFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&c.name, "name", "", "Name of the contest")
+	fs.StringVar(&c.questions, "questions", "", "Comma-separated IDs of the questions of the contest")
+	fs.StringVar(&c.languages, "languages", "", "Comma-separated IDs of the only languages accepted, empty accepts every language")
+}
+
+// patch : Fields whose flag was set on the command line
+func (c *contestFlags) patch(fs *flag.FlagSet) (client.ContestPatch, bool) {
+	var patch client.ContestPatch
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			patch.Name = &c.name
+		case "questions":
+			questions := splitList(c.questions)
+			patch.Questions = &questions
+		case "languages":
+			languages := splitList(c.languages)
+			patch.Languages = &languages
+		default:
+			return
+		}
+		changed = true
+	})
+	return patch, changed
+}
+
+func init() {
+	commands = append(commands,
+		&command{
+			group: "contest", name: "list",
+			summary: "List every contest",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					contests, err := app.client.ListContests(app.ctx)
+					if err != nil {
+						return err
+					}
+					app.out.print(contests, func() { app.out.contests(contests) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "contest", name: "get", args: "<id>",
+			summary: "Show the questions and languages of a contest",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					contest, err := app.client.GetContest(app.ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.out.print(contest, func() { app.out.contest(contest) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "contest", name: "create",
+			summary: "Add a contest of the given questions, limited to the given languages",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var fields contestFlags
+				fields.define(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					contest, err := app.client.CreateContest(app.ctx, client.ContestInput{
+						Name:      fields.name,
+						Questions: splitList(fields.questions),
+						Languages: splitList(fields.languages),
+					})
+					if err != nil {
+						return err
+					}
+					app.out.print(contest, func() {
+						app.out.message("Created contest %s\n", contest.ID)
+						app.out.contest(contest)
+					})
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "contest", name: "update", args: "<id>",
+			summary: "Change the name, questions or languages of a contest",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var fields contestFlags
+				fields.define(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					patch, changed := fields.patch(fs)
+					if !changed {
+						fs.Usage()
+						return errUsage
+					}
+					contest, err := app.client.UpdateContest(app.ctx, args[0], patch)
+					if err != nil {
+						return err
+					}
+					app.out.print(contest, func() { app.out.contest(contest) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "contest", name: "delete", args: "<id>",
+			summary: "Delete a contest for good, refused once submissions were made in it",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					if err = app.client.DeleteContest(app.ctx, args[0]); err != nil {
+						return err
+					}
+					app.out.print(map[string]string{"deleted": args[0]}, func() { app.out.message("Deleted contest %s", args[0]) })
+					return nil
+				}
+			},
+		},
+	)
+}
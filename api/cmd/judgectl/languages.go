@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"judge-two/pkg/client"
+)
+
+// languageFlags : Fields of a language given as flags, commands are split
+// on spaces and never go through a shell
+type languageFlags struct {
+	file                             string
+	name, filename, compile, execute string
+	artifacts                        string
+	time                             int
+	limits                           client.LanguageLimits
+	probe, version                   string
+	disabled, archived               bool
+}
+
+func (l *languageFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&l.file, "f", "", "JSON file holding the language, flags override its fields")
+	fs.StringVar(&l.name, "name", "", "Name of the language, such as \"Python 3\"")
+	fs.IntVar(&l.time, "time", 0, "Time multiplier applied to the time limit of questions")
+	fs.StringVar(&l.filename, "filename", "", "Name the source file is saved under, such as main.py")
+	fs.StringVar(&l.compile, "compile", "", "Command compiling the source, such as \"g++ -O2 -o {binary} {src}\", empty for none")
+	fs.StringVar(&l.execute, "execute", "", "Command running the solution, such as {binary}")
+	fs.StringVar(&l.artifacts, "artifacts", "", "Comma separated files or patterns carried from the compile step to the run step")
+	fs.IntVar(&l.limits.CompileTime, "compile-time", 0, "Time limit of the compile step in seconds")
+	fs.IntVar(&l.limits.CompileMemory, "compile-memory", 0, "Memory limit of the compile step in megabytes")
+	fs.IntVar(&l.limits.Memory, "memory", 0, "Memory limit of the run step in megabytes, given to commands as {memlimit}")
+	fs.IntVar(&l.limits.Output, "output", 0, "Output limit of the run step in megabytes")
+	fs.StringVar(&l.probe, "probe", "", "File of a program printing back its input, run to self-test the language")
+	fs.StringVar(&l.version, "version", "", "Command printing the version of the compiler, recorded by the self-test")
+	fs.BoolVar(&l.disabled, "disabled", false, "Refuse submissions in the language")
+}
+
+// defineArchived : Flag hiding the language from contestants, only edits
+// take it
+func (l *languageFlags) defineArchived(fs *flag.FlagSet) {
+	fs.BoolVar(&l.archived, "archived", false, "Hide the language from contestants, -archived=false shows it again")
+}
+
+// readProbe : Probe made of the source in the -probe file, nil without one
+func (l *languageFlags) readProbe() (*client.LanguageProbe, error) {
+	if len(l.probe) <= 0 {
+		return nil, nil
+	}
+	source, err := ioutil.ReadFile(l.probe)
+	if err != nil {
+		return nil, err
+	}
+	return &client.LanguageProbe{Source: string(source), Version: strings.Fields(l.version)}, nil
+}
+
+// splitList : Items of a comma separated list
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// input : Language read from the -f file with the flags set on the
+// command line on top
+func (l *languageFlags) input(fs *flag.FlagSet) (client.LanguageInput, error) {
+	var input client.LanguageInput
+	if len(l.file) > 0 {
+		content, err := ioutil.ReadFile(l.file)
+		if err == nil {
+			err = json.Unmarshal(content, &input)
+		}
+		if err != nil {
+			return input, fmt.Errorf("%s: %w", l.file, err)
+		}
+	}
+
+	patch, err := l.patch(fs)
+	if err != nil {
+		return input, err
+	}
+	if patch.Name != nil {
+		input.Name = *patch.Name
+	}
+	if patch.Time != nil {
+		input.Time = *patch.Time
+	}
+	if patch.Filename != nil {
+		input.Filename = *patch.Filename
+	}
+	if patch.Compile != nil {
+		input.Compile = patch.Compile
+		if len(patch.Compile.Argv) <= 0 {
+			input.Compile = nil
+		}
+	}
+	if patch.Execute != nil {
+		input.Execute = *patch.Execute
+	}
+	if patch.Artifacts != nil {
+		input.Artifacts = *patch.Artifacts
+	}
+	if patch.Limits != nil {
+		input.Limits = *patch.Limits
+	}
+	if patch.Probe != nil {
+		input.Probe = patch.Probe
+	}
+	if patch.Disabled != nil {
+		input.Disabled = *patch.Disabled
+	}
+	return input, nil
+}
+
+// patch : Fields whose flag was set on the command line
+func (l *languageFlags) patch(fs *flag.FlagSet) (client.LanguagePatch, error) {
+	var patch client.LanguagePatch
+	probe, err := l.readProbe()
+	if err != nil {
+		return patch, err
+	}
+	patch.Probe = probe
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			patch.Name = &l.name
+		case "time":
+			patch.Time = &l.time
+		case "filename":
+			patch.Filename = &l.filename
+		case "compile":
+			patch.Compile = &client.Command{Argv: strings.Fields(l.compile)}
+		case "execute":
+			patch.Execute = &client.Command{Argv: strings.Fields(l.execute)}
+		case "artifacts":
+			artifacts := splitList(l.artifacts)
+			patch.Artifacts = &artifacts
+		case "compile-time", "compile-memory", "memory", "output":
+			patch.Limits = &l.limits
+		case "disabled":
+			patch.Disabled = &l.disabled
+		case "archived":
+			patch.Archived = &l.archived
+		}
+	})
+	return patch, nil
+}
+
+func init() {
+	commands = append(commands,
+		&command{
+			group: "language", name: "list",
+			summary: "List every language",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				deleted := fs.Bool("deleted", false, "List the deleted languages instead, which can be restored")
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					list := app.client.ListLanguages
+					if *deleted {
+						list = app.client.ListDeletedLanguages
+					}
+					languages, err := list(app.ctx)
+					if err != nil {
+						return err
+					}
+					app.out.print(languages, func() { app.out.languages(languages) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "language", name: "get", args: "<id>",
+			summary: "Show a language",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					language, err := app.client.GetLanguage(app.ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.out.print(language, func() { app.out.language(language) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "language", name: "create",
+			summary: "Add a language from flags or a JSON file",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var fields languageFlags
+				fields.define(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					input, err := fields.input(fs)
+					if err != nil {
+						return err
+					}
+					language, err := app.client.CreateLanguage(app.ctx, input)
+					if err != nil {
+						return err
+					}
+					app.out.print(language, func() {
+						app.out.message("Created language %s\n", language.ID)
+						app.out.language(language)
+					})
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "language", name: "update", args: "<id>",
+			summary: "Change the fields of a language given as flags",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var fields languageFlags
+				fields.define(fs)
+				fields.defineArchived(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					patch, err := fields.patch(fs)
+					if err != nil {
+						return err
+					}
+					language, err := app.client.UpdateLanguage(app.ctx, args[0], patch)
+					if err != nil {
+						return err
+					}
+					app.out.print(language, func() { app.out.language(language) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "language", name: "self-test", args: "<id>",
+			summary: "Have the workers run the probe of a language again, those it fails on stop taking it",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					language, err := app.client.SelfTestLanguage(app.ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.out.print(language, func() { app.out.language(language) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "language", name: "delete", args: "<id>",
+			summary: "Delete a language, it can be restored unless deleted with -hard",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				hard := fs.Bool("hard", false, "Delete for good, refused while submissions or contests reference the language")
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					remove := app.client.DeleteLanguage
+					if *hard {
+						remove = app.client.PurgeLanguage
+					}
+					if err = remove(app.ctx, args[0]); err != nil {
+						return err
+					}
+					app.out.print(map[string]string{"deleted": args[0]}, func() { app.out.message("Deleted language %s", args[0]) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "language", name: "restore", args: "<id>",
+			summary: "Bring back a deleted language",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					language, err := app.client.RestoreLanguage(app.ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.out.print(language, func() { app.out.language(language) })
+					return nil
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,166 @@
+// Command judgectl : Manages the languages, questions, submissions and
+// rejudges of a judge server over its v2 API. The server and token are
+// read from ~/.config/judgectl/config.yaml, such as
+//
+//	server: https://judge.example.com
+//	token: secret
+//	output: human
+//
+// then from JUDGECTL_SERVER, JUDGECTL_TOKEN and JUDGECTL_OUTPUT, then from
+// the flags given before the command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"judge-two/pkg/client"
+)
+
+// command : Action of judgectl, such as "language create"
+type command struct {
+	group   string
+	name    string
+	args    string
+	summary string
+
+	// setup : Defines the flags of the command and returns what runs it
+	// once they are parsed
+	setup func(fs *flag.FlagSet) func(app *app, fs *flag.FlagSet) error
+}
+
+// commands : Every action, registered by the file implementing it
+var commands []*command
+
+// app : What every command needs
+type app struct {
+	ctx    context.Context
+	cfg    Config
+	client *client.Client
+	out    *printer
+}
+
+// errUsage : Wrong arguments, the usage of the command was printed
+var errUsage = errors.New("invalid usage")
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "Usage: judgectl [flags] <command> [command flags] [arguments]")
+		fmt.Fprintln(out, "\nCommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(out, "  %-46s %s\n", cmd.title()+" "+cmd.args, cmd.summary)
+		}
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+}
+
+// findCommand : Command named by the first one or two arguments, along
+// with the arguments left for it
+func findCommand(args []string) (*command, []string) {
+	for _, cmd := range commands {
+		switch {
+		case len(args) < 1 || cmd.group != args[0]:
+		case cmd.name == "":
+			return cmd, args[1:]
+		case len(args) >= 2 && cmd.name == args[1]:
+			return cmd, args[2:]
+		}
+	}
+	return nil, nil
+}
+
+// title : Words naming the command
+func (cmd *command) title() string {
+	return strings.TrimSpace(cmd.group + " " + cmd.name)
+}
+
+// commandFlags : Flags of cmd, with a usage naming the command, and what
+// runs it
+func commandFlags(cmd *command) (*flag.FlagSet, func(app *app, fs *flag.FlagSet) error) {
+	fs := flag.NewFlagSet(cmd.title(), flag.ContinueOnError)
+	runCommand := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: judgectl %s [flags] %s\n\n%s\n", cmd.title(), cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs, runCommand
+}
+
+func run(args []string) error {
+	cfg, args, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+
+	cmd, cmdArgs := findCommand(args)
+	if cmd == nil {
+		if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "judgectl: unknown command %q\n", strings.Join(args, " "))
+		}
+		usage(globalFlags(&cfg))()
+		return errUsage
+	}
+	fs, runCommand := commandFlags(cmd)
+	if err = fs.Parse(cmdArgs); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		stop()
+	}()
+
+	out := newPrinter(cfg.Output, os.Stdout)
+	err = runCommand(&app{
+		ctx:    ctx,
+		cfg:    cfg,
+		client: client.New(cfg.Server, client.WithToken(cfg.Token)),
+		out:    out,
+	}, fs)
+
+	// Scripts reading JSON get the error of the server as well
+	var apiErr *client.Error
+	if cfg.Output == OutputJSON && errors.As(err, &apiErr) {
+		out.json(map[string]*client.Error{"error": apiErr})
+	}
+	return err
+}
+
+// positional : Arguments left after the flags of the command, exactly n
+func positional(fs *flag.FlagSet, n int) ([]string, error) {
+	if fs.NArg() != n {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+func main() {
+	err := run(os.Args[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "judgectl:", err)
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.Report != nil {
+			newPrinter(OutputHuman, os.Stderr).report(apiErr.Report)
+		}
+		os.Exit(1)
+	}
+}
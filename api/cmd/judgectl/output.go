@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"judge-two/pkg/client"
+)
+
+// printer : Writes replies as tables for people or as JSON for scripts
+type printer struct {
+	mode string
+	w    io.Writer
+}
+
+func newPrinter(mode string, w io.Writer) *printer {
+	return &printer{mode: mode, w: w}
+}
+
+// json : Writes value as indented JSON whatever the mode
+func (p *printer) json(value interface{}) {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// print : Writes value as JSON, or calls human in human mode
+func (p *printer) print(value interface{}, human func()) {
+	if p.mode == OutputJSON {
+		p.json(value)
+		return
+	}
+	human()
+}
+
+// table : Writes rows under the header with aligned columns
+func (p *printer) table(header []string, rows [][]string) {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// message : Line for people, left out of JSON output
+func (p *printer) message(format string, args ...interface{}) {
+	if p.mode != OutputJSON {
+		fmt.Fprintf(p.w, format+"\n", args...)
+	}
+}
+
+func (p *printer) languages(languages []client.Language) {
+	rows := make([][]string, len(languages))
+	for i, language := range languages {
+		rows[i] = []string{language.ID, language.Name, fmt.Sprint(language.Time), language.Filename, languageStatus(language)}
+	}
+	p.table([]string{"ID", "NAME", "TIME", "FILENAME", "STATUS"}, rows)
+}
+
+// languageStatus : Whether the language takes submissions and how its
+// last self-test went
+func languageStatus(language client.Language) string {
+	status := "enabled"
+	if language.Disabled {
+		status = "disabled"
+	}
+	switch {
+	case language.SelfTest != nil:
+		status += ", self-test " + language.SelfTest.Verdict
+	case language.Probe != nil:
+		status += ", self-test pending"
+	}
+	return status + lifecycle(language.Archived, language.DeletedAt)
+}
+
+// lifecycle : Whether an item is archived or deleted, empty for neither
+func lifecycle(archived bool, deletedAt *time.Time) string {
+	switch {
+	case deletedAt != nil:
+		return ", deleted " + deletedAt.Local().Format(time.RFC3339)
+	case archived:
+		return ", archived"
+	}
+	return ""
+}
+
+// commandLine : Arguments of the command joined by spaces, "none" without
+// a command
+func commandLine(command *client.Command) string {
+	if command == nil || len(command.Argv) <= 0 {
+		return "none"
+	}
+	var env []string
+	for name, value := range command.Env {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return strings.Join(append(env, command.Argv...), " ")
+}
+
+// languageLimits : Limits of the language, "default" for those left unset
+func languageLimits(limits client.LanguageLimits) string {
+	limit := func(value int, unit string) string {
+		if value <= 0 {
+			return "default"
+		}
+		return fmt.Sprintf("%d%s", value, unit)
+	}
+	return fmt.Sprintf("compile %s / %s, run memory %s, output %s",
+		limit(limits.CompileTime, "s"), limit(limits.CompileMemory, "MB"), limit(limits.Memory, "MB"), limit(limits.Output, "MB"))
+}
+
+func (p *printer) language(language client.Language) {
+	rows := [][]string{
+		{"id", language.ID},
+		{"name", language.Name},
+		{"time", fmt.Sprint(language.Time)},
+		{"filename", language.Filename},
+		{"compile", commandLine(language.Compile)},
+		{"execute", commandLine(&language.Execute)},
+		{"artifacts", strings.Join(language.Artifacts, ", ")},
+		{"limits", languageLimits(language.Limits)},
+		{"status", languageStatus(language)},
+	}
+	if test := language.SelfTest; test != nil {
+		rows = append(rows,
+			[]string{"version", test.Version},
+			[]string{"checked at", test.CheckedAt.Local().Format(time.RFC3339)},
+		)
+	}
+	p.table([]string{"FIELD", "VALUE"}, rows)
+	if test := language.SelfTest; test != nil && len(test.Output) > 0 {
+		fmt.Fprintf(p.w, "\n%s\n", strings.TrimRight(test.Output, "\n"))
+	}
+}
+
+func (p *printer) questions(questions []client.Question) {
+	rows := make([][]string, len(questions))
+	for i, question := range questions {
+		status := strings.TrimPrefix(lifecycle(question.Archived, question.DeletedAt), ", ")
+		if status == "" {
+			status = "active"
+		}
+		rows[i] = []string{question.ID, question.Name, fmt.Sprint(question.Time), fmt.Sprint(question.NumTestcases), status}
+	}
+	p.table([]string{"ID", "NAME", "TIME", "TESTCASES", "STATUS"}, rows)
+}
+
+func (p *printer) question(question client.Question) {
+	fmt.Fprintf(p.w, "%s  %s  (%ds)\n", question.ID, question.Name, question.Time)
+	if len(question.Languages) > 0 {
+		fmt.Fprintf(p.w, "languages: %s\n", strings.Join(question.Languages, ", "))
+	}
+	if len(question.TimeOverrides) > 0 {
+		overrides := make([]string, len(question.TimeOverrides))
+		for i, override := range question.TimeOverrides {
+			overrides[i] = fmt.Sprintf("%s=%ds", override.LanguageID, override.Time)
+		}
+		fmt.Fprintf(p.w, "time overrides: %s\n", strings.Join(overrides, ", "))
+	}
+	fmt.Fprintln(p.w)
+	p.testcases(question.Testcases)
+}
+
+func (p *printer) contests(contests []client.Contest) {
+	rows := make([][]string, len(contests))
+	for i, contest := range contests {
+		languages := "all"
+		if len(contest.Languages) > 0 {
+			languages = fmt.Sprint(len(contest.Languages))
+		}
+		rows[i] = []string{contest.ID, contest.Name, fmt.Sprint(len(contest.Questions)), languages}
+	}
+	p.table([]string{"ID", "NAME", "QUESTIONS", "LANGUAGES"}, rows)
+}
+
+func (p *printer) contest(contest client.Contest) {
+	fmt.Fprintf(p.w, "%s  %s\n", contest.ID, contest.Name)
+	fmt.Fprintf(p.w, "questions: %s\n", strings.Join(contest.Questions, ", "))
+	if len(contest.Languages) > 0 {
+		fmt.Fprintf(p.w, "languages: %s\n", strings.Join(contest.Languages, ", "))
+	}
+}
+
+func (p *printer) questionLanguages(languages []client.QuestionLanguage) {
+	rows := make([][]string, len(languages))
+	for i, language := range languages {
+		limit := fmt.Sprintf("%ds", language.Time)
+		if language.Override {
+			limit += " (override)"
+		}
+		rows[i] = []string{language.ID, language.Name, language.Filename, limit}
+	}
+	p.table([]string{"ID", "NAME", "FILENAME", "TIME"}, rows)
+}
+
+func (p *printer) testcases(testcases []client.Testcase) {
+	rows := make([][]string, len(testcases))
+	for i, testcase := range testcases {
+		rows[i] = []string{fmt.Sprint(testcase.Index), fmt.Sprint(testcase.Number), testcase.Name, testcase.Description}
+	}
+	p.table([]string{"INDEX", "NUMBER", "NAME", "DESCRIPTION"}, rows)
+}
+
+// report : Outcome of validating an archive, with every issue found
+func (p *printer) report(report *client.ArchiveReport) {
+	if report == nil {
+		return
+	}
+	state := "valid"
+	if !report.Valid {
+		state = "invalid"
+	}
+	fmt.Fprintf(p.w, "Archive %s: %d testcases, %s layout\n", state, report.Testcases, report.Layout)
+	for _, issue := range report.Errors {
+		fmt.Fprintf(p.w, "  error    %s: %s\n", issue.Entry, issue.Reason)
+	}
+	for _, issue := range report.Warnings {
+		fmt.Fprintf(p.w, "  warning  %s: %s\n", issue.Entry, issue.Reason)
+	}
+}
+
+func (p *printer) workers(workers client.Workers) {
+	rows := make([][]string, len(workers.Workers))
+	for i, worker := range workers.Workers {
+		rows[i] = []string{worker.ID, worker.Host, fmt.Sprint(worker.Slots), fmt.Sprint(len(worker.Languages)),
+			fmt.Sprint(len(worker.Failed)), worker.HeartbeatAt.Local().Format(time.RFC3339)}
+	}
+	p.table([]string{"ID", "HOST", "SLOTS", "LANGUAGES", "FAILED", "HEARTBEAT"}, rows)
+
+	rows = make([][]string, len(workers.Languages))
+	for i, language := range workers.Languages {
+		rows[i] = []string{language.ID, language.Name, fmt.Sprint(len(language.Workers)), strings.Join(language.Workers, ", ")}
+	}
+	fmt.Fprintln(p.w)
+	p.table([]string{"LANGUAGE", "NAME", "WORKERS", "WORKER IDS"}, rows)
+
+	if len(workers.Uncovered) > 0 {
+		fmt.Fprintf(p.w, "\n%d languages have no capable worker, their submissions wait in the queue:\n", len(workers.Uncovered))
+		p.coverage(workers.Uncovered, len(workers.Workers))
+	}
+}
+
+// coverage : Languages no live worker can run and why
+func (p *printer) coverage(languages []client.LanguageCoverage, live int) {
+	rows := make([][]string, len(languages))
+	for i, language := range languages {
+		status := "enabled"
+		if language.Disabled {
+			status = "disabled"
+		}
+		rows[i] = []string{language.ID, language.Name, status, uncoveredReason(language, live)}
+	}
+	p.table([]string{"ID", "NAME", "STATUS", "REASON"}, rows)
+}
+
+func (p *printer) submission(submission client.Submission) {
+	fmt.Fprintf(p.w, "Submission %s: %s\n", submission.ID, submission.Verdict)
+	if len(submission.Results) <= 0 {
+		return
+	}
+	rows := make([][]string, len(submission.Results))
+	for i, result := range submission.Results {
+		rows[i] = []string{fmt.Sprint(result.Index), fmt.Sprint(result.Number), result.Verdict, fmt.Sprintf("%d ms", result.Time)}
+	}
+	fmt.Fprintln(p.w)
+	p.table([]string{"INDEX", "NUMBER", "VERDICT", "TIME"}, rows)
+}
+
+func (p *printer) rejudge(status client.RejudgeStatus) {
+	fmt.Fprintf(p.w, "Rejudge %s: %d submissions, %d pending, %d unchanged, %d changed\n",
+		status.Rejudge.ID, len(status.Rejudge.Submissions), status.Pending, status.Unchanged, len(status.Changed))
+	if len(status.Changed) <= 0 {
+		return
+	}
+	rows := make([][]string, len(status.Changed))
+	for i, change := range status.Changed {
+		rows[i] = []string{change.SubmissionID, change.Before, change.After}
+	}
+	fmt.Fprintln(p.w)
+	p.table([]string{"SUBMISSION", "BEFORE", "AFTER"}, rows)
+}
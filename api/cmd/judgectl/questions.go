@@ -0,0 +1,372 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"judge-two/pkg/client"
+)
+
+// questionFlags : Fields of a question given as flags
+type questionFlags struct {
+	name     string
+	time     int
+	archived bool
+	dryRun   bool
+
+	languages     string
+	timeOverrides string
+}
+
+func (q *questionFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&q.name, "name", "", "Name of the question")
+	fs.IntVar(&q.time, "time", 0, "Time limit in seconds")
+	fs.BoolVar(&q.dryRun, "dry-run", false, "Only validate the testcases and print the report")
+}
+
+// defineArchived : Flag hiding the question from contestants, only edits
+// take it
+func (q *questionFlags) defineArchived(fs *flag.FlagSet) {
+	fs.BoolVar(&q.archived, "archived", false, "Hide the question from contestants, -archived=false shows it again")
+}
+
+// defineLanguages : Flags restricting the languages of the question and
+// overriding their time limits, only edits take them
+func (q *questionFlags) defineLanguages(fs *flag.FlagSet) {
+	fs.StringVar(&q.languages, "languages", "", "Comma-separated IDs of the only languages accepted, empty accepts every language")
+	fs.StringVar(&q.timeOverrides, "time-override", "", "Comma-separated language-id=seconds time limits replacing the computed ones, empty removes them")
+}
+
+// parseTimeOverrides : Overrides given as id=seconds pairs
+func parseTimeOverrides(list string) ([]client.TimeOverride, error) {
+	overrides := []client.TimeOverride{}
+	for _, item := range splitList(list) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("time override %q is not language-id=seconds", item)
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("time override %q is not language-id=seconds", item)
+		}
+		overrides = append(overrides, client.TimeOverride{LanguageID: strings.TrimSpace(parts[0]), Time: seconds})
+	}
+	return overrides, nil
+}
+
+// patch : Fields whose flag was set on the command line
+func (q *questionFlags) patch(fs *flag.FlagSet) (client.QuestionPatch, bool, error) {
+	var patch client.QuestionPatch
+	var err error
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "languages":
+			languages := splitList(q.languages)
+			patch.Languages = &languages
+			changed = true
+		case "time-override":
+			var overrides []client.TimeOverride
+			if overrides, err = parseTimeOverrides(q.timeOverrides); err == nil {
+				patch.TimeOverrides = &overrides
+			}
+			changed = true
+		case "name":
+			patch.Name = &q.name
+			changed = true
+		case "time":
+			patch.Time = &q.time
+			changed = true
+		case "archived":
+			patch.Archived = &q.archived
+			changed = true
+		}
+	})
+	return patch, changed, err
+}
+
+// saveDownload : Copies body to the file at target, removing it when the
+// copy fails
+func saveDownload(target string, body io.ReadCloser) error {
+	defer body.Close()
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, body); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(target)
+	}
+	return err
+}
+
+func init() {
+	commands = append(commands,
+		&command{
+			group: "question", name: "list",
+			summary: "List every question",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				deleted := fs.Bool("deleted", false, "List the deleted questions instead, which can be restored")
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					list := app.client.ListQuestions
+					if *deleted {
+						list = app.client.ListDeletedQuestions
+					}
+					questions, err := list(app.ctx)
+					if err != nil {
+						return err
+					}
+					app.out.print(questions, func() { app.out.questions(questions) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "get", args: "<id>",
+			summary: "Show a question and its testcases",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					question, err := app.client.GetQuestion(app.ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.out.print(question, func() { app.out.question(question) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "languages", args: "<id> [language id]",
+			summary: "List the languages a question accepts, or check a single one",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				contest := fs.String("contest", "", "ID of the contest whose languages apply too")
+				return func(app *app, fs *flag.FlagSet) error {
+					if fs.NArg() == 2 {
+						language, err := app.client.CheckQuestionLanguage(app.ctx, fs.Arg(0), fs.Arg(1), *contest)
+						if err != nil {
+							return err
+						}
+						app.out.print(language, func() { app.out.questionLanguages([]client.QuestionLanguage{language}) })
+						return nil
+					}
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					languages, err := app.client.QuestionLanguages(app.ctx, args[0], *contest)
+					if err != nil {
+						return err
+					}
+					app.out.print(languages, func() { app.out.questionLanguages(languages) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "create", args: "<folder or archive>",
+			summary: "Create a question from a testcase folder, zipped automatically, or archive",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var fields questionFlags
+				fields.define(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					archive, closer, err := openTestcases(args[0])
+					if err != nil {
+						return err
+					}
+					defer closer.Close()
+
+					question, report, err := app.client.CreateQuestion(app.ctx, client.NewQuestion{
+						Name:    fields.name,
+						Time:    fields.time,
+						Archive: archive,
+						DryRun:  fields.dryRun,
+					})
+					if err != nil {
+						return err
+					}
+					app.out.print(map[string]interface{}{"question": question, "report": report}, func() {
+						app.out.report(report)
+						if question != nil {
+							app.out.message("Created question %s", question.ID)
+						}
+					})
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "update", args: "<id>",
+			summary: "Change the name, time limit, languages or archival of a question, or replace its testcases",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var fields questionFlags
+				fields.define(fs)
+				fields.defineArchived(fs)
+				fields.defineLanguages(fs)
+				testcases := fs.String("testcases", "", "Testcase folder or archive replacing every testcase")
+				rejudge := fs.Bool("rejudge", false, "Rejudge the submissions of the question once its testcases change")
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					patch, changed, err := fields.patch(fs)
+					if err != nil {
+						return err
+					}
+					if !changed && len(*testcases) <= 0 {
+						fs.Usage()
+						return errUsage
+					}
+
+					var result struct {
+						Question  *client.Question      `json:"question,omitempty"`
+						Testcases []client.Testcase     `json:"testcases,omitempty"`
+						Report    *client.ArchiveReport `json:"report,omitempty"`
+					}
+					if changed && !fields.dryRun {
+						question, err := app.client.UpdateQuestion(app.ctx, args[0], patch)
+						if err != nil {
+							return err
+						}
+						result.Question = &question
+					}
+					if len(*testcases) > 0 {
+						archive, closer, err := openTestcases(*testcases)
+						if err != nil {
+							return err
+						}
+						defer closer.Close()
+						result.Testcases, result.Report, err = app.client.ReplaceTestcases(app.ctx, args[0], archive, fields.dryRun, *rejudge)
+						if err != nil {
+							return err
+						}
+					}
+
+					app.out.print(result, func() {
+						app.out.report(result.Report)
+						if result.Question != nil {
+							app.out.message("Updated question %s", result.Question.ID)
+						}
+						if result.Testcases != nil {
+							app.out.message("Replaced the testcases of question %s\n", args[0])
+							app.out.testcases(result.Testcases)
+						}
+					})
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "delete", args: "<id>",
+			summary: "Delete a question, it can be restored unless deleted with -hard",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				hard := fs.Bool("hard", false, "Delete for good, refused while submissions or contests reference the question")
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					remove := app.client.DeleteQuestion
+					if *hard {
+						remove = app.client.PurgeQuestion
+					}
+					if err = remove(app.ctx, args[0]); err != nil {
+						return err
+					}
+					app.out.print(map[string]string{"deleted": args[0]}, func() { app.out.message("Deleted question %s", args[0]) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "restore", args: "<id>",
+			summary: "Bring back a deleted question",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					question, err := app.client.RestoreQuestion(app.ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.out.print(question, func() { app.out.question(question) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "export", args: "<id>",
+			summary: "Download a question as a zip that question create takes back",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				target := fs.String("o", "", "File the zip is written to (default question-<id>.zip)")
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					if len(*target) <= 0 {
+						*target = "question-" + args[0] + ".zip"
+					}
+					body, err := app.client.ExportQuestion(app.ctx, args[0])
+					if err == nil {
+						err = saveDownload(*target, body)
+					}
+					if err != nil {
+						return err
+					}
+					app.out.print(map[string]string{"file": *target}, func() { app.out.message("Exported question %s to %s", args[0], *target) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "question", name: "testcase", args: "<id> <index> <input|output>",
+			summary: "Download a single testcase file",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				target := fs.String("o", "", "File the testcase is written to (default standard output)")
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 3)
+					if err != nil {
+						return err
+					}
+					index, err := strconv.Atoi(args[1])
+					if err != nil {
+						fs.Usage()
+						return errUsage
+					}
+					body, err := app.client.DownloadTestcase(app.ctx, args[0], index, args[2])
+					if err != nil {
+						return err
+					}
+					if len(*target) > 0 {
+						return saveDownload(*target, body)
+					}
+					defer body.Close()
+					_, err = io.Copy(os.Stdout, body)
+					return err
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"judge-two/pkg/client"
+)
+
+// waitFlags : Whether and how often to poll a rejudge until it is done
+type waitFlags struct {
+	wait     bool
+	interval time.Duration
+}
+
+func (w *waitFlags) define(fs *flag.FlagSet) {
+	fs.BoolVar(&w.wait, "wait", false, "Follow the rejudge until every submission is judged again")
+	fs.DurationVar(&w.interval, "interval", 2*time.Second, "Time between two polls while waiting")
+}
+
+// followRejudge : Status of the rejudge, polled until nothing is pending
+// when asked to wait. Progress goes to standard error in human mode.
+func (app *app) followRejudge(ID string, wait waitFlags) (client.RejudgeStatus, error) {
+	for {
+		status, err := app.client.GetRejudge(app.ctx, ID)
+		if err != nil || !wait.wait || status.Pending <= 0 {
+			return status, err
+		}
+		if app.cfg.Output == OutputHuman {
+			fmt.Fprintf(os.Stderr, "%d of %d submissions pending\n", status.Pending, len(status.Rejudge.Submissions))
+		}
+
+		select {
+		case <-app.ctx.Done():
+			return status, app.ctx.Err()
+		case <-time.After(wait.interval):
+		}
+	}
+}
+
+func init() {
+	commands = append(commands,
+		&command{
+			group: "rejudge", name: "create",
+			summary: "Send the submissions matching the flags back to the judge",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var request client.RejudgeRequest
+				var since string
+				var wait waitFlags
+				fs.StringVar(&request.SubmissionID, "submission", "", "ID of a single submission")
+				fs.StringVar(&request.QuestionID, "question", "", "ID of the question whose submissions are rejudged")
+				fs.StringVar(&request.Verdict, "verdict", "", "Only submissions with this verdict, such as WA")
+				fs.StringVar(&since, "since", "", "Only submissions made after this RFC 3339 time")
+				wait.define(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					if len(since) > 0 {
+						t, err := time.Parse(time.RFC3339, since)
+						if err != nil {
+							return fmt.Errorf("since: %w", err)
+						}
+						request.Since = &t
+					}
+
+					created, err := app.client.CreateRejudge(app.ctx, request)
+					if err != nil {
+						return err
+					}
+					if !wait.wait {
+						app.out.print(created, func() {
+							app.out.message("Started rejudge %s of %d submissions", created.ID, created.Submissions)
+						})
+						return nil
+					}
+					status, err := app.followRejudge(created.ID, wait)
+					if err != nil {
+						return err
+					}
+					app.out.print(status, func() { app.out.rejudge(status) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "rejudge", name: "status", args: "<id>",
+			summary: "Show the progress of a rejudge and the verdicts it changed",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var wait waitFlags
+				wait.define(fs)
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					status, err := app.followRejudge(args[0], wait)
+					if err != nil {
+						return err
+					}
+					app.out.print(status, func() { app.out.rejudge(status) })
+					return nil
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"judge-two/pkg/client"
+)
+
+// followSubmission : Submission, polled until a worker judged it when
+// asked to wait
+func (app *app) followSubmission(ID string, wait waitFlags) (client.Submission, error) {
+	for {
+		submission, err := app.client.GetSubmission(app.ctx, ID)
+		if err != nil || !wait.wait || submission.Verdict != client.VerdictQueued {
+			return submission, err
+		}
+
+		select {
+		case <-app.ctx.Done():
+			return submission, app.ctx.Err()
+		case <-time.After(wait.interval):
+		}
+	}
+}
+
+// defineSubmissionWait : Flags following a submission until its verdict
+func defineSubmissionWait(fs *flag.FlagSet, wait *waitFlags, follow bool) {
+	fs.BoolVar(&wait.wait, "wait", follow, "Follow the submission until a worker judged it")
+	fs.DurationVar(&wait.interval, "interval", time.Second, "Time between two polls while waiting")
+}
+
+func init() {
+	commands = append(commands,
+		&command{
+			group: "submit", args: "<question id> <language id> <file>",
+			summary: "Submit a solution and follow it until its verdict",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var wait waitFlags
+				defineSubmissionWait(fs, &wait, true)
+				contest := fs.String("contest", "", "ID of the contest the submission is made in")
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 3)
+					if err != nil {
+						return err
+					}
+					source, err := ioutil.ReadFile(args[2])
+					if err != nil {
+						return err
+					}
+
+					submission, err := app.client.CreateSubmission(app.ctx, client.SubmissionInput{
+						QuestionID: args[0],
+						LanguageID: args[1],
+						ContestID:  *contest,
+						Source:     string(source),
+					})
+					if err != nil {
+						return err
+					}
+					if wait.wait && app.cfg.Output == OutputHuman {
+						fmt.Fprintf(os.Stderr, "Submitted %s, waiting for a worker\n", submission.ID)
+					}
+					submission, err = app.followSubmission(submission.ID, wait)
+					if err != nil {
+						return err
+					}
+					app.out.print(submission, func() { app.out.submission(submission) })
+					return nil
+				}
+			},
+		},
+		&command{
+			group: "submission", name: "show", args: "<id>",
+			summary: "Show the verdict of a submission on each testcase",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				var wait waitFlags
+				defineSubmissionWait(fs, &wait, false)
+				return func(app *app, fs *flag.FlagSet) error {
+					args, err := positional(fs, 1)
+					if err != nil {
+						return err
+					}
+					submission, err := app.followSubmission(args[0], wait)
+					if err != nil {
+						return err
+					}
+					app.out.print(submission, func() { app.out.submission(submission) })
+					return nil
+				}
+			},
+		},
+	)
+}
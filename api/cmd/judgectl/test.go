@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"judge-two/internal/judge"
+)
+
+// builtinLanguages : Languages judgectl test knows without configuration,
+// keyed by file extension
+var builtinLanguages = map[string]judge.Language{
+	".c": {
+		Name: "C", Time: 1, Filename: "main.c",
+		Compile:   &judge.Command{Argv: []string{"gcc", "-O2", "-o", "{binary}", "{src}", "-lm"}},
+		Execute:   judge.Command{Argv: []string{"{binary}"}},
+		Artifacts: []string{"main"},
+	},
+	".cpp": {
+		Name: "C++", Time: 1, Filename: "main.cpp",
+		Compile:   &judge.Command{Argv: []string{"g++", "-O2", "-o", "{binary}", "{src}"}},
+		Execute:   judge.Command{Argv: []string{"{binary}"}},
+		Artifacts: []string{"main"},
+	},
+	".go": {
+		Name: "Go", Time: 1, Filename: "main.go",
+		Compile:   &judge.Command{Argv: []string{"go", "build", "-o", "{binary}", "{src}"}},
+		Execute:   judge.Command{Argv: []string{"{binary}"}},
+		Artifacts: []string{"main"},
+	},
+	".java": {
+		Name: "Java", Time: 2, Filename: "Main.java",
+		Compile:   &judge.Command{Argv: []string{"javac", "{src}"}},
+		Execute:   judge.Command{Argv: []string{"java", "-Xmx{memlimit}m", "-cp", "{workdir}", "Main"}},
+		Artifacts: []string{"*.class"},
+	},
+	// Compiled only to report syntax errors as such, the whole folder is
+	// carried over to the run step
+	".py": {
+		Name: "Python 3", Time: 2, Filename: "main.py",
+		Compile: &judge.Command{Argv: []string{"python3", "-m", "py_compile", "{src}"}},
+		Execute: judge.Command{Argv: []string{"python3", "{src}"}},
+	},
+}
+
+// languageFor : Language of the solution file, found from its extension
+func (app *app) languageFor(solution string) (judge.Language, error) {
+	extension := strings.ToLower(filepath.Ext(solution))
+	if language, ok := app.cfg.Languages[extension]; ok {
+		return language, nil
+	}
+	if language, ok := builtinLanguages[extension]; ok {
+		return language, nil
+	}
+	return judge.Language{}, fmt.Errorf("%s: no language for %q files, add one under languages in the config file", solution, extension)
+}
+
+// testVerdicts : Verdicts a solution can be expected to get
+var testVerdicts = []string{
+	judge.VerdictAccepted,
+	judge.VerdictWrongAnswer,
+	judge.VerdictTimeLimit,
+	judge.VerdictRuntimeError,
+	judge.VerdictCompileError,
+	judge.VerdictOutputLimit,
+}
+
+// parseSolution : Path and expected verdict of an argument such as
+// wrong.py=WA, solutions without a verdict are expected to be accepted
+func parseSolution(arg string) (string, string, error) {
+	path, verdict := arg, judge.VerdictAccepted
+	if i := strings.LastIndex(arg, "="); i >= 0 {
+		path, verdict = arg[:i], strings.ToUpper(arg[i+1:])
+	}
+	for _, known := range testVerdicts {
+		if verdict == known {
+			return path, verdict, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s: expected verdict should be one of %s", arg, strings.Join(testVerdicts, ", "))
+}
+
+// TestRun : Outcome of one testcase
+type TestRun struct {
+	Index   int     `json:"index"`
+	Number  int     `json:"number"`
+	Name    string  `json:"name,omitempty"`
+	Verdict string  `json:"verdict"`
+	Time    float64 `json:"time_ms"`
+	Detail  string  `json:"detail,omitempty"`
+}
+
+// SolutionResult : Outcome of a solution over every testcase
+type SolutionResult struct {
+	Solution      string    `json:"solution"`
+	Language      string    `json:"language"`
+	Expected      string    `json:"expected"`
+	Verdict       string    `json:"verdict"`
+	Ok            bool      `json:"ok"`
+	CompileOutput string    `json:"compile_output,omitempty"`
+	Tests         []TestRun `json:"tests"`
+}
+
+// testSolution : Compiles the solution and runs it on every testcase. The
+// verdict is that of the first testcase not accepted.
+func (app *app) testSolution(problem judge.Problem, timeLimit int, path string, expected string) (SolutionResult, error) {
+	result := SolutionResult{Solution: path, Expected: expected, Verdict: judge.VerdictAccepted, Tests: []TestRun{}}
+	language, err := app.languageFor(path)
+	if err != nil {
+		return result, err
+	}
+	result.Language = language.Name
+
+	program, err := judge.Compile(app.ctx, language, path)
+	var compileErr *judge.CompileError
+	if errors.As(err, &compileErr) {
+		result.Verdict = judge.VerdictCompileError
+		result.CompileOutput = compileErr.Output
+		result.Ok = expected == result.Verdict
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	defer program.Close()
+
+	for _, testcase := range problem.Testcases {
+		run, err := program.Run(app.ctx, testcase, language.TimeLimit(timeLimit))
+		if err != nil {
+			return result, err
+		}
+		result.Tests = append(result.Tests, TestRun{
+			Index:   testcase.Index,
+			Number:  testcase.Number,
+			Name:    testcase.Name,
+			Verdict: run.Verdict,
+			Time:    float64(run.Time.Microseconds()) / 1000,
+			Detail:  run.Detail,
+		})
+		if result.Verdict == judge.VerdictAccepted {
+			result.Verdict = run.Verdict
+		}
+	}
+	result.Ok = expected == result.Verdict
+	return result, nil
+}
+
+// solutionResult : Table of the runs of a solution and how it compares
+// to what was expected
+func (p *printer) solutionResult(result SolutionResult) {
+	fmt.Fprintf(p.w, "%s (%s)\n", result.Solution, result.Language)
+	if len(result.CompileOutput) > 0 {
+		fmt.Fprintln(p.w, strings.TrimRight(result.CompileOutput, "\n"))
+	}
+	if len(result.Tests) > 0 {
+		rows := make([][]string, len(result.Tests))
+		for i, run := range result.Tests {
+			rows[i] = []string{fmt.Sprint(run.Index), fmt.Sprint(run.Number), run.Name, run.Verdict, fmt.Sprintf("%.0fms", run.Time), run.Detail}
+		}
+		p.table([]string{"TEST", "NUMBER", "NAME", "VERDICT", "TIME", "DETAIL"}, rows)
+	}
+
+	switch {
+	case result.Ok:
+		fmt.Fprintf(p.w, "%s as expected\n\n", result.Verdict)
+	case result.Verdict == judge.VerdictAccepted:
+		fmt.Fprintf(p.w, "UNEXPECTED PASS: expected %s, got AC\n\n", result.Expected)
+	default:
+		fmt.Fprintf(p.w, "UNEXPECTED: expected %s, got %s\n\n", result.Expected, result.Verdict)
+	}
+}
+
+func init() {
+	commands = append(commands, &command{
+		group: "test", args: "<problem folder> <solution>[=VERDICT]...",
+		summary: "Judge solutions locally against the testcases of a problem folder, no server needed",
+		setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+			timeLimit := fs.Int("time", 0, "Time limit in seconds (default the one in manifest.json, or 1)")
+			return func(app *app, fs *flag.FlagSet) error {
+				if fs.NArg() < 2 {
+					fs.Usage()
+					return errUsage
+				}
+				problem, err := judge.LoadProblem(fs.Arg(0))
+				if err != nil {
+					return err
+				}
+				if *timeLimit <= 0 {
+					*timeLimit = problem.Time
+				}
+				if *timeLimit <= 0 {
+					*timeLimit = 1
+				}
+				app.out.message("%d testcases, %s layout, %ds time limit\n", len(problem.Testcases), problem.Layout, *timeLimit)
+
+				var results []SolutionResult
+				failed := 0
+				for _, arg := range fs.Args()[1:] {
+					path, expected, err := parseSolution(arg)
+					if err != nil {
+						return err
+					}
+					result, err := app.testSolution(problem, *timeLimit, path, expected)
+					if err != nil {
+						return err
+					}
+					if !result.Ok {
+						failed++
+					}
+					results = append(results, result)
+					if app.cfg.Output == OutputHuman {
+						app.out.solutionResult(result)
+					}
+				}
+				if app.cfg.Output == OutputJSON {
+					app.out.json(results)
+				}
+
+				if failed > 0 {
+					return fmt.Errorf("%d of %d solutions did not get the expected verdict", failed, len(results))
+				}
+				return nil
+			}
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+
+	"judge-two/pkg/client"
+)
+
+func init() {
+	commands = append(commands,
+		&command{
+			group:   "workers",
+			summary: "List live workers and the languages no worker can run",
+			setup: func(fs *flag.FlagSet) func(*app, *flag.FlagSet) error {
+				uncovered := fs.Bool("uncovered", false, "Only list the languages no live worker can run")
+				return func(app *app, fs *flag.FlagSet) error {
+					if _, err := positional(fs, 0); err != nil {
+						return err
+					}
+					workers, err := app.client.ListWorkers(app.ctx)
+					if err != nil {
+						return err
+					}
+					if *uncovered {
+						app.out.print(workers.Uncovered, func() { app.out.coverage(workers.Uncovered, len(workers.Workers)) })
+						return nil
+					}
+					app.out.print(workers, func() { app.out.workers(workers) })
+					return nil
+				}
+			},
+		},
+	)
+}
+
+// uncoveredReason : Why no worker runs a language
+func uncoveredReason(language client.LanguageCoverage, live int) string {
+	if live <= 0 {
+		return "no live worker"
+	}
+	if !language.Probe {
+		return "no probe to self-test with"
+	}
+	if len(language.Failing) < live {
+		return "self-test pending"
+	}
+	return "failed the self-test on every worker"
+}
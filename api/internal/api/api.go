@@ -3,18 +3,27 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"judge-two/internal/config"
+
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // API : Structure for the main app object
@@ -22,6 +31,24 @@ type API struct {
 	Log    *zap.Logger
 	Router *mux.Router
 	Db     *mongo.Database
+	Config config.Config
+
+	// Stores behind the handlers, MongoDB unless replaced for tests
+	Languages   LanguageRepo
+	Questions   QuestionRepo
+	Submissions SubmissionRepo
+	Jobs        JobRepo
+	Rejudges    RejudgeRepo
+	Contests    ContestRepo
+	Workers     WorkerRepo
+
+	// Derived from the configuration
+	Limits  TestcaseLimits
+	Storage string // Root folder of the testcases, ending with a slash
+
+	draining     int32         // Set once shutdown starts, read atomically
+	workerChecks []healthCheck // Readiness checks of workers, set before serving
+	stopTracing  func(context.Context) error
 }
 
 func jsonResponse(next http.Handler) http.Handler {
@@ -31,49 +58,148 @@ func jsonResponse(next http.Handler) http.Handler {
 	})
 }
 
-// StartAPI : Returns an API object
-func StartAPI() API {
-	var api API
-
+// StartAPI : Returns an API object, or an error when the database cannot
+// be reached or its schema is outdated
+func StartAPI(cfg config.Config) (*API, error) {
+	api := newAPI(cfg)
 	api.mountLogger()
+	api.mountTracing(serviceName)
 	api.mountRouter()
-	api.mountDatabase()
+	if err := api.mountDatabase(); err != nil {
+		api.stopTracing(context.Background())
+		api.Log.Sync()
+		return nil, err
+	}
+
+	return api, nil
+}
 
+// NewMemoryAPI : API keeping every store in memory, for tests. Testcases
+// are still written under the storage path, and nothing gets judged as
+// workers need MongoDB.
+func NewMemoryAPI(cfg config.Config) *API {
+	api := newAPI(cfg)
+	api.mountLogger()
+	api.mountRouter()
+	api.Languages = NewMemoryLanguageRepo()
+	api.Questions = NewMemoryQuestionRepo()
+	api.Submissions = NewMemorySubmissionRepo()
+	api.Jobs = NewMemoryJobRepo()
+	api.Rejudges = NewMemoryRejudgeRepo()
+	api.Contests = NewMemoryContestRepo()
+	api.Workers = NewMemoryWorkerRepo()
 	return api
 }
 
-// Run : Start the server
-func (api *API) Run() {
+// newAPI : API with the settings derived from the configuration
+func newAPI(cfg config.Config) *API {
+	return &API{
+		Config: cfg,
+		Limits: TestcaseLimits{
+			MaxArchiveSize: cfg.Limits.MaxArchiveSize,
+			MaxFileSize:    cfg.Limits.MaxFileSize,
+			MaxTotalSize:   cfg.Limits.MaxTotalSize,
+			MaxEntries:     cfg.Limits.MaxEntries,
+			MaxRatio:       cfg.Limits.MaxRatio,
+		},
+		Storage: strings.TrimSuffix(cfg.Storage.Path, "/") + "/",
+	}
+}
+
+// Run : Start the server and serve until SIGINT or SIGTERM. Readiness
+// fails for the shutdown delay first, then requests in flight get until
+// the shutdown timeout to finish, then the database is disconnected and
+// the logs flushed.
+func (api *API) Run() error {
 	server := &http.Server{
-		Addr:         "0.0.0.0:8080",
+		Addr:         api.Config.Listen,
 		WriteTimeout: time.Second * 5 * 60,
 		ReadTimeout:  time.Second * 5 * 60,
 		IdleTimeout:  time.Second * 5 * 60,
-		Handler:      api.Router,
+		Handler:      api.Handler(),
 	}
 
+	// Background jobs stop once requests in flight are done, before the
+	// database is disconnected. Solutions and probes never run here but on
+	// the workers.
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	for _, job := range []func(context.Context){api.runPurge, api.runRejudges} {
+		background.Add(1)
+		go func(job func(context.Context)) {
+			defer background.Done()
+			job(backgroundCtx)
+		}(job)
+	}
+
+	serverErr := make(chan error, 1)
 	go func() {
-		api.Log.Info("Listening on port 8080")
-		if err := server.ListenAndServe(); err != nil {
-			api.Log.Info(err.Error())
-			panic(errors.New("Server launch error"))
+		api.Log.Info("Listening on " + api.Config.Listen)
+		if len(api.Config.TLS.CertFile) > 0 {
+			serverErr <- server.ListenAndServeTLS(api.Config.TLS.CertFile, api.Config.TLS.KeyFile)
+		} else {
+			serverErr <- server.ListenAndServe()
 		}
 	}()
 
 	signalChannel := make(chan os.Signal, 1)
-	signal.Notify(signalChannel, os.Interrupt)
+	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChannel)
+
+	var err error
+	select {
+	case err = <-serverErr:
+		api.Log.Error("Server stopped", zap.Error(err))
+	case sig := <-signalChannel:
+		api.Log.Info("Shutting down", zap.String("signal", sig.String()))
 
-	<-signalChannel
+		// No new judge work is accepted and readiness fails. The server
+		// keeps taking requests until the load balancer has seen readiness
+		// fail and stopped sending any, then those in flight finish.
+		atomic.StoreInt32(&api.draining, 1)
+		time.Sleep(time.Duration(api.Config.ShutdownDelay) * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(api.Config.ShutdownTimeout)*time.Second)
+		defer cancel()
+		if err = server.Shutdown(ctx); err != nil {
+			api.Log.Error("Requests still running at the shutdown deadline", zap.Error(err))
+		}
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	stopBackground()
+	background.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	server.Shutdown(ctx)
+	if dbErr := api.Db.Client().Disconnect(ctx); dbErr != nil {
+		api.Log.Error("Database disconnection failed", zap.Error(dbErr))
+	}
+	if traceErr := api.stopTracing(ctx); traceErr != nil {
+		api.Log.Error("Flushing traces failed", zap.Error(traceErr))
+	}
+
 	api.Log.Info("bye")
+	api.Log.Sync()
+	return err
+}
+
+// shuttingDown : Whether shutdown has started
+func (api *API) shuttingDown() bool {
+	return atomic.LoadInt32(&api.draining) != 0
 }
 
 func (api *API) mountLogger() {
 	var cfg zap.Config
-	cfg = zap.NewDevelopmentConfig()
+	if api.Config.Log.Format == config.LogConsole {
+		cfg = zap.NewDevelopmentConfig()
+	} else {
+		cfg = zap.NewProductionConfig()
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(api.Config.Log.Level)); err != nil {
+		panic(err)
+	}
+	cfg.Level = zap.NewAtomicLevelAt(level)
 
 	cfg.OutputPaths = []string{
 		"stdout",
@@ -91,55 +217,132 @@ func (api *API) mountLogger() {
 	api.Log = logger
 }
 
+// Handler : Router wrapped in what applies to every request, routed or not
+func (api *API) Handler() http.Handler {
+	return api.logRequests(api.Router)
+}
+
 func (api *API) mountRouter() {
 	api.Router = mux.NewRouter()
+	api.Router.Use(otelmux.Middleware(serviceName))
+	api.Router.Use(recordRoute)
+	api.Router.Use(measureRequests)
 	api.Router.Use(jsonResponse)
 
-	// Languages
-	api.Router.HandleFunc("/addLanguage", api.addLanguageHandler).Methods("POST")
-	api.Router.HandleFunc("/editLanguage", api.editLanguageHandler).Methods("POST")
-	api.Router.HandleFunc("/deleteLanguage", api.deleteLanguageHandler).Methods("POST")
+	// Monitoring
+	api.Router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	api.Router.HandleFunc("/healthz", api.livenessHandler).Methods("GET")
+	api.Router.HandleFunc("/readyz", api.readinessHandler).Methods("GET")
+	api.Router.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+
+	// Languages, the v1 routes below are kept for existing clients
+	api.Router.HandleFunc("/addLanguage", api.requireAdmin(api.addLanguageHandler)).Methods("POST")
+	api.Router.HandleFunc("/editLanguage", api.requireAdmin(api.editLanguageHandler)).Methods("POST")
+	api.Router.HandleFunc("/deleteLanguage", api.requireAdmin(api.deleteLanguageHandler)).Methods("POST")
 
 	// Questions
 	api.Router.HandleFunc("/addQuestion", api.addQuestionHandler).Methods("POST")
-	api.Router.HandleFunc("/editTestcases", api.editTestcasesHandler).Methods("POST")
+	api.Router.HandleFunc("/editTestcases", api.requireAdmin(api.editTestcasesHandler)).Methods("POST")
 	api.Router.HandleFunc("/editQuestion", api.editQuestionHandler).Methods("POST")
-	api.Router.HandleFunc("/deleteQuestion", api.deleteQuestionHandler).Methods("POST")
+	api.Router.HandleFunc("/deleteQuestion", api.requireAdmin(api.deleteQuestionHandler)).Methods("POST")
+
+	// Testcases
+	api.Router.HandleFunc("/addTestcase", api.requireAdmin(api.addTestcaseHandler)).Methods("POST")
+	api.Router.HandleFunc("/replaceTestcase", api.requireAdmin(api.replaceTestcaseHandler)).Methods("POST")
+	api.Router.HandleFunc("/deleteTestcase", api.requireAdmin(api.deleteTestcaseHandler)).Methods("POST")
+	api.Router.HandleFunc("/reorderTestcases", api.requireAdmin(api.reorderTestcasesHandler)).Methods("POST")
+	api.Router.HandleFunc("/downloadTestcase", api.requireAdmin(api.downloadTestcaseHandler)).Methods("GET")
+	api.Router.HandleFunc("/exportQuestion", api.requireAdmin(api.exportQuestionHandler)).Methods("GET")
+
+	// Rejudges
+	api.Router.HandleFunc("/rejudge", api.requireAdmin(api.rejudgeHandler)).Methods("POST")
+	api.Router.HandleFunc("/rejudgeStatus", api.requireAdmin(api.rejudgeStatusHandler)).Methods("GET")
+
+	api.mountV2()
 }
 
-func (api *API) mountDatabase() {
-	//clientOpts := options.Client().ApplyURI("mongodb://mongo-0.mongo,mongo-1.mongo,mongo-2.mongo:27017/?replicaSet=rs0&readPreference=secondaryPreferred")
-	clientOpts := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.TODO(), clientOpts)
-	if err != nil {
-		api.Log.Info("Database connection failed, URI")
-		panic(err)
-	}
+// maxConnectDelay : Longest wait between two connection attempts at boot
+const maxConnectDelay = 10 * time.Second
 
-	err = client.Ping(context.TODO(), readpref.Primary())
+// connectDatabase : Connects to MongoDB, retrying with a growing delay
+// until it answers or the connect timeout runs out, so the judge can start
+// alongside its replica set
+func (api *API) connectDatabase(ctx context.Context) error {
+	cfg := api.Config.Mongo
+	clientOpts := options.Client().ApplyURI(cfg.URI).SetMonitor(mongoMonitor()).
+		SetServerSelectionTimeout(time.Duration(cfg.ReadTimeout) * time.Second)
+	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
-		api.Log.Info("Database connection failed, Ping")
-		panic(err)
-	} else {
-		api.Log.Info("Connected to Database")
+		api.Log.Error("Database connection failed, URI", zap.Error(err))
+		return err
 	}
 
-	api.Db = client.Database("judge")
-
-	// Initialize database with languages table if not available
-	cols, err := api.Db.ListCollectionNames(context.TODO(), bson.D{})
-	foundLanguagesTable := false
-	for _, result := range cols {
-		if result == "languages" {
-			foundLanguagesTable = true
+	deadline := time.Now().Add(time.Duration(cfg.ConnectTimeout) * time.Second)
+	delay := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		if err = client.Ping(ctx, readpref.Primary()); err == nil {
 			break
 		}
+		if time.Now().Add(delay).After(deadline) {
+			api.Log.Error("Database connection failed, Ping", zap.Int("attempts", attempt), zap.Error(err))
+			client.Disconnect(context.Background())
+			return err
+		}
+		api.Log.Warn("Database not reachable yet, retrying", zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			client.Disconnect(context.Background())
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxConnectDelay {
+			delay = maxConnectDelay
+		}
+	}
+	api.Log.Info("Connected to Database")
+
+	// Writes, verdicts included, are acknowledged once a majority of the
+	// replica set has them, so a failover does not roll them back
+	writeTimeout := time.Duration(cfg.WriteTimeout) * time.Second
+	api.Db = client.Database(cfg.Database, options.Database().SetWriteConcern(
+		writeconcern.New(writeconcern.WMajority(), writeconcern.J(true), writeconcern.WTimeout(writeTimeout))))
+
+	repoOpts := MongoOptions{
+		ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
+		WriteTimeout: writeTimeout,
+	}
+	if mode, err := readpref.ModeFromString(cfg.ListReadPref); err == nil && mode != readpref.PrimaryMode {
+		repoOpts.ListReadPref, _ = readpref.New(mode)
+	}
+	api.Languages = NewMongoLanguageRepo(api.Db, repoOpts)
+	api.Questions = NewMongoQuestionRepo(api.Db, repoOpts)
+	api.Submissions = NewMongoSubmissionRepo(api.Db, repoOpts)
+	api.Jobs = NewMongoJobRepo(api.Db, repoOpts)
+	api.Rejudges = NewMongoRejudgeRepo(api.Db, repoOpts)
+	api.Contests = NewMongoContestRepo(api.Db, repoOpts)
+	api.Workers = NewMongoWorkerRepo(api.Db, repoOpts)
+	return nil
+}
+
+// mountDatabase : Connects to MongoDB and brings its schema up to date,
+// refusing to start on an outdated schema when migrations are left to
+// judge migrate
+func (api *API) mountDatabase() error {
+	ctx := context.Background()
+	if err := api.connectDatabase(ctx); err != nil {
+		return err
+	}
+	collectQueue(api.Db)
+
+	statuses, err := api.migrate(ctx, !api.Config.Mongo.Migrate)
+	if err != nil {
+		api.Log.Error("Schema migration failed", zap.Error(err))
+		return err
 	}
-	if !foundLanguagesTable {
-		api.Db.Collection("languages").InsertMany(context.TODO(), []interface{}{
-			Language{ID: primitive.NewObjectID(), Name: "C++", Time: 2, Filename: "main.cpp", Compile: "g++ -O2 --std=c++17 /tmp/runner/main.cpp", Execute: "/tmp/runner/a.out"},
-			Language{ID: primitive.NewObjectID(), Name: "Java 8", Time: 4, Filename: "Main.java", Compile: "javac /tmp/runner/Main.java", Execute: "java -cp /tmp/runner Main"},
-			Language{ID: primitive.NewObjectID(), Name: "Python 3", Time: 6, Filename: "main.py", Compile: "compilation-not-needed", Execute: "python3 /tmp/runner/main.py"},
-		}, options.InsertMany().SetOrdered(false))
+	if pending := pendingMigrations(statuses); pending > 0 {
+		err = fmt.Errorf("%d schema migrations are pending, run judge migrate", pending)
+		api.Log.Error("Database schema is outdated", zap.Error(err))
+		return err
 	}
+	return nil
 }
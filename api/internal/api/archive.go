@@ -0,0 +1,335 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// TestcaseLimits : Bounds enforced on uploaded testcase archives
+type TestcaseLimits struct {
+	MaxArchiveSize int64 // Size of the uploaded archive in bytes
+	MaxFileSize    int64 // Uncompressed size of a single entry in bytes
+	MaxTotalSize   int64 // Uncompressed size of all entries together in bytes
+	MaxEntries     int   // Number of entries in the archive, directories included
+	MaxRatio       int64 // Uncompressed to compressed size ratio of an entry, or of a whole tarball
+}
+
+// Errors returned or reported while reading an archive
+var (
+	ErrUnsupportedArchive = errors.New("testcases should be a .zip, .tar.gz or .tar.xz archive")
+	ErrArchiveTooLarge    = errors.New("archive exceeds the maximum upload size")
+	ErrTooManyEntries     = errors.New("archive has too many entries")
+	ErrFileTooLarge       = errors.New("entry exceeds the maximum file size")
+	ErrTotalTooLarge      = errors.New("archive exceeds the maximum uncompressed size")
+	ErrCompressionRatio   = errors.New("entry exceeds the maximum compression ratio")
+	ErrUnsafeName         = errors.New("entry has an unsafe name")
+	ErrNotRegularFile     = errors.New("entry is not a regular file")
+)
+
+// archiveFormat : Container format of an uploaded archive
+type archiveFormat string
+
+// Supported archive formats
+const (
+	formatZip   archiveFormat = ".zip"
+	formatTarGz archiveFormat = ".tar.gz"
+	formatTarXz archiveFormat = ".tar.xz"
+)
+
+// detectArchiveFormat : Picks the format from the uploaded file name
+func detectArchiveFormat(filename string) (archiveFormat, error) {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return formatZip, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return formatTarGz, nil
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return formatTarXz, nil
+	}
+	return "", ErrUnsupportedArchive
+}
+
+// archiveEntry : Single entry of an archive, whatever its format
+type archiveEntry struct {
+	Name       string
+	Dir        bool
+	Regular    bool
+	Size       uint64 // Declared uncompressed size
+	Compressed int64  // Compressed size, -1 when the format does not record it
+	open       func() (io.ReadCloser, error)
+}
+
+// Open : Reader over the uncompressed content of the entry
+func (e *archiveEntry) Open() (io.ReadCloser, error) {
+	if e.open == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNotRegularFile, e.Name)
+	}
+	return e.open()
+}
+
+// archiveReader : Entries of an opened archive, along with problems
+// concerning the archive as a whole
+type archiveReader struct {
+	entries  []*archiveEntry
+	problems []error
+	close    func() error
+}
+
+// Close : Releases whatever the archive holds open
+func (a *archiveReader) Close() error {
+	return a.close()
+}
+
+// saveTestcaseUpload : Copies the "testcases" form file to a temporary file
+// and returns its path. The caller removes the file once done with it.
+func saveTestcaseUpload(r *http.Request, limits TestcaseLimits) (string, archiveFormat, error) {
+	file, handler, err := r.FormFile("testcases")
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	format, err := detectArchiveFormat(handler.Filename)
+	if err != nil {
+		return "", "", err
+	}
+	if handler.Size > limits.MaxArchiveSize {
+		return "", "", ErrArchiveTooLarge
+	}
+
+	// The client supplied file name is never used on disk
+	f, err := ioutil.TempFile("", "testcases-*"+string(format))
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(file, limits.MaxArchiveSize+1))
+	if err == nil && n > limits.MaxArchiveSize {
+		err = ErrArchiveTooLarge
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", "", err
+	}
+
+	return f.Name(), format, nil
+}
+
+// sanitizeEntryName : Rejects absolute paths, parent references and
+// anything else that would not stay inside the extraction folder
+func sanitizeEntryName(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "\\\x00") || strings.HasPrefix(name, "/") {
+		return "", ErrUnsafeName
+	}
+	trimmed := strings.TrimSuffix(name, "/")
+	if path.Clean(trimmed) != trimmed {
+		return "", ErrUnsafeName
+	}
+	for _, part := range strings.Split(trimmed, "/") {
+		if part == ".." || part == "." {
+			return "", ErrUnsafeName
+		}
+	}
+	return name, nil
+}
+
+// checkEntrySize : Checks the sizes declared in the header of an entry
+func checkEntrySize(entry *archiveEntry, limits TestcaseLimits) error {
+	size := entry.Size
+	if size > uint64(limits.MaxFileSize) {
+		return ErrFileTooLarge
+	}
+	if entry.Compressed >= 0 && size > 0 && (entry.Compressed == 0 || size/uint64(entry.Compressed) > uint64(limits.MaxRatio)) {
+		return ErrCompressionRatio
+	}
+	return nil
+}
+
+// openArchive : Lists the entries of the archive at filePath
+func openArchive(filePath string, format archiveFormat, limits TestcaseLimits) (*archiveReader, error) {
+	switch format {
+	case formatZip:
+		return openZip(filePath)
+	case formatTarGz:
+		return openTar(filePath, limits, func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		})
+	case formatTarXz:
+		return openTar(filePath, limits, func(r io.Reader) (io.Reader, error) {
+			return xz.NewReader(r)
+		})
+	}
+	return nil, ErrUnsupportedArchive
+}
+
+func openZip(filePath string) (*archiveReader, error) {
+	zipr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &archiveReader{close: zipr.Close}
+	for _, item := range zipr.File {
+		mode := item.Mode()
+		archive.entries = append(archive.entries, &archiveEntry{
+			Name:       item.Name,
+			Dir:        mode.IsDir(),
+			Regular:    mode.IsRegular(),
+			Size:       item.UncompressedSize64,
+			Compressed: int64(item.CompressedSize64),
+			open:       item.Open,
+		})
+	}
+
+	return archive, nil
+}
+
+// openTar : Tarballs can only be read front to back, so every regular
+// file is spooled into a temporary folder while the limits are enforced.
+// The tar reader reads through every byte an entry declares, even for an
+// entry that is skipped, so the limits are checked against the declared
+// sizes before anything is decompressed and reading stops at the first
+// entry going past them.
+func openTar(filePath string, limits TestcaseLimits, decompress func(io.Reader) (io.Reader, error)) (*archiveReader, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := decompress(f)
+	if err != nil {
+		return nil, err
+	}
+
+	spoolPath, err := ioutil.TempDir("", "testcases-")
+	if err != nil {
+		return nil, err
+	}
+	archive := &archiveReader{
+		close: func() error {
+			return os.RemoveAll(spoolPath)
+		},
+	}
+
+	tarr := tar.NewReader(stream)
+	remaining := limits.MaxTotalSize
+	// Size the whole tarball may unpack to given its compressed size
+	unpackable := limits.MaxRatio * info.Size()
+	for {
+		header, err := tarr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			archive.Close()
+			return nil, err
+		}
+		if len(archive.entries) >= limits.MaxEntries {
+			archive.problems = append(archive.problems, ErrTooManyEntries)
+			break
+		}
+
+		var problem error
+		switch {
+		case header.Size > limits.MaxFileSize:
+			problem = ErrFileTooLarge
+		case header.Size > remaining:
+			problem = ErrTotalTooLarge
+		case header.Size > unpackable:
+			problem = ErrCompressionRatio
+		}
+		if problem != nil {
+			archive.problems = append(archive.problems, fmt.Errorf("%w: %q", problem, header.Name))
+			break
+		}
+		remaining -= header.Size
+		unpackable -= header.Size
+
+		// PAX and GNU metadata headers are consumed by the tar reader itself
+		entry := &archiveEntry{
+			Name:       header.Name,
+			Dir:        header.Typeflag == tar.TypeDir,
+			Regular:    header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA,
+			Size:       uint64(header.Size),
+			Compressed: -1,
+		}
+		archive.entries = append(archive.entries, entry)
+		if !entry.Regular {
+			continue
+		}
+
+		spoolFile := filepath.Join(spoolPath, strconv.Itoa(len(archive.entries)))
+		if _, err = copyLimited(spoolFile, tarr, header.Size); err != nil {
+			archive.Close()
+			return nil, fmt.Errorf("%w: %q", err, header.Name)
+		}
+		entry.open = func() (io.ReadCloser, error) {
+			return os.Open(spoolFile)
+		}
+	}
+
+	return archive, nil
+}
+
+// copyLimited : Copies src to target, failing once more than limit
+// bytes come out of it
+func copyLimited(target string, src io.Reader, limit int64) (int64, error) {
+	targetFile, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return 0, err
+	}
+	defer targetFile.Close()
+
+	n, err := io.Copy(targetFile, io.LimitReader(src, limit+1))
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, ErrFileTooLarge
+	}
+
+	return n, nil
+}
+
+// copyEntry : Copies an entry to target, stopping once it grows past
+// maxFile bytes or past the remaining total budget
+func copyEntry(target string, entry *archiveEntry, maxFile int64, remaining int64) (int64, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	limit := maxFile
+	if remaining < limit {
+		limit = remaining
+	}
+	n, err := copyLimited(target, src, limit)
+	if err == ErrFileTooLarge && limit < maxFile {
+		err = ErrTotalTooLarge
+	}
+	if err != nil {
+		return n, fmt.Errorf("%w: %q", err, entry.Name)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,416 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// testLimits : Limits small enough for the tests to go past each of them
+func testLimits() TestcaseLimits {
+	return TestcaseLimits{
+		MaxArchiveSize: 1 << 16,
+		MaxFileSize:    1024,
+		MaxTotalSize:   4096,
+		MaxEntries:     16,
+		MaxRatio:       50,
+	}
+}
+
+// archiveFile : Entry of an archive built for a test
+type archiveFile struct {
+	Name string
+	Body string
+	Mode os.FileMode // Zero for a regular file
+	Type byte        // Tar type flag, zero for a regular file
+	Link string      // Target of links
+	Raw  *tar.Header // Tar header written as it is, when set
+}
+
+// zipArchive : Zip of the files, deflated
+func zipArchive(t *testing.T, files ...archiveFile) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, file := range files {
+		header := &zip.FileHeader{Name: file.Name, Method: zip.Deflate}
+		header.SetMode(0644)
+		if file.Mode != 0 {
+			header.SetMode(file.Mode)
+		}
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, file.Body)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tarArchive : Tarball of the files, compressed in the format
+func tarArchive(t *testing.T, format archiveFormat, files ...archiveFile) []byte {
+	var buf bytes.Buffer
+	var compressed io.WriteCloser
+	switch format {
+	case formatTarGz:
+		compressed = gzip.NewWriter(&buf)
+	case formatTarXz:
+		w, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed = w
+	default:
+		t.Fatalf("no tarball in %s", format)
+	}
+
+	w := tar.NewWriter(compressed)
+	for _, file := range files {
+		header := file.Raw
+		if header == nil {
+			header = &tar.Header{Name: file.Name, Mode: 0644, Size: int64(len(file.Body)), Typeflag: tar.TypeReg, Linkname: file.Link}
+			if file.Type != 0 {
+				header.Typeflag = file.Type
+				header.Size = 0
+			}
+			if file.Type == tar.TypeDir {
+				header.Mode = 0755
+			}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, file.Body)
+	}
+	// Headers declaring more than their body leave the tarball short,
+	// closing it would fail
+	w.Flush()
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// archiveOf : Archive of the files in the format
+func archiveOf(t *testing.T, format archiveFormat, files ...archiveFile) []byte {
+	if format == formatZip {
+		return zipArchive(t, files...)
+	}
+	return tarArchive(t, format, files...)
+}
+
+// pairs : Files of n testcases in the standard layout
+func pairs(n int, body string) []archiveFile {
+	var files []archiveFile
+	for i := 1; i <= n; i++ {
+		files = append(files,
+			archiveFile{Name: "input/input" + strconv.Itoa(i) + ".txt", Body: body},
+			archiveFile{Name: "output/output" + strconv.Itoa(i) + ".txt", Body: body},
+		)
+	}
+	return files
+}
+
+// openBytes : Validates the archive raw holds
+func openBytes(t *testing.T, raw []byte, format archiveFormat, limits TestcaseLimits) (*testcaseArchive, error) {
+	f, err := ioutil.TempFile("", "archive-test-*"+string(format))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(raw)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return openTestcaseArchive(f.Name(), format, limits)
+}
+
+// importBytes : Validates the archive and extracts it when it is valid,
+// failing the test when the extracted files break the limits or land
+// outside the folder they are extracted to
+func importBytes(t *testing.T, raw []byte, format archiveFormat, limits TestcaseLimits) (*ArchiveReport, error) {
+	archive, err := openBytes(t, raw, format, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	if !archive.report.Valid {
+		if len(archive.report.Errors) == 0 {
+			t.Fatalf("invalid archive reported without errors: %+v", archive.report)
+		}
+		return &archive.report, nil
+	}
+
+	parent, err := ioutil.TempDir("", "archive-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "question") + "/"
+	testcases, err := archive.extract(dir)
+	if err != nil {
+		return &archive.report, err
+	}
+
+	var files int
+	var total int64
+	filepath.Walk(parent, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if !strings.HasPrefix(path, dir) {
+			t.Fatalf("extracted %s outside of %s", path, dir)
+		}
+		if !info.Mode().IsRegular() || info.Size() > limits.MaxFileSize {
+			t.Fatalf("extracted %s of mode %s and size %d", path, info.Mode(), info.Size())
+		}
+		files++
+		total += info.Size()
+		return nil
+	})
+	if files != 2*len(testcases) || total > limits.MaxTotalSize {
+		t.Fatalf("extracted %d files of %d bytes for %d testcases", files, total, len(testcases))
+	}
+	return &archive.report, nil
+}
+
+// expectIssue : Fails the test unless the report has an error with the
+// reason, on the entry when entry is set
+func expectIssue(t *testing.T, report *ArchiveReport, entry string, reason error) {
+	t.Helper()
+	if report == nil {
+		t.Fatal("no report")
+	}
+	for _, issue := range report.Errors {
+		if strings.Contains(issue.Reason, reason.Error()) && (entry == "" || issue.Entry == entry) {
+			if report.Valid {
+				t.Fatalf("valid with errors: %+v", report)
+			}
+			return
+		}
+	}
+	t.Fatalf("errors %+v, expected %q on %q", report.Errors, reason, entry)
+}
+
+func TestArchiveRejectsUnsafeEntries(t *testing.T) {
+	for _, format := range []archiveFormat{formatZip, formatTarGz, formatTarXz} {
+		for _, name := range []string{"../input/input9.txt", "/etc/input9.txt", "input/../../input9.txt", "input/./input9.txt", "input\\input9.txt", "input//input9.txt"} {
+			files := append(pairs(1, "1"), archiveFile{Name: name, Body: "x"})
+			report, err := importBytes(t, archiveOf(t, format, files...), format, testLimits())
+			if err != nil {
+				t.Fatalf("%s %q: %v", format, name, err)
+			}
+			expectIssue(t, report, name, ErrUnsafeName)
+		}
+	}
+
+	// Links would point anywhere once extracted
+	report, err := importBytes(t, zipArchive(t, append(pairs(1, "1"), archiveFile{Name: "input/input2.txt", Body: "/etc/passwd", Mode: os.ModeSymlink | 0777})...), formatZip, testLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIssue(t, report, "input/input2.txt", ErrNotRegularFile)
+	for _, format := range []archiveFormat{formatTarGz, formatTarXz} {
+		for _, link := range []archiveFile{
+			{Name: "input/input2.txt", Type: tar.TypeSymlink, Link: "/etc/passwd"},
+			{Name: "output/output2.txt", Type: tar.TypeLink, Link: "input/input1.txt"},
+			{Name: "input/input3.txt", Type: tar.TypeFifo},
+		} {
+			report, err := importBytes(t, tarArchive(t, format, append(pairs(1, "1"), link)...), format, testLimits())
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIssue(t, report, link.Name, ErrNotRegularFile)
+		}
+	}
+}
+
+func TestArchiveLimits(t *testing.T) {
+	limits := testLimits()
+	random := func(n int) string {
+		r := rand.New(rand.NewSource(int64(n)))
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte('a' + r.Intn(26))
+		}
+		return string(b)
+	}
+
+	for _, format := range []archiveFormat{formatZip, formatTarGz, formatTarXz} {
+		report, err := importBytes(t, archiveOf(t, format, pairs(2, random(100))...), format, limits)
+		if err != nil || !report.Valid || report.Testcases != 2 {
+			t.Fatalf("%s within the limits: %+v, %v", format, report, err)
+		}
+
+		report, err = importBytes(t, archiveOf(t, format, append(pairs(1, "1"), archiveFile{Name: "input/input2.txt", Body: random(2000)})...), format, limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIssue(t, report, "", ErrFileTooLarge)
+
+		report, err = importBytes(t, archiveOf(t, format, pairs(3, random(1000))...), format, limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIssue(t, report, "", ErrTotalTooLarge)
+
+		report, err = importBytes(t, archiveOf(t, format, pairs(9, "1")...), format, limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIssue(t, report, "", ErrTooManyEntries)
+	}
+
+	// Entries unpacking far beyond their compressed size are bombs, for
+	// zip by entry and for tarballs as a whole
+	bomb := strings.Repeat("0", 1000)
+	report, err := importBytes(t, zipArchive(t, pairs(1, bomb)...), formatZip, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIssue(t, report, "input/input1.txt", ErrCompressionRatio)
+	for _, format := range []archiveFormat{formatTarGz, formatTarXz} {
+		strict := limits
+		strict.MaxRatio = 2
+		report, err = importBytes(t, tarArchive(t, format, pairs(2, bomb)...), format, strict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIssue(t, report, "", ErrCompressionRatio)
+	}
+}
+
+func TestTarballStopsAtOversizedHeaders(t *testing.T) {
+	// A header declaring a huge entry is refused before anything of it is
+	// decompressed, even though the body never comes
+	for _, format := range []archiveFormat{formatTarGz, formatTarXz} {
+		files := append(pairs(1, "1"), archiveFile{Raw: &tar.Header{Name: "input/input2.txt", Mode: 0644, Size: 1 << 40, Typeflag: tar.TypeReg}})
+		report, err := importBytes(t, tarArchive(t, format, files...), format, testLimits())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIssue(t, report, "", ErrFileTooLarge)
+	}
+}
+
+func TestZipSizesAreCountedAgain(t *testing.T) {
+	// Sizes in zip headers can lie, extraction counts the bytes itself
+	raw := zipArchive(t, pairs(1, strings.Repeat("ab", 300))...)
+	lying := bytes.Replace(raw, le32(600), le32(6), -1)
+	if bytes.Equal(raw, lying) {
+		t.Fatal("no size to rewrite")
+	}
+	limits := testLimits()
+	limits.MaxFileSize = 100
+	if _, err := importBytes(t, lying, formatZip, limits); err == nil {
+		t.Fatal("zip lying about its sizes extracted")
+	}
+}
+
+// le32 : Little endian encoding of n, as zip headers hold sizes
+func le32(n uint32) []byte {
+	return []byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+}
+
+func TestSaveTestcaseUploadIgnoresClientNames(t *testing.T) {
+	upload := func(filename string, content []byte) (string, archiveFormat, error) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("testcases", filename)
+		part.Write(content)
+		w.Close()
+		r := httptest.NewRequest("POST", "/v2/questions", &body)
+		r.Header.Set("Content-Type", w.FormDataContentType())
+		return saveTestcaseUpload(r, testLimits())
+	}
+
+	saved, format, err := upload("../../../../tmp/evil.tar.gz", []byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(saved)
+	if format != formatTarGz || filepath.Dir(saved) != filepath.Clean(os.TempDir()) || strings.Contains(filepath.Base(saved), "evil") {
+		t.Fatalf("saved as %s in %s", saved, format)
+	}
+
+	if _, _, err = upload("testcases.rar", []byte("x")); err != ErrUnsupportedArchive {
+		t.Fatalf("rar upload gave %v", err)
+	}
+	if _, _, err = upload("testcases.zip", make([]byte, testLimits().MaxArchiveSize+1)); err != ErrArchiveTooLarge {
+		t.Fatalf("oversized upload gave %v", err)
+	}
+}
+
+// Longer runs of TestMalformedArchives, such as
+// go test -run TestMalformedArchives -archive.mutations 100000 -archive.seed 7
+var (
+	archiveMutations = flag.Int("archive.mutations", 300, "malformed archives tried per format")
+	archiveSeed      = flag.Int64("archive.seed", 26, "seed the malformed archives are drawn from")
+)
+
+func TestMalformedArchives(t *testing.T) {
+	limits := testLimits()
+	files := append(pairs(3, "12345\n"), archiveFile{Name: manifestFile, Body: `{"name":"Echo","testcases":[{"number":2},{"number":1}]}`})
+
+	// Every format draws its mutations from a source of its own, so a
+	// failure reproduces with the same seed whatever else runs
+	for i, format := range []archiveFormat{formatZip, formatTarGz, formatTarXz} {
+		seed := *archiveSeed + int64(i)
+		r := rand.New(rand.NewSource(seed))
+		valid := archiveOf(t, format, files...)
+		for n := 0; n < *archiveMutations; n++ {
+			data := mutateArchive(r, valid)
+			func() {
+				defer func() {
+					if p := recover(); p != nil {
+						t.Fatalf("%s mutation %d of seed %d panicked: %v\n%x", format, n, seed, p, data)
+					}
+				}()
+				// Extraction may fail, it must not break the limits
+				importBytes(t, data, format, limits)
+			}()
+		}
+	}
+}
+
+// mutateArchive : Copy of the archive with a few bytes flipped, cut,
+// inserted or blown up into large sizes
+func mutateArchive(r *rand.Rand, valid []byte) []byte {
+	data := append([]byte{}, valid...)
+	for n := 1 + r.Intn(4); n > 0 && len(data) > 0; n-- {
+		at := r.Intn(len(data))
+		switch r.Intn(5) {
+		case 0:
+			data[at] ^= byte(1 << uint(r.Intn(8)))
+		case 1:
+			data = data[:at]
+		case 2:
+			data = append(data[:at], append(make([]byte, 1+r.Intn(16)), data[at:]...)...)
+		case 3:
+			// Sizes and offsets blown up
+			copy(data[at:], []byte{0xff, 0xff, 0xff, 0x7f})
+		case 4:
+			data[at] = byte(r.Intn(256))
+		}
+	}
+	return data
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// isAdmin : Whether the request carries the admin token as
+// "Authorization: Bearer <token>". Without a configured token every
+// request is refused.
+func (api *API) isAdmin(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return len(api.Config.AdminToken) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(api.Config.AdminToken)) == 1
+}
+
+// requireAdmin : Lets a request to a v1 route through only when it comes
+// from an admin
+func (api *API) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !api.isAdmin(r) {
+			api.log(r.Context()).Warn("Unauthorized request to " + r.URL.Path)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(TemplateResponse{
+				Success: false,
+			})
+			return
+		}
+		setRequestUser(r, "admin")
+		next(w, r)
+	}
+}
+
+// requireAdminV2 : Same as requireAdmin with the v2 error body
+func (api *API) requireAdminV2(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !api.isAdmin(r) {
+			api.log(r.Context()).Warn("Unauthorized request to " + r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: &APIError{
+				Code:    CodeUnauthorized,
+				Message: "admin token required",
+			}})
+			return
+		}
+		setRequestUser(r, "admin")
+		next(w, r)
+	}
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned for contests and the submissions made in them
+var (
+	ErrNoSuchContest        = errors.New("no such contest with this ID")
+	ErrQuestionNotInContest = errors.New("the question is not part of the contest")
+	ErrContestInUse         = errors.New("submissions were made in the contest, it cannot be deleted")
+)
+
+// ContestsResponse : Every contest
+type ContestsResponse struct {
+	Contests []Contest `json:"contests"`
+}
+
+// ContestInput : Body of POST /v2/contests
+type ContestInput struct {
+	Name      string               `json:"name"`
+	Questions []primitive.ObjectID `json:"questions"`
+	Languages []primitive.ObjectID `json:"languages"` // Empty to allow every language
+}
+
+func (c ContestInput) validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Name, validation.Required),
+	)
+}
+
+// ContestPatch : Fields of a contest to change, missing ones are kept
+type ContestPatch struct {
+	Name      *string               `json:"name"`
+	Questions *[]primitive.ObjectID `json:"questions"`
+	Languages *[]primitive.ObjectID `json:"languages"` // Empty to allow every language again
+}
+
+func (p ContestPatch) validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.NilOrNotEmpty),
+	)
+}
+
+// apply : Changes the fields of the contest the patch sets
+func (p ContestPatch) apply(contest *Contest) {
+	if p.Name != nil {
+		contest.Name = *p.Name
+	}
+	if p.Questions != nil {
+		contest.Questions = *p.Questions
+	}
+	if p.Languages != nil {
+		contest.Languages = *p.Languages
+	}
+}
+
+// update : $set document of the patch
+func (p ContestPatch) update() bson.M {
+	set := bson.M{}
+	if p.Name != nil {
+		set["name"] = *p.Name
+	}
+	if p.Questions != nil {
+		set["questions"] = *p.Questions
+	}
+	if p.Languages != nil {
+		set["languages"] = *p.Languages
+	}
+	return bson.M{"$set": set}
+}
+
+// allows : Whether submissions in the contest may use the language, any
+// language outside of a contest
+func (c *Contest) allows(ID primitive.ObjectID) bool {
+	return c == nil || len(c.Languages) <= 0 || containsID(c.Languages, ID)
+}
+
+// validateContestReferences : Checks that the questions and languages of
+// the contest exist
+func (api *API) validateContestReferences(ctx context.Context, contest Contest) error {
+	fieldErrs := validation.Errors{}
+	for _, ID := range contest.Questions {
+		exists, err := api.Questions.Exists(ctx, ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			fieldErrs["questions"] = fmt.Errorf("no such question %s", ID.Hex())
+		}
+	}
+
+	languages, err := api.Languages.Find(ctx, contest.Languages)
+	if err != nil {
+		return err
+	}
+	known := make(map[primitive.ObjectID]bool)
+	for _, language := range languages {
+		known[language.ID] = true
+	}
+	for _, ID := range contest.Languages {
+		if !known[ID] {
+			fieldErrs["languages"] = fmt.Errorf("no such language %s", ID.Hex())
+		}
+	}
+	return fieldErrs.Filter()
+}
+
+// createContest : New contest from the input
+func (api *API) createContest(ctx context.Context, input ContestInput) (Contest, error) {
+	if err := input.validate(); err != nil {
+		return Contest{}, err
+	}
+
+	contest := Contest{
+		ID:        primitive.NewObjectID(),
+		Name:      input.Name,
+		Questions: input.Questions,
+		Languages: input.Languages,
+	}
+	if contest.Questions == nil {
+		contest.Questions = []primitive.ObjectID{}
+	}
+	if err := api.validateContestReferences(ctx, contest); err != nil {
+		return Contest{}, err
+	}
+	return contest, api.Contests.Insert(ctx, contest)
+}
+
+// updateContest : Applies the patch to the contest with the given ID
+func (api *API) updateContest(ctx context.Context, ID primitive.ObjectID, patch ContestPatch) (Contest, error) {
+	if err := patch.validate(); err != nil {
+		return Contest{}, err
+	}
+	contest, err := api.Contests.Get(ctx, ID)
+	if err != nil {
+		return contest, err
+	}
+	patch.apply(&contest)
+	if err = api.validateContestReferences(ctx, contest); err != nil {
+		return Contest{}, err
+	}
+	return api.Contests.Update(ctx, ID, patch)
+}
+
+// deleteContest : Removes the contest, refused while a submission was
+// made in it
+func (api *API) deleteContest(ctx context.Context, ID primitive.ObjectID) error {
+	used, err := api.Submissions.UsesContest(ctx, ID)
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrContestInUse
+	}
+	return api.Contests.Delete(ctx, ID)
+}
+
+// questionContest : Contest with the "contest_id" given for a submission
+// to the question, nil when none is given
+func (api *API) questionContest(ctx context.Context, question Question, contestID string) (*Contest, error) {
+	if len(contestID) <= 0 {
+		return nil, nil
+	}
+	ID, err := parseID(contestID)
+	if err != nil {
+		return nil, validation.Errors{"contest_id": err}
+	}
+	contest, err := api.Contests.Get(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if !containsID(contest.Questions, question.ID) {
+		return nil, fmt.Errorf("%w: %s is not part of %s", ErrQuestionNotInContest, question.Name, contest.Name)
+	}
+	return &contest, nil
+}
+
+func (api *API) listContestsV2(w http.ResponseWriter, r *http.Request) {
+	contests, err := api.Contests.List(r.Context())
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ContestsResponse{Contests: contests})
+}
+
+func (api *API) createContestV2(w http.ResponseWriter, r *http.Request) {
+	var reqBody ContestInput
+	if err := decodeJSON(r, &reqBody); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	contest, err := api.createContest(r.Context(), reqBody)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v2/contests/"+contest.ID.Hex())
+	writeJSON(w, http.StatusCreated, contest)
+}
+
+func (api *API) getContestV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	contest, err := api.Contests.Get(r.Context(), ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, contest)
+}
+
+func (api *API) updateContestV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	var patch ContestPatch
+	if err = decodeJSON(r, &patch); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	contest, err := api.updateContest(r.Context(), ID, patch)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, contest)
+}
+
+func (api *API) deleteContestV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.deleteContest(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
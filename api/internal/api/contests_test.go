@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestContestRoutes(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1")
+	input := ContestInput{Name: "Qualifier", Questions: []primitive.ObjectID{question.ID}, Languages: []primitive.ObjectID{python.ID}}
+
+	expectError(t, s.json(http.MethodPost, "/v2/contests", input, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodPost, "/v2/contests", ContestInput{}, true), http.StatusUnprocessableEntity, CodeValidationFailed)
+	expectError(t, s.json(http.MethodPost, "/v2/contests", ContestInput{Name: "Qualifier", Questions: []primitive.ObjectID{primitive.NewObjectID()}}, true),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+	expectError(t, s.json(http.MethodPost, "/v2/contests", ContestInput{Name: "Qualifier", Languages: []primitive.ObjectID{primitive.NewObjectID()}}, true),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	var contest Contest
+	rec := s.json(http.MethodPost, "/v2/contests", input, true)
+	expectJSON(t, rec, http.StatusCreated, &contest)
+	if rec.Header().Get("Location") != "/v2/contests/"+contest.ID.Hex() {
+		t.Fatalf("location %q", rec.Header().Get("Location"))
+	}
+
+	var listed ContestsResponse
+	expectJSON(t, s.get("/v2/contests", false), http.StatusOK, &listed)
+	if len(listed.Contests) != 1 || listed.Contests[0].ID != contest.ID {
+		t.Fatalf("contests %v", listed.Contests)
+	}
+	var got Contest
+	expectJSON(t, s.get("/v2/contests/"+contest.ID.Hex(), false), http.StatusOK, &got)
+	if got.Name != "Qualifier" || len(got.Questions) != 1 || len(got.Languages) != 1 {
+		t.Fatalf("contest %+v", got)
+	}
+	expectError(t, s.get("/v2/contests/"+primitive.NewObjectID().Hex(), false), http.StatusNotFound, CodeNotFound)
+
+	name := "Final"
+	everything := []primitive.ObjectID{}
+	expectError(t, s.json(http.MethodPatch, "/v2/contests/"+contest.ID.Hex(), ContestPatch{Name: &name}, false), http.StatusUnauthorized, CodeUnauthorized)
+	var patched Contest
+	expectJSON(t, s.json(http.MethodPatch, "/v2/contests/"+contest.ID.Hex(), ContestPatch{Name: &name, Languages: &everything}, true), http.StatusOK, &patched)
+	if patched.Name != "Final" || len(patched.Languages) != 0 || len(patched.Questions) != 1 {
+		t.Fatalf("patched contest %+v", patched)
+	}
+
+	// Questions and languages a contest holds are not deleted for good
+	languages := []primitive.ObjectID{python.ID}
+	expectStatus(t, s.json(http.MethodPatch, "/v2/contests/"+contest.ID.Hex(), ContestPatch{Languages: &languages}, true), http.StatusOK)
+	expectError(t, s.json(http.MethodDelete, "/v2/questions/"+question.ID.Hex()+"?hard=true", nil, true), http.StatusConflict, CodeConflict)
+	expectError(t, s.json(http.MethodDelete, "/v2/languages/"+python.ID.Hex()+"?hard=true", nil, true), http.StatusConflict, CodeConflict)
+
+	expectError(t, s.json(http.MethodDelete, "/v2/contests/"+contest.ID.Hex(), nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectStatus(t, s.json(http.MethodDelete, "/v2/contests/"+contest.ID.Hex(), nil, true), http.StatusNoContent)
+	expectError(t, s.json(http.MethodDelete, "/v2/contests/"+contest.ID.Hex(), nil, true), http.StatusNotFound, CodeNotFound)
+	expectStatus(t, s.json(http.MethodDelete, "/v2/languages/"+python.ID.Hex()+"?hard=true", nil, true), http.StatusNoContent)
+}
+
+func TestContestLanguages(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	ruby := s.language("Ruby")
+	question := s.question("Echo", "1")
+	other := s.question("Other", "1")
+	var contest Contest
+	expectJSON(t, s.json(http.MethodPost, "/v2/contests", ContestInput{
+		Name:      "Qualifier",
+		Questions: []primitive.ObjectID{question.ID},
+		Languages: []primitive.ObjectID{python.ID},
+	}, true), http.StatusCreated, &contest)
+	path := "/v2/questions/" + question.ID.Hex() + "/languages"
+	inContest := "?contest_id=" + contest.ID.Hex()
+
+	// The question alone takes both, the contest only one of them
+	var accepted QuestionLanguagesResponse
+	expectJSON(t, s.get(path, false), http.StatusOK, &accepted)
+	if len(accepted.Languages) != 2 {
+		t.Fatalf("accepts %v", accepted.Languages)
+	}
+	expectJSON(t, s.get(path+inContest, false), http.StatusOK, &accepted)
+	if len(accepted.Languages) != 1 || accepted.Languages[0].ID != python.ID {
+		t.Fatalf("accepts %v in the contest", accepted.Languages)
+	}
+	expectStatus(t, s.get(path+"/"+ruby.ID.Hex(), false), http.StatusOK)
+	expectError(t, s.get(path+"/"+ruby.ID.Hex()+inContest, false), http.StatusBadRequest, CodeInvalidRequest)
+	expectError(t, s.get("/v2/questions/"+other.ID.Hex()+"/languages"+inContest, false), http.StatusBadRequest, CodeInvalidRequest)
+	expectError(t, s.get(path+"?contest_id=nope", false), http.StatusUnprocessableEntity, CodeValidationFailed)
+	expectError(t, s.get(path+"?contest_id="+primitive.NewObjectID().Hex(), false), http.StatusNotFound, CodeNotFound)
+
+	submit := func(question Question, language Language, contestID string) SubmissionInput {
+		return SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: language.ID.Hex(), ContestID: contestID, Source: "x"}
+	}
+	rec := s.json(http.MethodPost, "/v2/submissions", submit(question, ruby, contest.ID.Hex()), false)
+	expectError(t, rec, http.StatusBadRequest, CodeInvalidRequest)
+	if !strings.Contains(rec.Body.String(), "Ruby is not allowed in Qualifier, use one of Python") {
+		t.Fatalf("refusal %s", rec.Body.String())
+	}
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", submit(other, python, contest.ID.Hex()), false), http.StatusBadRequest, CodeInvalidRequest)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", submit(question, python, primitive.NewObjectID().Hex()), false), http.StatusNotFound, CodeNotFound)
+
+	// The question still narrows down what the contest allows
+	languages := []primitive.ObjectID{ruby.ID}
+	expectStatus(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Languages: &languages}, true), http.StatusOK)
+	rec = s.json(http.MethodPost, "/v2/submissions", submit(question, python, contest.ID.Hex()), false)
+	expectError(t, rec, http.StatusBadRequest, CodeInvalidRequest)
+	if !strings.Contains(rec.Body.String(), "Python is not allowed for Echo") {
+		t.Fatalf("refusal %s", rec.Body.String())
+	}
+	languages = []primitive.ObjectID{}
+	expectStatus(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Languages: &languages}, true), http.StatusOK)
+
+	var submission Submission
+	expectJSON(t, s.json(http.MethodPost, "/v2/submissions", submit(question, python, contest.ID.Hex()), false), http.StatusCreated, &submission)
+	if submission.ContestID == nil || *submission.ContestID != contest.ID {
+		t.Fatalf("submission in contest %v", submission.ContestID)
+	}
+	expectStatus(t, s.json(http.MethodPost, "/v2/submissions", submit(question, ruby, ""), false), http.StatusCreated)
+
+	// Contests keep the submissions made in them
+	expectError(t, s.json(http.MethodDelete, "/v2/contests/"+contest.ID.Hex(), nil, true), http.StatusConflict, CodeConflict)
+}
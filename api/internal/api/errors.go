@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error codes of the v2 API, stable for clients to switch on
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+// ErrInvalidID : IDs are 24 hexadecimal characters
+var ErrInvalidID = errors.New("invalid ID")
+
+// APIError : Error body of the v2 API
+type APIError struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Report  *ArchiveReport    `json:"report,omitempty"`
+	cause   error
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// ErrorResponse : Body of every failed v2 request
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+// badRequest : The request itself is wrong, err says how
+func badRequest(err error) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: err.Error(), cause: err}
+}
+
+// Errors caused by the request rather than by the server
+var (
+	notFoundErrors = []error{
+		ErrInvalidID, ErrNoSuchQuestion, ErrNoSuchTestcase, ErrNoSuchLanguage, ErrNoSuchRejudge, ErrNoSuchSubmission, ErrNoSuchContest, mongo.ErrNoDocuments,
+	}
+	conflictErrors = []error{
+		ErrLastTestcaseLeft, ErrNoProbe, ErrSelfTestFailed, ErrStillReferenced, ErrNotDeleted, ErrContestInUse,
+	}
+	requestErrors = []error{
+		ErrUnsupportedArchive, ErrTooManyEntries, ErrFileTooLarge, ErrTotalTooLarge, ErrCompressionRatio,
+		ErrUnsafeName, ErrNotRegularFile, ErrMissingTestcase, ErrInvalidReorder, ErrEmptyRejudgeFilter,
+		ErrInvalidTestcaseFile, ErrLanguageNotAllowed, ErrLanguageDisabled, ErrQuestionNotInContest, http.ErrMissingFile, http.ErrNotMultipart,
+	}
+)
+
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// toAPIError : Status, code and message err is reported with. Errors not
+// known to come from the request are internal and their details are kept
+// out of the response.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		apiErr = &APIError{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    CodeValidationFailed,
+			Message: "request failed validation",
+			Fields:  map[string]string{},
+			cause:   err,
+		}
+		for field, fieldErr := range fieldErrs {
+			apiErr.Fields[field] = fieldErr.Error()
+		}
+		return apiErr
+	}
+
+	switch {
+	case isAny(err, notFoundErrors):
+		return &APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: err.Error(), cause: err}
+	case isAny(err, conflictErrors):
+		return &APIError{Status: http.StatusConflict, Code: CodeConflict, Message: err.Error(), cause: err}
+	case errors.Is(err, ErrArchiveTooLarge):
+		return &APIError{Status: http.StatusRequestEntityTooLarge, Code: CodePayloadTooLarge, Message: err.Error(), cause: err}
+	case errors.Is(err, ErrShuttingDown):
+		return &APIError{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: err.Error(), cause: err}
+	case isAny(err, requestErrors):
+		return badRequest(err)
+	}
+	return &APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error", cause: err}
+}
+
+// parseID : ObjectID from its hex form
+func parseID(id string) (primitive.ObjectID, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ID, ErrInvalidID
+	}
+	return ID, nil
+}
+
+// writeJSON : Sends body with the given status
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError : Logs err and sends it as a v2 error body
+func (api *API) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		api.log(r.Context()).Error(err.Error())
+	} else {
+		api.log(r.Context()).Info(err.Error())
+	}
+	if apiErr.Status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "5")
+	}
+	writeJSON(w, apiErr.Status, ErrorResponse{Error: apiErr})
+}
+
+// writeV1Error : Logs err and sends it the way the v1 routes always did,
+// a 400 with a TemplateResponse, now carrying the message
+func (api *API) writeV1Error(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		api.log(r.Context()).Error(err.Error())
+	} else {
+		api.log(r.Context()).Info(err.Error())
+	}
+	status := http.StatusBadRequest
+	if apiErr.Status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "5")
+		status = apiErr.Status
+	}
+	message := apiErr.Message
+	if apiErr.Code == CodeValidationFailed {
+		message = apiErr.cause.Error()
+	}
+	writeJSON(w, status, TemplateResponse{
+		Success: false,
+		Error:   message,
+	})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// copyFileToZip : Adds the file at source to the zip under name
+func copyFileToZip(zipw *zip.Writer, name string, source string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zipw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// ErrInvalidTestcaseFile : Testcases are made of an input and an output
+var ErrInvalidTestcaseFile = errors.New("file should be input or output")
+
+// sendTestcaseFile : Streams the input or output file of a testcase
+func (api *API) sendTestcaseFile(w http.ResponseWriter, r *http.Request, question Question, index int, file string) error {
+	// Either the "input" or the "output" of the testcase
+	var filePath string
+	switch file {
+	case "input":
+		filePath = api.inputPath(question.ID, index)
+	case "output":
+		filePath = api.outputPath(question.ID, index)
+	default:
+		return ErrInvalidTestcaseFile
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s%d.txt\"", file, index))
+	if _, err = io.Copy(w, f); err != nil {
+		api.log(r.Context()).Warn(err.Error())
+	}
+	return nil
+}
+
+func (api *API) downloadTestcaseHandler(w http.ResponseWriter, r *http.Request) {
+	question, err := api.findQuestion(r)
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+	index, err := testcaseIndex(r.FormValue("index"), question.testcaseList())
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	if err = api.sendTestcaseFile(w, r, question, index, r.FormValue("file")); err != nil {
+		api.writeV1Error(w, r, err)
+	}
+}
+
+// sendQuestionExport : Streams the question as a zip that /addQuestion
+// takes back as is. Files keep the numbers the setter gave them and the
+// manifest carries the order, names, time limit and question name.
+func (api *API) sendQuestionExport(w http.ResponseWriter, r *http.Request, question Question) {
+	manifest := testcaseManifest{
+		Name: question.Name,
+		Time: question.Time,
+	}
+	for _, testcase := range question.testcaseList() {
+		manifest.Testcases = append(manifest.Testcases, manifestTestcase{
+			Number:      testcase.Number,
+			Name:        testcase.Name,
+			Description: testcase.Description,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"question-%s.zip\"", question.ID.Hex()))
+
+	// Headers are out once the zip starts, later failures are only logged
+	zipw := zip.NewWriter(w)
+	defer zipw.Close()
+
+	dst, err := zipw.Create(manifestFile)
+	if err == nil {
+		err = json.NewEncoder(dst).Encode(manifest)
+	}
+	for _, testcase := range question.testcaseList() {
+		if err != nil {
+			break
+		}
+		err = copyFileToZip(zipw, fmt.Sprintf("input/input%d.txt", testcase.Number), api.inputPath(question.ID, testcase.Index))
+		if err == nil {
+			err = copyFileToZip(zipw, fmt.Sprintf("output/output%d.txt", testcase.Number), api.outputPath(question.ID, testcase.Index))
+		}
+	}
+	if err != nil {
+		api.log(r.Context()).Error(err.Error())
+	}
+}
+
+func (api *API) exportQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	question, err := api.findQuestion(r)
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	api.sendQuestionExport(w, r, question)
+}
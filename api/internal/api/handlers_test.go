@@ -0,0 +1,280 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+
+	"judge-two/internal/config"
+	"judge-two/internal/judge"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testToken : Admin token of the test servers
+const testToken = "test-token"
+
+// testServer : API on the memory stores, with its testcases in a folder
+// removed at the end of the test
+type testServer struct {
+	*API
+	t *testing.T
+}
+
+func newTestServer(t *testing.T) *testServer {
+	dir, err := ioutil.TempDir("", "judge-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	cfg := config.Default()
+	cfg.AdminToken = testToken
+	cfg.Storage.Path = dir
+	cfg.Log.Level = "error"
+	return &testServer{API: NewMemoryAPI(cfg), t: t}
+}
+
+// serve : Response to the request, sent as an admin when admin is set
+func (s *testServer) serve(r *http.Request, admin bool) *httptest.ResponseRecorder {
+	if admin {
+		r.Header.Set("Authorization", "Bearer "+testToken)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, r)
+	return rec
+}
+
+// get : Response to a GET of target
+func (s *testServer) get(target string, admin bool) *httptest.ResponseRecorder {
+	return s.serve(httptest.NewRequest(http.MethodGet, target, nil), admin)
+}
+
+// json : Response to a request with body encoded as JSON, or no body when
+// body is nil
+func (s *testServer) json(method string, target string, body interface{}, admin bool) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	r.Header.Set("Content-Type", "application/json")
+	return s.serve(r, admin)
+}
+
+// form : Response to a multipart form of the fields and files, files being
+// named after their field with a .txt extension unless the field is
+// "testcases", which holds a zip
+func (s *testServer) form(method string, target string, fields map[string]string, files map[string][]byte, admin bool) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	for name, content := range files {
+		filename := name + ".txt"
+		if name == "testcases" {
+			filename = "testcases.zip"
+		}
+		part, err := w.CreateFormFile(name, filename)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		s.t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(method, target, &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return s.serve(r, admin)
+}
+
+// language : Language stored for the test, running python3
+func (s *testServer) language(name string) Language {
+	rec := s.json(http.MethodPost, "/v2/languages", LanguageInput{
+		Name:     name,
+		Time:     1,
+		Filename: "main.py",
+		Execute:  judge.Command{Argv: []string{"python3", "{src}"}},
+	}, true)
+	var language Language
+	expectJSON(s.t, rec, http.StatusCreated, &language)
+	return language
+}
+
+// probedLanguage : Language run by the interpreter with a probe printing
+// back its input
+func (s *testServer) probedLanguage(name string, interpreter string) Language {
+	rec := s.json(http.MethodPost, "/v2/languages", LanguageInput{
+		Name:     name,
+		Time:     1,
+		Filename: "main.sh",
+		Execute:  judge.Command{Argv: []string{interpreter, "{src}"}},
+		Probe:    &LanguageProbe{Source: "cat\n"},
+	}, true)
+	var language Language
+	expectJSON(s.t, rec, http.StatusCreated, &language)
+	return language
+}
+
+// question : Question stored for the test with a testcase per input,
+// each expecting its input back
+func (s *testServer) question(name string, inputs ...string) Question {
+	rec := s.form(http.MethodPost, "/v2/questions", map[string]string{"name": name, "time": "2"},
+		map[string][]byte{"testcases": testcasesZip(s.t, inputs...)}, true)
+	var response QuestionCreatedResponse
+	expectJSON(s.t, rec, http.StatusCreated, &response)
+	return *response.Question
+}
+
+// submissions : Memory store of the submissions, to put judged ones in
+func (s *testServer) submissions() *MemorySubmissionRepo {
+	return s.Submissions.(*MemorySubmissionRepo)
+}
+
+// testcasesZip : Zip in the standard layout whose testcase n has the n-th
+// input as both its input and its output
+func testcasesZip(t *testing.T, inputs ...string) []byte {
+	files := map[string]string{}
+	for i, input := range inputs {
+		files["input/input"+strconv.Itoa(i+1)+".txt"] = input
+		files["output/output"+strconv.Itoa(i+1)+".txt"] = input
+	}
+	return zipOf(t, files)
+}
+
+// zipOf : Zip holding the files, by name
+func zipOf(t *testing.T, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, files[name])
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// zipFiles : Content of every file of the zip, by name
+func zipFiles(t *testing.T, raw []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+// expectStatus : Fails the test unless the response has the status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status %d, expected %d: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+// expectJSON : Fails the test unless the response has the status, then
+// decodes its body into v
+func expectJSON(t *testing.T, rec *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	expectStatus(t, rec, status)
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+// expectError : Fails the test unless the response is a v2 error with the
+// status and code
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var response ErrorResponse
+	expectJSON(t, rec, status, &response)
+	if response.Error == nil || response.Error.Code != code {
+		t.Fatalf("error %s, expected code %q", rec.Body.String(), code)
+	}
+}
+
+// expectV1 : Fails the test unless the response is a v1 body with the
+// status and success, then decodes it into v when v is set
+func expectV1(t *testing.T, rec *httptest.ResponseRecorder, status int, success bool, v interface{}) {
+	t.Helper()
+	var response TemplateResponse
+	expectJSON(t, rec, status, &response)
+	if response.Success != success {
+		t.Fatalf("success %v, expected %v: %s", response.Success, success, rec.Body.String())
+	}
+	if v != nil {
+		json.Unmarshal(rec.Body.Bytes(), v)
+	}
+}
+
+func TestMonitoringRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	var health HealthResponse
+	expectJSON(t, s.get("/healthz", false), http.StatusOK, &health)
+	if health.Status != healthOK {
+		t.Fatalf("liveness %q, expected %q", health.Status, healthOK)
+	}
+
+	// Without MongoDB the readiness fails on it alone
+	expectJSON(t, s.get("/readyz", false), http.StatusServiceUnavailable, &health)
+	for _, component := range health.Components {
+		failed := component.Status != healthOK
+		if failed != (component.Name == "mongo") {
+			t.Fatalf("component %s is %s: %s", component.Name, component.Status, component.Error)
+		}
+	}
+
+	expectStatus(t, s.get("/metrics", false), http.StatusOK)
+
+	var spec map[string]interface{}
+	expectJSON(t, s.get("/openapi.json", false), http.StatusOK, &spec)
+	if _, ok := spec["paths"]; !ok {
+		t.Fatal("spec has no paths")
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	s := newTestServer(t)
+	expectStatus(t, s.get("/v2/nothing", true), http.StatusNotFound)
+	expectStatus(t, s.get("/v2/questions/"+primitive.NewObjectID().Hex()+"/testcases/x/input", true), http.StatusNotFound)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.uber.org/zap"
+)
+
+// Health statuses of the process and of its components
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// healthCheckTimeout : Time a single readiness check may take
+const healthCheckTimeout = 2 * time.Second
+
+// errNoDatabase : The stores live in memory, there is no database to reach
+var errNoDatabase = errors.New("no database connection")
+
+// healthCheck : Dependency checked before the process reports ready.
+// Workers add their own, such as a sandbox capability check.
+type healthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ComponentHealth : Result of a single health check
+type ComponentHealth struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// HealthResponse : Body of /healthz and /readyz
+type HealthResponse struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components,omitempty"`
+}
+
+// readinessChecks : Dependencies the process needs to serve requests, or
+// on workers to judge submissions
+func (api *API) readinessChecks() []healthCheck {
+	return append([]healthCheck{
+		{Name: "shutdown", Check: api.checkShutdown},
+		{Name: "mongo", Check: api.checkMongo},
+		{Name: "storage", Check: api.checkStorage},
+	}, api.workerChecks...)
+}
+
+// checkShutdown : A process shutting down takes no new requests
+func (api *API) checkShutdown(ctx context.Context) error {
+	if api.shuttingDown() {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// checkMongo : Any member of the replica set answering is enough to serve
+// reads, writes fail on their own until a primary is elected
+func (api *API) checkMongo(ctx context.Context) error {
+	if api.Db == nil {
+		return errNoDatabase
+	}
+	return api.Db.Client().Ping(ctx, readpref.PrimaryPreferred())
+}
+
+// checkSandbox : The sandbox of the worker can run a program at all
+func (api *API) checkSandbox(ctx context.Context) error {
+	_, err := api.sandbox().Version(ctx, []string{"true"})
+	return err
+}
+
+// checkStorage : Testcases can be written to the storage folder
+func (api *API) checkStorage(ctx context.Context) error {
+	if err := os.MkdirAll(api.Storage, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(api.Storage, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// runHealthChecks : Runs the checks concurrently, each with its own timeout
+func runHealthChecks(ctx context.Context, checks []healthCheck) HealthResponse {
+	response := HealthResponse{
+		Status:     healthOK,
+		Components: make([]ComponentHealth, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			component := ComponentHealth{
+				Name:    check.Name,
+				Status:  healthOK,
+				Latency: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				component.Status = healthFail
+				component.Error = err.Error()
+			}
+			response.Components[i] = component
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, component := range response.Components {
+		if component.Status != healthOK {
+			response.Status = healthFail
+		}
+	}
+	return response
+}
+
+// livenessHandler : The process is up and serving requests, dependencies
+// are left to the readiness probe so an outage does not restart every pod
+func (api *API) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(HealthResponse{
+		Status: healthOK,
+	})
+}
+
+// readinessHandler : Every dependency of the process is usable
+func (api *API) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	response := runHealthChecks(r.Context(), api.readinessChecks())
+	for _, component := range response.Components {
+		if component.Status != healthOK {
+			api.log(r.Context()).Warn("Readiness check failed", zap.String("component", component.Name), zap.String("error", component.Error))
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if response.Status != healthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
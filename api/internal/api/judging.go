@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"judge-two/internal/config"
+	"judge-two/internal/judge"
+	"judge-two/internal/metrics"
+	"judge-two/internal/tracing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+	"go.uber.org/zap"
+)
+
+// Errors returned by workers
+var (
+	ErrNoSandbox      = errors.New("workers only run solutions inside a sandbox, set judge.sandbox")
+	ErrNoSlots        = errors.New("workers judge at least one submission at once, set judge.workers")
+	errNothingToJudge = errors.New("nothing left to judge")
+	errClaimLost      = errors.New("another worker took the job over")
+)
+
+// pollInterval : Time a worker slot waits before looking at the queue
+// again once it found it empty or failed a job
+const pollInterval = time.Second
+
+// sandbox : Sandbox the worker runs solutions and probes in
+func (api *API) sandbox() judge.Sandbox {
+	return judge.Sandbox(api.Config.Judge.Sandbox)
+}
+
+// StartWorker : Returns a worker judging queued submissions inside the
+// sandbox, or an error when there is no sandbox, the database cannot be
+// reached or its schema is outdated
+func StartWorker(cfg config.Config) (*API, error) {
+	if len(cfg.Judge.Sandbox) <= 0 {
+		return nil, ErrNoSandbox
+	}
+	if cfg.Judge.Workers < 1 {
+		return nil, ErrNoSlots
+	}
+
+	api := newAPI(cfg)
+	api.mountLogger()
+	api.mountTracing(workerServiceName)
+	api.mountWorkerRouter()
+	if err := api.mountDatabase(); err != nil {
+		api.stopTracing(context.Background())
+		api.Log.Sync()
+		return nil, err
+	}
+
+	return api, nil
+}
+
+// mountWorkerRouter : Workers only serve their health and metrics
+func (api *API) mountWorkerRouter() {
+	api.Router = mux.NewRouter()
+	api.Router.Use(recordRoute)
+	api.Router.Use(measureRequests)
+	api.Router.Use(jsonResponse)
+
+	api.Router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	api.Router.HandleFunc("/healthz", api.livenessHandler).Methods("GET")
+	api.Router.HandleFunc("/readyz", api.readinessHandler).Methods("GET")
+}
+
+// RunWorker : Advertises the languages the worker passes the self-test of
+// and judges jobs in them until SIGINT or SIGTERM. Judgements in flight
+// get until the shutdown timeout to finish, those still running are put
+// back in the queue for another worker.
+func (api *API) RunWorker() error {
+	ad := api.newAdvertisement()
+	api.workerChecks = []healthCheck{
+		{Name: "sandbox", Check: api.checkSandbox},
+		{Name: "advertisement", Check: ad.checkAdvertised(api.heartbeatInterval())},
+	}
+
+	server := &http.Server{
+		Addr:         api.Config.Listen,
+		WriteTimeout: 30 * time.Second,
+		ReadTimeout:  30 * time.Second,
+		Handler:      api.Handler(),
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		api.Log.Info("Listening on " + api.Config.Listen)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChannel)
+
+	// Slots stop claiming as soon as shutdown starts, the judgements they
+	// are running are only aborted at the shutdown deadline
+	claimCtx, stopClaiming := context.WithCancel(context.Background())
+	judgeCtx, abortJudgements := context.WithCancel(context.Background())
+	defer abortJudgements()
+
+	tested := make(capabilities)
+	passed := make(map[primitive.ObjectID]bool)
+	api.discover(claimCtx, ad, tested, passed)
+	api.advertise(claimCtx, ad)
+
+	var slots sync.WaitGroup
+	slots.Add(1)
+	go func() {
+		defer slots.Done()
+		api.runAdvertisements(claimCtx, ad, tested, passed)
+	}()
+	for i := 0; i < api.Config.Judge.Workers; i++ {
+		slots.Add(1)
+		go func() {
+			defer slots.Done()
+			api.judgeJobs(claimCtx, judgeCtx, ad)
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-serverErr:
+		api.Log.Error("Server stopped", zap.Error(err))
+	case sig := <-signalChannel:
+		api.Log.Info("Shutting down", zap.String("signal", sig.String()))
+	}
+
+	atomic.StoreInt32(&api.draining, 1)
+	stopClaiming()
+	done := make(chan struct{})
+	go func() {
+		slots.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Duration(api.Config.ShutdownTimeout) * time.Second):
+		api.Log.Warn("Judgements still running at the shutdown deadline, putting them back in the queue")
+		abortJudgements()
+		<-done
+	}
+	api.retireWorker(ad.current())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if shutdownErr := server.Shutdown(ctx); shutdownErr != nil {
+		api.Log.Error("Health requests still running at the shutdown deadline", zap.Error(shutdownErr))
+	}
+	if dbErr := api.Db.Client().Disconnect(ctx); dbErr != nil {
+		api.Log.Error("Database disconnection failed", zap.Error(dbErr))
+	}
+	if traceErr := api.stopTracing(ctx); traceErr != nil {
+		api.Log.Error("Flushing traces failed", zap.Error(traceErr))
+	}
+
+	api.Log.Info("bye")
+	api.Log.Sync()
+	return err
+}
+
+// judgeJobs : Claims jobs in the languages the worker advertises and
+// judges them one at a time until claimCtx ends. Judgements run under
+// judgeCtx, so the one in flight at shutdown gets to finish.
+func (api *API) judgeJobs(claimCtx context.Context, judgeCtx context.Context, ad *advertisement) {
+	for claimCtx.Err() == nil {
+		worker := ad.current()
+		job, err := api.Jobs.Claim(claimCtx, worker.ID, worker.Languages)
+		if err == nil && api.runJob(judgeCtx, worker, job) {
+			continue
+		}
+		if err != nil && !errors.Is(err, errQueueEmpty) && claimCtx.Err() == nil {
+			api.Log.Error("Claiming a job failed", zap.String("worker", worker.ID), zap.Error(err))
+		}
+
+		select {
+		case <-claimCtx.Done():
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// runJob : Judges the job and records the verdict, or puts the job back
+// when the worker failed rather than the solution. A worker whose claim
+// expired meanwhile leaves the verdict to the one that took the job over.
+// Whether the job left the queue is returned.
+func (api *API) runJob(ctx context.Context, worker Worker, job JudgeJob) bool {
+	// The judgement continues the trace of the request that queued it
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, job.Trace), "judge submission", trace.WithAttributes(
+		label.String("judge.submission", job.SubmissionID.Hex()),
+		label.String("judge.worker", worker.ID),
+	))
+	defer span.End()
+	log := api.log(ctx).With(zap.String("worker", worker.ID), zap.String("submission", job.SubmissionID.Hex()))
+
+	err := api.Submissions.Claim(ctx, job.SubmissionID, *job.ClaimID)
+	var submission Submission
+	var language Language
+	if err == nil {
+		submission, language, err = api.judgeSubmission(ctx, job)
+	}
+	if err == nil {
+		err = api.Submissions.SetVerdict(ctx, submission, *job.ClaimID)
+	}
+	switch {
+	case errors.Is(err, errClaimLost):
+		log.Warn("Claim lost, dropping the verdict", zap.Error(err))
+		return false
+	case err == nil:
+		span.SetAttributes(label.String("judge.verdict", submission.Verdict))
+		metrics.Submissions.WithLabelValues(submission.Verdict, language.Name).Inc()
+		log.Info("Submission judged", zap.String("verdict", submission.Verdict))
+	case errors.Is(err, errNothingToJudge):
+		log.Warn("Dropping job", zap.Error(err))
+	default:
+		span.SetStatus(codes.Error, err.Error())
+		log.Error("Judging failed, putting the job back in the queue", zap.Error(err))
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err = api.Jobs.Release(releaseCtx, job.ID, worker.ID); err != nil {
+			log.Error("Putting the job back failed", zap.Error(err))
+		}
+		return false
+	}
+
+	if err = api.Jobs.Finish(ctx, job.ID, worker.ID); err != nil {
+		log.Error("Removing the judged job failed", zap.Error(err))
+	}
+	return true
+}
+
+// judgeSubmission : Compiles the submission of the job inside the sandbox
+// and runs it on every testcase of its question in display order. The
+// verdict is the one of the first testcase not accepted. The language the
+// submission was judged in comes along.
+func (api *API) judgeSubmission(ctx context.Context, job JudgeJob) (Submission, Language, error) {
+	found, err := api.Submissions.Find(ctx, SubmissionFilter{IDs: []primitive.ObjectID{job.SubmissionID}})
+	if err != nil {
+		return Submission{}, Language{}, err
+	}
+	if len(found) <= 0 {
+		return Submission{}, Language{}, fmt.Errorf("%w: the submission was deleted", errNothingToJudge)
+	}
+	submission := found[0]
+	// Submissions queued before their question or language was soft
+	// deleted are still judged
+	question, err := api.Questions.GetIncludingDeleted(ctx, submission.QuestionID)
+	if errors.Is(err, ErrNoSuchQuestion) {
+		err = fmt.Errorf("%w: %v", errNothingToJudge, err)
+	}
+	if err != nil {
+		return submission, Language{}, err
+	}
+	language, err := api.Languages.GetIncludingDeleted(ctx, submission.LanguageID)
+	if errors.Is(err, ErrNoSuchLanguage) {
+		err = fmt.Errorf("%w: %v", errNothingToJudge, err)
+	}
+	if err != nil {
+		return submission, language, err
+	}
+
+	dir, err := ioutil.TempDir("", "submission-")
+	if err != nil {
+		return submission, language, err
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "source")
+	if err = ioutil.WriteFile(source, []byte(submission.Source), 0644); err != nil {
+		return submission, language, err
+	}
+
+	submission.Testcases = map[int]string{}
+	submission.Results = []TestcaseResult{}
+	start := time.Now()
+	program, err := api.sandbox().Compile(ctx, language.runnerLanguage(), source)
+	metrics.CompileDuration.WithLabelValues(language.Name).Observe(time.Since(start).Seconds())
+	var compileErr *judge.CompileError
+	if errors.As(err, &compileErr) {
+		submission.Verdict = VerdictCompileError
+		return submission, language, nil
+	}
+	if err != nil {
+		return submission, language, sandboxFailure(ctx, "compile", err)
+	}
+	defer program.Close()
+
+	submission.Verdict = VerdictAccepted
+	limit := question.timeLimit(language)
+	for _, testcase := range question.Testcases {
+		files := judge.Testcase{Input: api.inputPath(question.ID, testcase.Index), Output: api.outputPath(question.ID, testcase.Index)}
+		result, err := program.Run(ctx, files, limit)
+		if err != nil {
+			return submission, language, sandboxFailure(ctx, "run", err)
+		}
+		metrics.RunDuration.WithLabelValues(language.Name).Observe(result.Time.Seconds())
+		submission.Testcases[testcase.Index] = result.Verdict
+		submission.Results = append(submission.Results, TestcaseResult{
+			Index:   testcase.Index,
+			Number:  testcase.Number,
+			Verdict: result.Verdict,
+			Time:    result.Time.Milliseconds(),
+		})
+		if submission.Verdict == VerdictAccepted && result.Verdict != VerdictAccepted {
+			submission.Verdict = result.Verdict
+		}
+	}
+	return submission, language, nil
+}
+
+// sandboxFailure : Counts a failure of the sandbox itself at the stage,
+// unless the judgement was aborted
+func sandboxFailure(ctx context.Context, stage string, err error) error {
+	if ctx.Err() == nil {
+		metrics.SandboxFailures.WithLabelValues(stage).Inc()
+	}
+	return fmt.Errorf("%s: %w", stage, err)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestJudgeAfterSoftDelete(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh on this host")
+	}
+	s := newTestServer(t)
+	shell := s.probedLanguage("Shell", "sh")
+	question := s.question("Echo", "1", "2")
+	input := SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: shell.ID.Hex(), Source: "cat\n"}
+	var submission Submission
+	expectJSON(t, s.json(http.MethodPost, "/v2/submissions", input, false), http.StatusCreated, &submission)
+
+	// Both go to the trash while the submission waits in the queue
+	expectStatus(t, s.json(http.MethodDelete, "/v2/questions/"+question.ID.Hex(), nil, true), http.StatusNoContent)
+	expectStatus(t, s.json(http.MethodDelete, "/v2/languages/"+shell.ID.Hex(), nil, true), http.StatusNoContent)
+
+	worker := Worker{ID: "worker", Languages: []primitive.ObjectID{shell.ID}}
+	job, err := s.Jobs.Claim(context.Background(), worker.ID, worker.Languages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.runJob(context.Background(), worker, job) {
+		t.Fatal("job left in the queue")
+	}
+	judged, err := s.Submissions.Get(context.Background(), submission.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if judged.Verdict != VerdictAccepted || len(judged.Results) != 2 {
+		t.Fatalf("judged %s with %+v", judged.Verdict, judged.Results)
+	}
+	queued, err := s.Jobs.Queued(context.Background(), []primitive.ObjectID{submission.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued[submission.ID] {
+		t.Fatal("judged job still queued")
+	}
+}
@@ -1,15 +1,25 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+
+	"judge-two/internal/judge"
 
 	validation "github.com/go-ozzo/ozzo-validation/v3"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// AddLanguageRequest : Add language support
+// ErrNoSuchLanguage : No language has the given ID
+var ErrNoSuchLanguage = errors.New("no such language with this ID")
+
+// AddLanguageRequest : Add language support, described by command strings
+// as the first language documents were
 type AddLanguageRequest struct {
 	Name     string `json:"name"`
 	Time     int    `json:"time"`
@@ -28,13 +38,26 @@ func (r AddLanguageRequest) validate() error {
 	)
 }
 
+// input : Structured form of the language
+func (r AddLanguageRequest) input() LanguageInput {
+	compile, execute := legacyCommands(r.Compile, r.Execute, r.Filename)
+	return LanguageInput{
+		Name:     r.Name,
+		Time:     r.Time,
+		Filename: r.Filename,
+		Compile:  compile,
+		Execute:  execute,
+	}
+}
+
 // AddLanguageResponse : ID of the added language
 type AddLanguageResponse struct {
 	Success bool   `json:"success"`
 	ID      string `json:"id"`
 }
 
-// EditLanguageRequest : Edit language support
+// EditLanguageRequest : Edit language support, described by command
+// strings as the first language documents were
 type EditLanguageRequest struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -55,6 +78,24 @@ func (r EditLanguageRequest) validate() error {
 	)
 }
 
+// patch : Structured form of the language, replacing every field but the
+// limits, probe and artifacts
+func (r EditLanguageRequest) patch() LanguagePatch {
+	compile, execute := legacyCommands(r.Compile, r.Execute, r.Filename)
+	if compile == nil {
+		compile = &judge.Command{}
+	}
+	artifacts := []string{}
+	return LanguagePatch{
+		Name:      &r.Name,
+		Time:      &r.Time,
+		Filename:  &r.Filename,
+		Compile:   compile,
+		Execute:   &execute,
+		Artifacts: &artifacts,
+	}
+}
+
 // DeleteLanguageRequest : Delete language support
 type DeleteLanguageRequest struct {
 	ID string `json:"id"`
@@ -66,41 +107,243 @@ func (r DeleteLanguageRequest) validate() error {
 	)
 }
 
+// sourceFilename : Source files are saved under a plain file name
+var sourceFilename = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// artifactsRule : Artifacts stay inside the working folder
+var artifactsRule = validation.By(func(value interface{}) error {
+	artifacts, _ := value.([]string)
+	if pointer, ok := value.(*[]string); ok && pointer != nil {
+		artifacts = *pointer
+	}
+	for _, artifact := range artifacts {
+		if err := judge.ValidateArtifact(artifact); err != nil {
+			return fmt.Errorf("%s: %w", artifact, err)
+		}
+	}
+	return nil
+})
+
+// LanguageInput : Fields of a new language, with its commands as argument
+// lists that may use the placeholders of judge.Language
+type LanguageInput struct {
+	Name      string         `json:"name"`
+	Time      int            `json:"time"`
+	Filename  string         `json:"filename"`
+	Compile   *judge.Command `json:"compile"`
+	Execute   judge.Command  `json:"execute"`
+	Artifacts []string       `json:"artifacts"`
+	Limits    judge.Limits   `json:"limits"`
+	Probe     *LanguageProbe `json:"probe"`
+	Disabled  bool           `json:"disabled"`
+}
+
+func (r LanguageInput) validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name, validation.Required),
+		validation.Field(&r.Time, validation.Required, validation.Min(1)),
+		validation.Field(&r.Filename, validation.Required, validation.Match(sourceFilename)),
+		validation.Field(&r.Compile),
+		validation.Field(&r.Execute),
+		validation.Field(&r.Artifacts, artifactsRule),
+		validation.Field(&r.Limits),
+		validation.Field(&r.Probe),
+	)
+}
+
+// LanguagePatch : Fields of a language to change, missing ones are kept.
+// A compile command with an empty argv removes the compile step.
+type LanguagePatch struct {
+	Name      *string        `json:"name"`
+	Time      *int           `json:"time"`
+	Filename  *string        `json:"filename"`
+	Compile   *judge.Command `json:"compile"`
+	Execute   *judge.Command `json:"execute"`
+	Artifacts *[]string      `json:"artifacts"`
+	Limits    *judge.Limits  `json:"limits"`
+	Probe     *LanguageProbe `json:"probe"`
+	Disabled  *bool          `json:"disabled"`
+	Archived  *bool          `json:"archived"`
+}
+
+// removesCompile : Whether the patch turns the language interpreted
+func (p LanguagePatch) removesCompile() bool {
+	return p.Compile != nil && len(p.Compile.Argv) <= 0
+}
+
+func (p LanguagePatch) validate() error {
+	var compileRules []validation.Rule
+	if p.removesCompile() {
+		compileRules = append(compileRules, validation.Skip)
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.NilOrNotEmpty),
+		validation.Field(&p.Time, validation.NilOrNotEmpty, validation.Min(1)),
+		validation.Field(&p.Filename, validation.NilOrNotEmpty, validation.Match(sourceFilename)),
+		validation.Field(&p.Compile, compileRules...),
+		validation.Field(&p.Execute),
+		validation.Field(&p.Artifacts, artifactsRule),
+		validation.Field(&p.Limits),
+		validation.Field(&p.Probe),
+	)
+}
+
+// apply : Changes the fields of the language the patch sets
+func (p LanguagePatch) apply(language *Language) {
+	if p.Name != nil {
+		language.Name = *p.Name
+	}
+	if p.Time != nil {
+		language.Time = *p.Time
+	}
+	if p.Filename != nil {
+		language.Filename = *p.Filename
+	}
+	if p.Compile != nil {
+		language.Compile = p.Compile
+		if p.removesCompile() {
+			language.Compile = nil
+		}
+	}
+	if p.Execute != nil {
+		language.Execute = *p.Execute
+	}
+	if p.Artifacts != nil {
+		language.Artifacts = *p.Artifacts
+	}
+	if p.Limits != nil {
+		language.Limits = *p.Limits
+	}
+	if p.Probe != nil {
+		language.Probe = p.Probe
+	}
+	if p.Disabled != nil {
+		language.Disabled = *p.Disabled
+	}
+	if p.Archived != nil {
+		language.Archived = *p.Archived
+	}
+	if p.needsSelfTest() {
+		language.SelfTest = nil
+	}
+}
+
+// update : $set of the fields the patch sets, the last self-test being
+// dropped when the patch changes how solutions are run
+func (p LanguagePatch) update() bson.M {
+	set := bson.M{}
+	if p.Name != nil {
+		set["name"] = *p.Name
+	}
+	if p.Time != nil {
+		set["time"] = *p.Time
+	}
+	if p.Filename != nil {
+		set["filename"] = *p.Filename
+	}
+	if p.Compile != nil {
+		set["compile"] = p.Compile
+		if p.removesCompile() {
+			set["compile"] = nil
+		}
+	}
+	if p.Execute != nil {
+		set["execute"] = *p.Execute
+	}
+	if p.Artifacts != nil {
+		set["artifacts"] = *p.Artifacts
+	}
+	if p.Limits != nil {
+		set["limits"] = *p.Limits
+	}
+	if p.Probe != nil {
+		set["probe"] = p.Probe
+	}
+	if p.Disabled != nil {
+		set["disabled"] = *p.Disabled
+	}
+	if p.Archived != nil {
+		set["archived"] = *p.Archived
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if p.needsSelfTest() {
+		update["$unset"] = bson.M{"self_test": ""}
+	}
+	return update
+}
+
+// needsSelfTest : Whether the patch changes how solutions are run
+func (p LanguagePatch) needsSelfTest() bool {
+	return p.Time != nil || p.Filename != nil || p.Compile != nil || p.Execute != nil ||
+		p.Artifacts != nil || p.Limits != nil || p.Probe != nil
+}
+
+// createLanguage : Validates and stores a new language, whose probe the
+// workers run before advertising it
+func (api *API) createLanguage(ctx context.Context, request LanguageInput) (Language, error) {
+	if err := request.validate(); err != nil {
+		return Language{}, err
+	}
+
+	language := Language{
+		ID:        primitive.NewObjectID(),
+		Name:      request.Name,
+		Time:      request.Time,
+		Filename:  request.Filename,
+		Compile:   request.Compile,
+		Execute:   request.Execute,
+		Artifacts: request.Artifacts,
+		Limits:    request.Limits,
+		Probe:     request.Probe,
+		Disabled:  request.Disabled,
+	}
+	if language.Artifacts == nil {
+		language.Artifacts = []string{}
+	}
+	return language, api.Languages.Insert(ctx, language)
+}
+
+// updateLanguage : Applies the patch to the language with the given ID.
+// When the way solutions are run changes, the last self-test is cleared
+// and workers test the language again. A language that failed its
+// self-test cannot be enabled before it passes.
+func (api *API) updateLanguage(ctx context.Context, ID primitive.ObjectID, patch LanguagePatch) (Language, error) {
+	if err := patch.validate(); err != nil {
+		return Language{}, err
+	}
+	language, err := api.Languages.Get(ctx, ID)
+	if err != nil {
+		return Language{}, err
+	}
+
+	// Only the patch is written, so edits of other fields made meanwhile
+	// are kept
+	patch.apply(&language)
+	if !language.Disabled && language.SelfTest != nil && !language.SelfTest.Passed {
+		return Language{}, ErrSelfTestFailed
+	}
+	return api.Languages.Update(ctx, ID, patch)
+}
+
 func (api *API) addLanguageHandler(w http.ResponseWriter, r *http.Request) {
 	var reqBody AddLanguageRequest
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, badRequest(err))
 		return
 	}
+
 	if err := reqBody.validate(); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	language := Language{
-		ID:       primitive.NewObjectID(),
-		Name:     reqBody.Name,
-		Time:     reqBody.Time,
-		Filename: reqBody.Filename,
-		Compile:  reqBody.Compile,
-		Execute:  reqBody.Execute,
-	}
-
-	_, err := api.Db.Collection("languages").InsertOne(r.Context(), language)
+	language, err := api.createLanguage(r.Context(), reqBody.input())
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
@@ -113,47 +356,23 @@ func (api *API) addLanguageHandler(w http.ResponseWriter, r *http.Request) {
 func (api *API) editLanguageHandler(w http.ResponseWriter, r *http.Request) {
 	var reqBody EditLanguageRequest
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, badRequest(err))
 		return
 	}
 	if err := reqBody.validate(); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	objID, err := primitive.ObjectIDFromHex(reqBody.ID)
+	objID, err := parseID(reqBody.ID)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	updateResult, err := api.Db.Collection("languages").UpdateOne(r.Context(), bson.M{"_id": bson.M{"$eq": objID}}, bson.M{"$set": bson.M{"name": reqBody.Name, "time": reqBody.Time, "filename": reqBody.Filename, "compile": reqBody.Compile, "execute": reqBody.Execute}})
+	_, err = api.updateLanguage(r.Context(), objID, reqBody.patch())
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
-	if updateResult.MatchedCount <= 0 {
-		api.Log.Info("No such language with this ID")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
@@ -165,47 +384,22 @@ func (api *API) editLanguageHandler(w http.ResponseWriter, r *http.Request) {
 func (api *API) deleteLanguageHandler(w http.ResponseWriter, r *http.Request) {
 	var reqBody DeleteLanguageRequest
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, badRequest(err))
 		return
 	}
 	if err := reqBody.validate(); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	objID, err := primitive.ObjectIDFromHex(reqBody.ID)
+	objID, err := parseID(reqBody.ID)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	deleteResult, err := api.Db.Collection("languages").DeleteOne(r.Context(), bson.M{"_id": bson.M{"$eq": objID}})
-	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
-	if deleteResult.DeletedCount <= 0 {
-		api.Log.Info("No such language with this ID")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+	if err = api.Languages.SoftDelete(r.Context(), objID); err != nil {
+		api.writeV1Error(w, r, err)
 		return
 	}
 
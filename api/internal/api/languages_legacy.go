@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"judge-two/internal/judge"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Command strings of the first language documents ran from a fixed runner
+// folder, with a marker for languages that need no compilation
+const (
+	legacyRunnerPath = "/tmp/runner"
+	legacyNoCompile  = "compilation-not-needed"
+)
+
+// legacyCommand : Argument list of a command string, with paths into the
+// runner folder turned into placeholders
+func legacyCommand(line string, filename string) judge.Command {
+	argv := strings.Fields(line)
+	for i, arg := range argv {
+		switch {
+		case arg == legacyRunnerPath+"/"+filename:
+			argv[i] = judge.PlaceholderSrc
+		case arg == legacyRunnerPath || arg == legacyRunnerPath+"/":
+			argv[i] = judge.PlaceholderWorkdir
+		case strings.HasPrefix(arg, legacyRunnerPath+"/"):
+			argv[i] = judge.PlaceholderWorkdir + strings.TrimPrefix(arg, legacyRunnerPath)
+		}
+	}
+	return judge.Command{Argv: argv}
+}
+
+// legacyCommands : Structured compile and execute commands of a language
+// described by command strings. Such languages have no artifacts, so the
+// run step gets the whole compile folder as the runner folder used to be.
+func legacyCommands(compile string, execute string, filename string) (*judge.Command, judge.Command) {
+	var compileCommand *judge.Command
+	if line := strings.TrimSpace(compile); line != "" && line != legacyNoCompile {
+		command := legacyCommand(line, filename)
+		compileCommand = &command
+	}
+	return compileCommand, legacyCommand(execute, filename)
+}
+
+// migrateLanguages : Rewrites language documents still holding command
+// strings, and probes whose version command is a string, in the
+// structured form
+func (api *API) migrateLanguages(ctx context.Context) error {
+	languages := api.Db.Collection("languages")
+	cursor, err := languages.Find(ctx, bson.M{"execute": bson.M{"$type": "string"}})
+	if err != nil {
+		return err
+	}
+	var legacy []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Filename string             `bson:"filename"`
+		Compile  string             `bson:"compile"`
+		Execute  string             `bson:"execute"`
+	}
+	if err = cursor.All(ctx, &legacy); err != nil {
+		return err
+	}
+	for _, language := range legacy {
+		compile, execute := legacyCommands(language.Compile, language.Execute, language.Filename)
+		_, err = languages.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": language.ID}}, bson.M{"$set": bson.M{
+			"compile":   compile,
+			"execute":   execute,
+			"artifacts": []string{},
+			"limits":    judge.Limits{},
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	cursor, err = languages.Find(ctx, bson.M{"probe.version": bson.M{"$type": "string"}})
+	if err != nil {
+		return err
+	}
+	var probes []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Probe struct {
+			Version string `bson:"version"`
+		} `bson:"probe"`
+	}
+	if err = cursor.All(ctx, &probes); err != nil {
+		return err
+	}
+	for _, language := range probes {
+		_, err = languages.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": language.ID}},
+			bson.M{"$set": bson.M{"probe.version": strings.Fields(language.Probe.Version)}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
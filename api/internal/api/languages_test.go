@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"judge-two/internal/judge"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLegacyLanguageRoutes(t *testing.T) {
+	s := newTestServer(t)
+	add := AddLanguageRequest{Name: "C++", Time: 2, Filename: "main.cpp", Compile: "g++ main.cpp -o main", Execute: "./main"}
+
+	expectStatus(t, s.json(http.MethodPost, "/addLanguage", add, false), http.StatusUnauthorized)
+	expectV1(t, s.json(http.MethodPost, "/addLanguage", AddLanguageRequest{Name: "C++"}, true), http.StatusBadRequest, false, nil)
+
+	var added AddLanguageResponse
+	expectV1(t, s.json(http.MethodPost, "/addLanguage", add, true), http.StatusOK, true, &added)
+	ID, err := parseID(added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	language, err := s.Languages.Get(context.Background(), ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if language.Compile == nil || language.Compile.Argv[0] != "g++" || language.Execute.Argv[0] != "./main" {
+		t.Fatalf("commands stored as %v and %v", language.Compile, language.Execute)
+	}
+
+	edit := EditLanguageRequest{ID: added.ID, Name: "C++17", Time: 3, Filename: "main.cpp", Compile: "g++ -std=c++17 main.cpp -o main", Execute: "./main"}
+	expectStatus(t, s.json(http.MethodPost, "/editLanguage", edit, false), http.StatusUnauthorized)
+	expectV1(t, s.json(http.MethodPost, "/editLanguage", edit, true), http.StatusOK, true, nil)
+	if language, _ = s.Languages.Get(context.Background(), ID); language.Name != "C++17" || language.Time != 3 {
+		t.Fatalf("edited to %s with time %d", language.Name, language.Time)
+	}
+	edit.ID = "nope"
+	expectV1(t, s.json(http.MethodPost, "/editLanguage", edit, true), http.StatusBadRequest, false, nil)
+
+	expectV1(t, s.json(http.MethodPost, "/deleteLanguage", DeleteLanguageRequest{ID: added.ID}, false), http.StatusUnauthorized, false, nil)
+	expectV1(t, s.json(http.MethodPost, "/deleteLanguage", DeleteLanguageRequest{ID: "nope"}, true), http.StatusBadRequest, false, nil)
+	expectV1(t, s.json(http.MethodPost, "/deleteLanguage", DeleteLanguageRequest{ID: added.ID}, true), http.StatusOK, true, nil)
+	if _, err = s.Languages.Get(context.Background(), ID); err != ErrNoSuchLanguage {
+		t.Fatalf("deleted language read with %v", err)
+	}
+	expectV1(t, s.json(http.MethodPost, "/deleteLanguage", DeleteLanguageRequest{ID: added.ID}, true), http.StatusBadRequest, false, nil)
+}
+
+func TestLanguageRoutes(t *testing.T) {
+	s := newTestServer(t)
+	input := LanguageInput{Name: "Python", Time: 1, Filename: "main.py", Execute: judge.Command{Argv: []string{"python3", "{src}"}}}
+
+	expectError(t, s.json(http.MethodPost, "/v2/languages", input, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodPost, "/v2/languages", LanguageInput{Name: "Python", Filename: "../main.py"}, true),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	rec := s.json(http.MethodPost, "/v2/languages", input, true)
+	var language Language
+	expectJSON(t, rec, http.StatusCreated, &language)
+	path := "/v2/languages/" + language.ID.Hex()
+	if location := rec.Header().Get("Location"); location != path {
+		t.Fatalf("created at %q, expected %q", location, path)
+	}
+
+	var list LanguagesResponse
+	expectJSON(t, s.get("/v2/languages", false), http.StatusOK, &list)
+	if len(list.Languages) != 1 || list.Languages[0].ID != language.ID {
+		t.Fatalf("listed %v", list.Languages)
+	}
+	expectStatus(t, s.get(path, false), http.StatusOK)
+	expectError(t, s.get("/v2/languages/nope", false), http.StatusNotFound, CodeNotFound)
+	expectError(t, s.get("/v2/languages/"+primitive.NewObjectID().Hex(), false), http.StatusNotFound, CodeNotFound)
+
+	// Archived languages are left to admins
+	archived := true
+	expectError(t, s.json(http.MethodPatch, path, LanguagePatch{Archived: &archived}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.json(http.MethodPatch, path, LanguagePatch{Archived: &archived}, true), http.StatusOK, &language)
+	if !language.Archived {
+		t.Fatal("language not archived")
+	}
+	expectError(t, s.get(path, false), http.StatusNotFound, CodeNotFound)
+	expectStatus(t, s.get(path, true), http.StatusOK)
+	expectJSON(t, s.get("/v2/languages", false), http.StatusOK, &list)
+	if len(list.Languages) != 0 {
+		t.Fatalf("contestants see %v", list.Languages)
+	}
+	zero := 0
+	expectError(t, s.json(http.MethodPatch, path, LanguagePatch{Time: &zero}, true), http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	// Self-tests need a probe, and ask the workers to run it again
+	expectError(t, s.json(http.MethodPost, path+"/self-test", nil, true), http.StatusConflict, CodeConflict)
+	expectStatus(t, s.json(http.MethodPatch, path, LanguagePatch{Probe: &LanguageProbe{Source: "print(input())"}}, true), http.StatusOK)
+	if _, err := s.Languages.SetSelfTest(context.Background(), language.ID, &SelfTest{Passed: true, Verdict: VerdictAccepted}); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, s.json(http.MethodPost, path+"/self-test", nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.json(http.MethodPost, path+"/self-test", nil, true), http.StatusAccepted, &language)
+	if language.SelfTest != nil {
+		t.Fatalf("self-test kept as %v", language.SelfTest)
+	}
+
+	// Soft deletes go to the trash, from which languages are restored
+	expectError(t, s.json(http.MethodDelete, path, nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectStatus(t, s.json(http.MethodDelete, path, nil, true), http.StatusNoContent)
+	expectError(t, s.get(path, true), http.StatusNotFound, CodeNotFound)
+	expectError(t, s.get("/v2/languages?deleted=true", false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.get("/v2/languages?deleted=true", true), http.StatusOK, &list)
+	if len(list.Languages) != 1 || list.Languages[0].DeletedAt == nil {
+		t.Fatalf("trash holds %v", list.Languages)
+	}
+	expectError(t, s.json(http.MethodPost, path+"/restore", nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectStatus(t, s.json(http.MethodPost, path+"/restore", nil, true), http.StatusOK)
+	expectError(t, s.json(http.MethodPost, path+"/restore", nil, true), http.StatusConflict, CodeConflict)
+
+	// Purges are refused while a submission is made in the language
+	submission := Submission{ID: primitive.NewObjectID(), LanguageID: language.ID, QuestionID: primitive.NewObjectID(), Verdict: VerdictAccepted}
+	if err := s.submissions().Add(submission); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, s.json(http.MethodDelete, path+"?hard=true", nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodDelete, path+"?hard=true", nil, true), http.StatusConflict, CodeConflict)
+	if err := s.Submissions.Delete(context.Background(), submission.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.json(http.MethodDelete, path+"?hard=true", nil, true), http.StatusNoContent)
+	expectJSON(t, s.get("/v2/languages?deleted=true", true), http.StatusOK, &list)
+	if len(list.Languages) != 0 {
+		t.Fatalf("purged language left %v", list.Languages)
+	}
+	expectError(t, s.json(http.MethodDelete, path+"?hard=true", nil, true), http.StatusNotFound, CodeNotFound)
+}
+
+func TestLanguageRoutesRejectMalformedBodies(t *testing.T) {
+	s := newTestServer(t)
+	language := s.language("Python")
+
+	for _, target := range []string{"/v2/languages", "/v2/languages/" + language.ID.Hex()} {
+		method := http.MethodPost
+		if strings.Contains(target, language.ID.Hex()) {
+			method = http.MethodPatch
+		}
+		r, _ := http.NewRequest(method, target, strings.NewReader("{"))
+		r.Header.Set("Content-Type", "application/json")
+		expectError(t, s.serve(r, true), http.StatusBadRequest, CodeInvalidRequest)
+	}
+
+	// The v1 routes answer with their own body
+	body := url.Values{"id": {language.ID.Hex()}}.Encode()
+	r, _ := http.NewRequest(http.MethodPost, "/deleteLanguage", strings.NewReader(body))
+	expectV1(t, s.serve(r, true), http.StatusBadRequest, false, nil)
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Errors returned while deleting and restoring languages and questions
+var (
+	ErrStillReferenced = errors.New("submissions or contests still reference it, it can only be soft deleted")
+	ErrNotDeleted      = errors.New("only deleted items can be restored")
+)
+
+// visibility : Languages and questions a listing includes
+type visibility int
+
+const (
+	visibleToContestants visibility = iota // Neither archived nor deleted
+	visibleToAdmins                        // Archived ones too
+	visibleInTrash                         // Only the soft deleted ones
+)
+
+// filter : Mongo filter selecting the documents of the visibility
+func (v visibility) filter() bson.M {
+	switch v {
+	case visibleInTrash:
+		return bson.M{"deleted_at": bson.M{"$exists": true}}
+	case visibleToAdmins:
+		return bson.M{"deleted_at": bson.M{"$exists": false}}
+	}
+	return bson.M{"deleted_at": bson.M{"$exists": false}, "archived": bson.M{"$ne": true}}
+}
+
+// matches : Whether a document with the given state has the visibility
+func (v visibility) matches(archived bool, deletedAt *time.Time) bool {
+	switch v {
+	case visibleInTrash:
+		return deletedAt != nil
+	case visibleToAdmins:
+		return deletedAt == nil
+	}
+	return deletedAt == nil && !archived
+}
+
+// requestVisibility : Admins see archived items, contestants do not
+func (api *API) requestVisibility(r *http.Request) visibility {
+	if api.isAdmin(r) {
+		return visibleToAdmins
+	}
+	return visibleToContestants
+}
+
+// notDeleted : Filter matching the document with the given ID unless it
+// was soft deleted
+func notDeleted(ID primitive.ObjectID) bson.M {
+	return bson.M{"_id": bson.M{"$eq": ID}, "deleted_at": bson.M{"$exists": false}}
+}
+
+// purgeLanguage : Deletes the language for good, refused while a
+// submission is made in it or a contest lists it
+func (api *API) purgeLanguage(ctx context.Context, ID primitive.ObjectID) error {
+	used, err := api.Submissions.UsesLanguage(ctx, ID)
+	if err == nil && !used {
+		used, err = api.Contests.UsesLanguage(ctx, ID)
+	}
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrStillReferenced
+	}
+	return api.Languages.Delete(ctx, ID)
+}
+
+// purgeQuestion : Deletes the question for good, refused while a
+// submission answers it or a contest holds it
+func (api *API) purgeQuestion(ctx context.Context, ID primitive.ObjectID) error {
+	used, err := api.Submissions.UsesQuestion(ctx, ID)
+	if err == nil && !used {
+		used, err = api.Contests.UsesQuestion(ctx, ID)
+	}
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrStillReferenced
+	}
+	return api.Questions.Delete(ctx, ID)
+}
+
+// purgeStorage : Removes the testcase folders of the questions deleted for
+// good, recorded by their tombstones, along with their leftover staging
+// folders. Folders are only ever removed for a tombstone, a folder without
+// a question is left alone as it may be an upload in progress.
+func (api *API) purgeStorage(ctx context.Context) (int, error) {
+	tombstones, err := api.Questions.Tombstones(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, tombstone := range tombstones {
+		// Folders are named after the question, "<id>", "<id>.staging-*"
+		// while an edit is built and "<id>.staging-*.old" while it is swapped in
+		folder := api.Storage + tombstone.ID.Hex()
+		staging, err := filepath.Glob(folder + ".*")
+		if err != nil {
+			return purged, err
+		}
+		for _, path := range append([]string{folder}, staging...) {
+			if err = os.RemoveAll(path); err != nil {
+				return purged, err
+			}
+		}
+		if err = api.Questions.ForgetTombstone(ctx, tombstone.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// runPurge : Purges the storage of deleted questions every purge interval
+// until ctx ends
+func (api *API) runPurge(ctx context.Context) {
+	if api.Config.Storage.PurgeInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(api.Config.Storage.PurgeInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		purged, err := api.purgeStorage(ctx)
+		if err != nil && ctx.Err() == nil {
+			api.Log.Error("Testcase storage purge failed", zap.Error(err))
+		}
+		if purged > 0 {
+			api.Log.Info("Purged testcases of deleted questions", zap.Int("questions", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (api *API) listDeletedLanguagesV2(w http.ResponseWriter, r *http.Request) {
+	languages, err := api.Languages.List(r.Context(), visibleInTrash)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, LanguagesResponse{Languages: languages})
+}
+
+func (api *API) restoreLanguageV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.Languages.Restore(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	language, err := api.Languages.Get(r.Context(), ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, language)
+}
+
+func (api *API) purgeLanguageV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.purgeLanguage(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) listDeletedQuestionsV2(w http.ResponseWriter, r *http.Request) {
+	questions, err := api.Questions.List(r.Context(), visibleInTrash)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, QuestionsResponse{Questions: questions})
+}
+
+func (api *API) restoreQuestionV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.Questions.Restore(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	question, err := api.Questions.Get(r.Context(), ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	question.Testcases = question.testcaseList()
+	writeJSON(w, http.StatusOK, question)
+}
+
+// purgeQuestionV2 : Removes the question document, its testcases go with
+// the next storage purge
+func (api *API) purgeQuestionV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.purgeQuestion(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/api/trace"
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const requestInfoKey contextKey = iota
+
+// requestInfo : Details about a request gathered while it is handled
+type requestInfo struct {
+	ID    string
+	User  string
+	Route string // Template of the matched route, empty when none matched
+}
+
+// statusRecorder : Keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Flush : Lets handlers streaming their response flush it
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// logRequests : Gives every request an ID, kept from the X-Request-ID
+// header when the client sends one, and logs it once it is handled. It
+// wraps the whole router so requests no route matches are logged too.
+func (api *API) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{ID: r.Header.Get("X-Request-ID")}
+		if len(info.ID) <= 0 || len(info.ID) > 64 {
+			info.ID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", info.ID)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestInfoKey, info)))
+
+		route := info.Route
+		if len(route) <= 0 {
+			route = r.URL.Path
+		}
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("route", route),
+			zap.Int("status", recorder.status),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("request_id", info.ID),
+		}
+		if len(info.User) > 0 {
+			fields = append(fields, zap.String("user", info.User))
+		}
+
+		if recorder.status >= http.StatusInternalServerError {
+			api.Log.Error("Request handled", fields...)
+		} else {
+			api.Log.Info("Request handled", fields...)
+		}
+	})
+}
+
+// recordRoute : Tells the request log which route matched, only known
+// inside the router
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+			info.Route = routeTemplate(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// log : Logger tagged with the ID of the request ctx belongs to and the
+// trace it is part of
+func (api *API) log(ctx context.Context) *zap.Logger {
+	logger := api.Log
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		logger = logger.With(zap.String("request_id", info.ID))
+	}
+	if span := trace.SpanFromContext(ctx).SpanContext(); span.IsValid() {
+		logger = logger.With(zap.String("trace_id", span.TraceID.String()))
+	}
+	return logger
+}
+
+// setRequestUser : Records who made the request for the request log
+func setRequestUser(r *http.Request, user string) {
+	if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+		info.User = user
+	}
+}
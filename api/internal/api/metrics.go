@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"judge-two/internal/metrics"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// routeTemplate : Route the request matched, such as /addQuestion, so
+// metrics and logs are not split by query strings or IDs
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// measureRequests : Counts requests and times them per route
+func measureRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := routeTemplate(r)
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// mongoMonitor : Times and traces every command sent to MongoDB
+func mongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: startMongoSpan,
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			metrics.MongoDuration.WithLabelValues(e.CommandName, "success").Observe(time.Duration(e.DurationNanos).Seconds())
+			endMongoSpan(ctx, e.RequestID, "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			metrics.MongoDuration.WithLabelValues(e.CommandName, "failure").Observe(time.Duration(e.DurationNanos).Seconds())
+			endMongoSpan(ctx, e.RequestID, e.Failure)
+		},
+	}
+}
+
+// Outcomes of a testcase import
+const (
+	importSuccess = "success"
+	importInvalid = "invalid"
+	importDryRun  = "dry_run"
+	importError   = "error"
+)
+
+// observeImport : Records how long a testcase import took, meant to be
+// deferred with the outcome updated along the way
+func observeImport(start time.Time, outcome *string) {
+	metrics.TestcaseImportDuration.WithLabelValues(*outcome).Observe(time.Since(start).Seconds())
+}
+
+// queueCollector : Reads the depth and age of the judge queue from the
+// database on every scrape, so every API replica reports the same values.
+// A single one is registered for the process, pointed at the database of
+// the last API started.
+type queueCollector struct {
+	mu sync.RWMutex
+	db *mongo.Database
+}
+
+var (
+	queueMetrics         = &queueCollector{}
+	registerQueueMetrics sync.Once
+)
+
+// collectQueue : Reports the judge queue of db on the next scrapes
+func collectQueue(db *mongo.Database) {
+	registerQueueMetrics.Do(func() { prometheus.MustRegister(queueMetrics) })
+	queueMetrics.mu.Lock()
+	queueMetrics.db = db
+	queueMetrics.mu.Unlock()
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metrics.QueueDepthDesc
+	ch <- metrics.QueueAgeDesc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	db := c.db
+	c.mu.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cursor, err := db.Collection("judge_queue").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$priority"},
+			{Key: "depth", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "oldest", Value: bson.D{{Key: "$min", Value: "$enqueued_at"}}},
+		}}},
+	})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(metrics.QueueDepthDesc, err)
+		return
+	}
+	var groups []struct {
+		Priority int       `bson:"_id"`
+		Depth    int       `bson:"depth"`
+		Oldest   time.Time `bson:"oldest"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		ch <- prometheus.NewInvalidMetric(metrics.QueueDepthDesc, err)
+		return
+	}
+
+	var age time.Duration
+	for _, group := range groups {
+		ch <- prometheus.MustNewConstMetric(metrics.QueueDepthDesc, prometheus.GaugeValue, float64(group.Depth), strconv.Itoa(group.Priority))
+		if since := time.Since(group.Oldest); since > age {
+			age = since
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(metrics.QueueAgeDesc, prometheus.GaugeValue, age.Seconds())
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"judge-two/internal/config"
+	"judge-two/internal/judge"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// migration : Versioned change of the database schema. Migrations run once
+// each in order of version and must be safe to run again, as replicas
+// starting together may both run the same one.
+type migration struct {
+	Version int
+	Name    string
+	Up      func(api *API, ctx context.Context) error
+}
+
+// migrations : Every schema change, append new ones with the next version
+// and never reorder or remove applied ones
+var migrations = []migration{
+	{Version: 1, Name: "seed_languages", Up: (*API).seedLanguages},
+	{Version: 2, Name: "structured_language_commands", Up: (*API).migrateLanguages},
+	{Version: 3, Name: "queue_language_ids", Up: (*API).migrateQueue},
+	{Version: 4, Name: "worker_indexes", Up: (*API).ensureWorkerIndexes},
+	{Version: 5, Name: "submission_reference_indexes", Up: (*API).ensureSubmissionIndexes},
+	{Version: 6, Name: "contest_indexes", Up: (*API).ensureContestIndexes},
+}
+
+// SchemaMigration : Record of an applied migration in schema_migrations
+type SchemaMigration struct {
+	Version   int       `bson:"_id" json:"version"`
+	Name      string    `bson:"name" json:"name"`
+	AppliedAt time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+// MigrationStatus : Migration and when it was applied, nil while pending
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// appliedMigrations : Migrations recorded in schema_migrations by version
+func (api *API) appliedMigrations(ctx context.Context) (map[int]SchemaMigration, error) {
+	cursor, err := api.Db.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []SchemaMigration
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// migrate : Applies the pending migrations in order and records each, or
+// with dryRun only reports which ones are pending. The status of every
+// migration is returned, up to the one that failed.
+func (api *API) migrate(ctx context.Context, dryRun bool) ([]MigrationStatus, error) {
+	applied, err := api.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, step := range migrations {
+		status := MigrationStatus{Version: step.Version, Name: step.Name}
+		if record, ok := applied[step.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			statuses = append(statuses, status)
+			continue
+		}
+		if dryRun {
+			statuses = append(statuses, status)
+			continue
+		}
+
+		log := api.Log.With(zap.Int("version", step.Version), zap.String("migration", step.Name))
+		start := time.Now()
+		if err = step.Up(api, ctx); err != nil {
+			log.Error("Migration failed", zap.Error(err))
+			return statuses, fmt.Errorf("migration %d %s: %w", step.Version, step.Name, err)
+		}
+		record := SchemaMigration{Version: step.Version, Name: step.Name, AppliedAt: time.Now().UTC()}
+		_, err = api.Db.Collection("schema_migrations").ReplaceOne(ctx, bson.M{"_id": bson.M{"$eq": record.Version}}, record, options.Replace().SetUpsert(true))
+		if err != nil {
+			return statuses, fmt.Errorf("migration %d %s: %w", step.Version, step.Name, err)
+		}
+		log.Info("Migration applied", zap.Duration("took", time.Since(start)))
+		status.AppliedAt = &record.AppliedAt
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// pendingMigrations : Number of migrations not applied yet
+func pendingMigrations(statuses []MigrationStatus) int {
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+// Migrate : Connects to the database of cfg and applies the pending
+// migrations, or with dryRun only lists them
+func Migrate(cfg config.Config, dryRun bool) ([]MigrationStatus, error) {
+	api := &API{Config: cfg}
+	api.mountLogger()
+	defer api.Log.Sync()
+	ctx := context.Background()
+	if err := api.connectDatabase(ctx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		api.Db.Client().Disconnect(ctx)
+	}()
+
+	return api.migrate(ctx, dryRun)
+}
+
+// seedLanguages : Adds the usual languages to a new database, whose
+// languages collection does not exist yet
+func (api *API) seedLanguages(ctx context.Context) error {
+	names, err := api.Db.ListCollectionNames(ctx, bson.M{"name": "languages"})
+	if err != nil || len(names) > 0 {
+		return err
+	}
+	_, err = api.Db.Collection("languages").InsertMany(ctx, []interface{}{
+		Language{
+			ID: primitive.NewObjectID(), Name: "C++", Time: 2, Filename: "main.cpp",
+			Compile:   &judge.Command{Argv: []string{"g++", "-O2", "--std=c++17", "-o", "{binary}", "{src}"}},
+			Execute:   judge.Command{Argv: []string{"{binary}"}},
+			Artifacts: []string{"main"},
+		},
+		Language{
+			ID: primitive.NewObjectID(), Name: "Java 8", Time: 4, Filename: "Main.java",
+			Compile:   &judge.Command{Argv: []string{"javac", "{src}"}},
+			Execute:   judge.Command{Argv: []string{"java", "-Xmx{memlimit}m", "-cp", "{workdir}", "Main"}},
+			Artifacts: []string{"*.class"},
+		},
+		Language{
+			ID: primitive.NewObjectID(), Name: "Python 3", Time: 6, Filename: "main.py",
+			Execute:   judge.Command{Argv: []string{"python3", "{src}"}},
+			Artifacts: []string{},
+		},
+	}, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// ensureSubmissionIndexes : Keeps the reference checks made before a hard
+// delete and the rejudge lookups by question on an index
+func (api *API) ensureSubmissionIndexes(ctx context.Context) error {
+	_, err := api.Db.Collection("submissions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"ques_id": 1}},
+		{Keys: bson.M{"lang_id": 1}},
+	})
+	return err
+}
+
+// ensureContestIndexes : Keeps the reference checks on contests, and on
+// the submissions made in them, on an index
+func (api *API) ensureContestIndexes(ctx context.Context) error {
+	_, err := api.Db.Collection("contests").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"questions": 1}},
+		{Keys: bson.M{"languages": 1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = api.Db.Collection("submissions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"contest_id": 1},
+		Options: options.Index().SetSparse(true),
+	})
+	return err
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"judge-two/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testDatabase : API connected to a database of its own, dropped at the
+// end of the test. The server is the one at JUDGE_TEST_MONGO_URI, else a
+// mongod started for the test, and the test is skipped without either.
+func testDatabase(t *testing.T) *API {
+	uri := os.Getenv("JUDGE_TEST_MONGO_URI")
+	if uri == "" {
+		uri = startMongod(t)
+	}
+
+	cfg := config.Default()
+	cfg.Mongo.URI = uri
+	cfg.Mongo.Database = "judge_test_" + primitive.NewObjectID().Hex()
+	cfg.Mongo.ConnectTimeout = 30
+	cfg.Log.Level = "error"
+	api := newAPI(cfg)
+	api.mountLogger()
+	if err := api.connectDatabase(context.Background()); err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		api.Db.Drop(ctx)
+		api.Db.Client().Disconnect(ctx)
+	})
+	return api
+}
+
+// startMongod : URI of a mongod on a free port and a folder of its own,
+// stopped at the end of the test
+func startMongod(t *testing.T) string {
+	binary, err := exec.LookPath("mongod")
+	if err != nil {
+		t.Skip("neither JUDGE_TEST_MONGO_URI nor mongod available")
+	}
+	dir, err := ioutil.TempDir("", "judge-mongod-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cmd := exec.Command(binary, "--dbpath", dir, "--bind_ip", "127.0.0.1", "--port", fmt.Sprint(port), "--quiet")
+	if err = cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Signal(os.Interrupt)
+		cmd.Wait()
+		os.RemoveAll(dir)
+	})
+	return fmt.Sprintf("mongodb://127.0.0.1:%d", port)
+}
+
+// countDocuments : Documents of the collection matching filter
+func countDocuments(t *testing.T, api *API, collection string, filter bson.M) int64 {
+	t.Helper()
+	count, err := api.Db.Collection(collection).CountDocuments(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// indexKeys : Keys of the indexes of the collection, by index name
+func indexKeys(t *testing.T, api *API, collection string) map[string]bson.Raw {
+	t.Helper()
+	cursor, err := api.Db.Collection(collection).Indexes().List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indexes []struct {
+		Name string   `bson:"name"`
+		Key  bson.Raw `bson:"key"`
+	}
+	if err = cursor.All(context.Background(), &indexes); err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]bson.Raw{}
+	for _, index := range indexes {
+		keys[index.Name] = index.Key
+	}
+	return keys
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	api := testDatabase(t)
+	ctx := context.Background()
+
+	statuses, err := api.migrate(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(migrations) || pendingMigrations(statuses) != len(migrations) {
+		t.Fatalf("dry run reported %+v", statuses)
+	}
+	if count := countDocuments(t, api, "schema_migrations", bson.M{}); count != 0 {
+		t.Fatalf("dry run recorded %d migrations", count)
+	}
+
+	statuses, err = api.migrate(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pendingMigrations(statuses) != 0 || countDocuments(t, api, "schema_migrations", bson.M{}) != int64(len(migrations)) {
+		t.Fatalf("migrated to %+v", statuses)
+	}
+	for i, status := range statuses {
+		if status.Version != migrations[i].Version || status.Name != migrations[i].Name {
+			t.Fatalf("status %d is %+v", i, status)
+		}
+	}
+	if count := countDocuments(t, api, "languages", bson.M{}); count != 3 {
+		t.Fatalf("seeded %d languages", count)
+	}
+	for collection, index := range map[string]string{
+		"workers":     "expires_at_1",
+		"judge_queue": "lang_id_1_priority_-1_enqueued_at_1",
+		"submissions": "ques_id_1",
+	} {
+		if _, ok := indexKeys(t, api, collection)[index]; !ok {
+			t.Fatalf("no index %s on %s", index, collection)
+		}
+	}
+
+	// Applied migrations are left alone, their times read back at the
+	// millisecond precision of the database
+	again, err := api.migrate(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again {
+		if !again[i].AppliedAt.Equal(statuses[i].AppliedAt.Truncate(time.Millisecond)) {
+			t.Fatalf("migration %d applied again at %v", again[i].Version, again[i].AppliedAt)
+		}
+	}
+	if count := countDocuments(t, api, "languages", bson.M{}); count != 3 {
+		t.Fatalf("%d languages after migrating again", count)
+	}
+}
+
+func TestMigrateLegacyDocuments(t *testing.T) {
+	api := testDatabase(t)
+	ctx := context.Background()
+
+	// Documents as the first versions of the judge wrote them
+	languageID := primitive.NewObjectID()
+	_, err := api.Db.Collection("languages").InsertOne(ctx, bson.M{
+		"_id":      languageID,
+		"name":     "Python 3",
+		"time":     6,
+		"filename": "main.py",
+		"compile":  legacyNoCompile,
+		"execute":  "python3 " + legacyRunnerPath + "/main.py",
+		"probe":    bson.M{"source": "print(input())", "version": "python3 --version"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	submission := Submission{ID: primitive.NewObjectID(), QuestionID: primitive.NewObjectID(), LanguageID: languageID, Verdict: VerdictQueued}
+	if _, err = api.Db.Collection("submissions").InsertOne(ctx, submission); err != nil {
+		t.Fatal(err)
+	}
+	queued, orphaned := primitive.NewObjectID(), primitive.NewObjectID()
+	_, err = api.Db.Collection("judge_queue").InsertMany(ctx, []interface{}{
+		bson.M{"_id": queued, "sub_id": submission.ID, "priority": PrioritySubmission, "enqueued_at": time.Now()},
+		bson.M{"_id": orphaned, "sub_id": primitive.NewObjectID(), "priority": PrioritySubmission, "enqueued_at": time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = api.migrate(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing languages collection is not seeded
+	if count := countDocuments(t, api, "languages", bson.M{}); count != 1 {
+		t.Fatalf("%d languages after migrating", count)
+	}
+	language, err := api.Languages.Get(ctx, languageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if language.Compile != nil || !reflect.DeepEqual(language.Execute.Argv, []string{"python3", "{src}"}) {
+		t.Fatalf("commands migrated to %+v and %+v", language.Compile, language.Execute)
+	}
+	if language.Probe == nil || !reflect.DeepEqual(language.Probe.Version, []string{"python3", "--version"}) {
+		t.Fatalf("probe migrated to %+v", language.Probe)
+	}
+
+	var job JudgeJob
+	if err = api.Db.Collection("judge_queue").FindOne(ctx, bson.M{"_id": queued}).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.LanguageID != languageID {
+		t.Fatalf("queued job migrated to %+v", job)
+	}
+	if count := countDocuments(t, api, "judge_queue", bson.M{"_id": orphaned}); count != 0 {
+		t.Fatal("job of a missing submission kept")
+	}
+}
+
+func TestMigrateResumesAfterFailure(t *testing.T) {
+	api := testDatabase(t)
+	ctx := context.Background()
+
+	// A run stopped after the first migration leaves the others pending
+	record := SchemaMigration{Version: migrations[0].Version, Name: migrations[0].Name, AppliedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	if _, err := api.Db.Collection("schema_migrations").InsertOne(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := api.migrate(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pendingMigrations(statuses) != len(migrations)-1 || statuses[0].AppliedAt == nil || !statuses[0].AppliedAt.Equal(record.AppliedAt) {
+		t.Fatalf("dry run reported %+v", statuses)
+	}
+
+	if statuses, err = api.migrate(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if pendingMigrations(statuses) != 0 {
+		t.Fatalf("migrated to %+v", statuses)
+	}
+	// The seed was recorded as applied, so it does not run again
+	if count := countDocuments(t, api, "languages", bson.M{}); count != 0 {
+		t.Fatalf("seeded %d languages", count)
+	}
+}
@@ -1,6 +1,11 @@
 package api
 
 import (
+	"time"
+
+	"judge-two/internal/judge"
+	"judge-two/internal/tracing"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -10,27 +15,176 @@ type Question struct {
 	Time         int                `bson:"time" json:"time"`
 	Name         string             `bson:"name" json:"name"`
 	NumTestcases int                `bson:"num_testcases" json:"num_testcases"`
+	Testcases    []Testcase         `bson:"testcases" json:"testcases"`
+	Archived     bool               `bson:"archived" json:"archived"` // Hidden from contestants, kept for judging
+	DeletedAt    *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	// Languages submissions may use, every enabled language when empty,
+	// and time limits replacing Time times the language multiplier
+	Languages     []primitive.ObjectID `bson:"languages,omitempty" json:"languages,omitempty"`
+	TimeOverrides []TimeOverride       `bson:"time_overrides,omitempty" json:"time_overrides,omitempty"`
+
+	// Lease of the edit changing the testcases, see lockTestcases
+	TestcaseLock *TestcaseLock `bson:"testcase_lock,omitempty" json:"-"`
 }
 
-// Language : Structure for the language documents
+// Contest : Questions given together, whose submissions may only use the
+// languages of the contest on top of those their question allows
+type Contest struct {
+	ID        primitive.ObjectID   `bson:"_id" json:"id"`
+	Name      string               `bson:"name" json:"name"`
+	Questions []primitive.ObjectID `bson:"questions" json:"questions"`
+	Languages []primitive.ObjectID `bson:"languages,omitempty" json:"languages,omitempty"` // Every language when empty
+}
+
+// TestcaseLock : Lease on the testcases of a question, held by a single
+// edit across every replica of the API until it expires
+type TestcaseLock struct {
+	Holder    string    `bson:"holder"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// QuestionTombstone : Question deleted for good, whose testcase folders
+// the next storage purge removes
+type QuestionTombstone struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	DeletedAt time.Time          `bson:"deleted_at" json:"deleted_at"`
+}
+
+// TimeOverride : Time limit in seconds of a question for one language
+type TimeOverride struct {
+	LanguageID primitive.ObjectID `bson:"lang_id" json:"lang_id"`
+	Time       int                `bson:"time" json:"time"`
+}
+
+// Testcase : Position and labels of a single testcase of a question
+type Testcase struct {
+	Index       int    `bson:"index" json:"index"`   // Display index, also used for the file names
+	Number      int    `bson:"number" json:"number"` // Number the setter gave it in the archive
+	Name        string `bson:"name,omitempty" json:"name,omitempty"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+}
+
+// Language : Structure for the language documents, commands are
+// argument lists described by judge.Language
 type Language struct {
-	ID       primitive.ObjectID `bson:"_id" json:"id"`
-	Name     string             `bson:"name" json:"name"`
-	Time     int                `bson:"time" json:"time"`
-	Filename string             `bson:"filename" json:"filename"`
-	Compile  string             `bson:"compile" json:"compile"`
-	Execute  string             `bson:"execute" json:"execute"`
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Time      int                `bson:"time" json:"time"`
+	Filename  string             `bson:"filename" json:"filename"`
+	Compile   *judge.Command     `bson:"compile" json:"compile"`
+	Execute   judge.Command      `bson:"execute" json:"execute"`
+	Artifacts []string           `bson:"artifacts" json:"artifacts"`
+	Limits    judge.Limits       `bson:"limits" json:"limits"`
+	Probe     *LanguageProbe     `bson:"probe,omitempty" json:"probe,omitempty"`
+	SelfTest  *SelfTest          `bson:"self_test,omitempty" json:"self_test,omitempty"`
+	Disabled  bool               `bson:"disabled" json:"disabled"` // Refused for submissions
+	Archived  bool               `bson:"archived" json:"archived"` // Hidden from contestants, kept for judging
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+// LanguageProbe : Sample program checking that a language works, it must
+// print back whatever it reads on its standard input
+type LanguageProbe struct {
+	Source  string   `bson:"source" json:"source"`
+	Version []string `bson:"version,omitempty" json:"version,omitempty"` // Command printing the version, such as ["g++", "--version"]
 }
 
+// SelfTest : Outcome of compiling and running the probe of a language
+type SelfTest struct {
+	Passed    bool      `bson:"passed" json:"passed"`
+	Verdict   string    `bson:"verdict" json:"verdict"`
+	Version   string    `bson:"version,omitempty" json:"version,omitempty"`
+	Output    string    `bson:"output,omitempty" json:"output,omitempty"` // Compiler output or why the run failed
+	CheckedAt time.Time `bson:"checked_at" json:"checked_at"`
+}
+
+// Verdicts of a submission and of its single testcases
+const (
+	VerdictQueued       = judge.VerdictQueued
+	VerdictAccepted     = judge.VerdictAccepted
+	VerdictWrongAnswer  = judge.VerdictWrongAnswer
+	VerdictTimeLimit    = judge.VerdictTimeLimit
+	VerdictRuntimeError = judge.VerdictRuntimeError
+	VerdictCompileError = judge.VerdictCompileError
+	VerdictOutputLimit  = judge.VerdictOutputLimit
+)
+
 // Submission : Structure for the submission documents
 type Submission struct {
-	ID         primitive.ObjectID `bson:"_id" json:"id"`
-	LanguageID primitive.ObjectID `bson:"lang_id" json:"lang_id"`
-	QuestionID primitive.ObjectID `bson:"ques_id" json:"ques_id"`
+	ID         primitive.ObjectID  `bson:"_id" json:"id"`
+	LanguageID primitive.ObjectID  `bson:"lang_id" json:"lang_id"`
+	QuestionID primitive.ObjectID  `bson:"ques_id" json:"ques_id"`
+	ContestID  *primitive.ObjectID `bson:"contest_id,omitempty" json:"contest_id,omitempty"` // Contest it was made in, if any
+	Verdict    string              `bson:"verdict" json:"verdict"`
+	Testcases  map[int]string      `bson:"testcases" json:"testcases"` // Verdict by display index
+	Results    []TestcaseResult    `bson:"results,omitempty" json:"results,omitempty"`
+	History    []Judgement         `bson:"history,omitempty" json:"history,omitempty"`
+	Source     string              `bson:"source" json:"-"`             // Solution the workers compile, never sent back
+	ClaimID    *primitive.ObjectID `bson:"claim_id,omitempty" json:"-"` // Claim of the job judging it, the only one recording a verdict
+}
+
+// TestcaseResult : Outcome of a submission on one testcase, under both
+// its display index and the number the setter gave it
+type TestcaseResult struct {
+	Index   int    `bson:"index" json:"index"`
+	Number  int    `bson:"number" json:"number"`
+	Verdict string `bson:"verdict" json:"verdict"`
+	Time    int64  `bson:"time_ms" json:"time_ms"`
+}
+
+// Judgement : Earlier verdict of a submission, kept when it is rejudged
+type Judgement struct {
+	Verdict    string             `bson:"verdict" json:"verdict"`
 	Testcases  map[int]string     `bson:"testcases" json:"testcases"`
+	Results    []TestcaseResult   `bson:"results,omitempty" json:"results,omitempty"`
+	RejudgeID  primitive.ObjectID `bson:"rejudge_id" json:"rejudge_id"`
+	ReplacedAt time.Time          `bson:"replaced_at" json:"replaced_at"`
+}
+
+// JudgeJob : Submission waiting in the judge queue, only workers that
+// advertise its language take it
+type JudgeJob struct {
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`
+	SubmissionID primitive.ObjectID  `bson:"sub_id" json:"sub_id"`
+	LanguageID   primitive.ObjectID  `bson:"lang_id" json:"lang_id"`
+	Priority     int                 `bson:"priority" json:"priority"`
+	EnqueuedAt   time.Time           `bson:"enqueued_at" json:"enqueued_at"`
+	RejudgeID    *primitive.ObjectID `bson:"rejudge_id,omitempty" json:"rejudge_id,omitempty"`
+	ClaimedBy    string              `bson:"claimed_by,omitempty" json:"claimed_by,omitempty"` // Worker judging it
+	ClaimedAt    *time.Time          `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+	ClaimID      *primitive.ObjectID `bson:"claim_id,omitempty" json:"claim_id,omitempty"` // New for every claim, even by the same worker
+
+	// Trace context of the request that queued the job, workers continue
+	// the trace from it with tracing.Extract
+	Trace tracing.Carrier `bson:"trace,omitempty" json:"-"`
+}
+
+// Worker : Advertisement of a judge process, renewed every heartbeat.
+// Languages only lists those whose probe passed on the worker itself, and
+// Failed those whose probe failed there.
+type Worker struct {
+	ID          string               `bson:"_id" json:"id"`
+	Host        string               `bson:"host" json:"host"`
+	Slots       int                  `bson:"slots" json:"slots"` // Submissions it judges at once
+	Languages   []primitive.ObjectID `bson:"languages" json:"languages"`
+	Failed      []primitive.ObjectID `bson:"failed" json:"failed"`
+	StartedAt   time.Time            `bson:"started_at" json:"started_at"`
+	HeartbeatAt time.Time            `bson:"heartbeat_at" json:"heartbeat_at"`
+	ExpiresAt   time.Time            `bson:"expires_at" json:"expires_at"` // Considered gone after this without a heartbeat
+}
+
+// Rejudge : Batch of submissions sent back to the judge together
+type Rejudge struct {
+	ID          primitive.ObjectID   `bson:"_id" json:"id"`
+	Filter      RejudgeRequest       `bson:"filter" json:"filter"`
+	Submissions []primitive.ObjectID `bson:"submissions" json:"submissions"`
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	Queued      bool                 `bson:"queued" json:"queued"` // Every submission was requeued, false while pending
 }
 
 // TemplateResponse : Fields for normal response
 type TemplateResponse struct {
-	Success bool `json:"success"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
 }
@@ -0,0 +1,4687 @@
+package api
+
+import (
+	"net/http"
+)
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(openAPISpec))
+}
+
+// openAPISpec : OpenAPI 3 description of every route, served at
+// /openapi.json. Routes and bodies changed in the handlers are changed
+// here too.
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Judge API",
+    "version": "2.0.0",
+    "description": "Languages, questions, testcases and rejudges of the judge. The v1 routes are kept for existing clients, new clients should use /v2."
+  },
+  "tags": [
+    {
+      "name": "languages"
+    },
+    {
+      "name": "questions"
+    },
+    {
+      "name": "testcases"
+    },
+    {
+      "name": "contests"
+    },
+    {
+      "name": "submissions"
+    },
+    {
+      "name": "rejudges"
+    },
+    {
+      "name": "workers"
+    },
+    {
+      "name": "monitoring"
+    },
+    {
+      "name": "v1 (deprecated)"
+    }
+  ],
+  "paths": {
+    "/healthz": {
+      "get": {
+        "tags": [
+          "monitoring"
+        ],
+        "summary": "Liveness",
+        "operationId": "liveness",
+        "responses": {
+          "200": {
+            "description": "Process is up",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/readyz": {
+      "get": {
+        "tags": [
+          "monitoring"
+        ],
+        "summary": "Readiness of every dependency",
+        "operationId": "readiness",
+        "responses": {
+          "200": {
+            "description": "Ready",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthResponse"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Not ready",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Checks MongoDB, the testcase storage and whether shutdown started. Workers also check their sandbox can run a program and that their last advertisement is recorded."
+      }
+    },
+    "/metrics": {
+      "get": {
+        "tags": [
+          "monitoring"
+        ],
+        "summary": "Prometheus metrics",
+        "operationId": "metrics",
+        "responses": {
+          "200": {
+            "description": "Metrics in the Prometheus text format",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "tags": [
+          "monitoring"
+        ],
+        "summary": "This specification",
+        "operationId": "openapi",
+        "responses": {
+          "200": {
+            "description": "OpenAPI document",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/languages": {
+      "get": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "listLanguages",
+        "summary": "List languages",
+        "responses": {
+          "200": {
+            "description": "Languages by name",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/LanguagesResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "Database failure",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "deleted=true without the admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Archived languages are only listed for admins, deleted ones never. With deleted=true and the admin token, lists the soft deleted languages instead.",
+        "parameters": [
+          {
+            "name": "deleted",
+            "in": "query",
+            "required": false,
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "List the soft deleted languages, admin only"
+          }
+        ]
+      },
+      "post": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "createLanguage",
+        "summary": "Add a language",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/LanguageInput"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "Created",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Language"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Malformed body",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid fields",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "Database failure",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/languages/{id}": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "get": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "getLanguage",
+        "summary": "Get a language",
+        "responses": {
+          "200": {
+            "description": "Language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Language"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Archived languages are only found with the admin token"
+      },
+      "patch": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "updateLanguage",
+        "summary": "Change some fields of a language",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/LanguagePatch"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Updated language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Language"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Malformed body",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Enabling a language that failed its self-test",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid fields",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "delete": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "deleteLanguage",
+        "summary": "Delete a language",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted"
+          },
+          "404": {
+            "description": "No such language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Submissions or contests still reference the language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Soft deletes the language by setting deleted_at, it can be restored. With hard=true, the language is removed for good, which is refused while submissions or contests reference it.",
+        "parameters": [
+          {
+            "name": "hard",
+            "in": "query",
+            "required": false,
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Delete for good, admin only"
+          }
+        ]
+      }
+    },
+    "/v2/languages/{id}/restore": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "post": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "restoreLanguage",
+        "summary": "Bring back a soft deleted language",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Restored language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Language"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "The language is not deleted",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/languages/{id}/self-test": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "post": {
+        "tags": [
+          "languages"
+        ],
+        "operationId": "selfTestLanguage",
+        "summary": "Clear the self-test of a language, so every worker runs its probe again",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "202": {
+            "description": "Language waiting for its new self-test",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Language"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such language",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Language has no probe",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions": {
+      "get": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "listQuestions",
+        "summary": "List questions",
+        "responses": {
+          "200": {
+            "description": "Questions, newest first",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuestionsResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "deleted=true without the admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Archived questions are only listed for admins, deleted ones never. With deleted=true and the admin token, lists the soft deleted questions instead.",
+        "parameters": [
+          {
+            "name": "deleted",
+            "in": "query",
+            "required": false,
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "List the soft deleted questions, admin only"
+          }
+        ]
+      },
+      "post": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "createQuestion",
+        "summary": "Create a question from a testcase archive",
+        "parameters": [
+          {
+            "name": "dryRun",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Only validate the archive, nothing is stored"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "testcases": {
+                    "type": "string",
+                    "format": "binary",
+                    "description": "Testcase archive, .zip, .tar.gz or .tar.xz, in the standard (input/inputN.txt, output/outputN.txt) or flat (N.in, N.out or N.ans) layout, with an optional manifest.json"
+                  },
+                  "name": {
+                    "type": "string",
+                    "description": "Defaults to the name in the manifest"
+                  },
+                  "time": {
+                    "type": "integer",
+                    "description": "Time limit in seconds, defaults to the manifest then the configured default"
+                  }
+                },
+                "required": [
+                  "testcases"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Dry run, the archive is valid",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuestionCreatedResponse"
+                }
+              }
+            }
+          },
+          "201": {
+            "description": "Created",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuestionCreatedResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Unreadable or invalid archive, the report says why",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "413": {
+            "description": "Archive too large",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid name or time",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions/{id}": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "get": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "getQuestion",
+        "summary": "Get a question",
+        "responses": {
+          "200": {
+            "description": "Question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Question"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Archived questions are only found with the admin token"
+      },
+      "patch": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "updateQuestion",
+        "summary": "Change the name or time limit of a question",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/QuestionPatch"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Updated question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Question"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid fields",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "delete": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "deleteQuestion",
+        "summary": "Delete a question",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted"
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Submissions or contests still reference the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "description": "Soft deletes the question by setting deleted_at, it can be restored. With hard=true, the question is removed for good, which is refused while submissions or contests reference it. Its testcases are removed by the next storage purge.",
+        "parameters": [
+          {
+            "name": "hard",
+            "in": "query",
+            "required": false,
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Delete for good, admin only"
+          }
+        ]
+      }
+    },
+    "/v2/questions/{id}/restore": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "post": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "restoreQuestion",
+        "summary": "Bring back a soft deleted question",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Restored question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Question"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "The question is not deleted",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions/{id}/languages": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "get": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "listQuestionLanguages",
+        "summary": "List the enabled languages a question accepts with their time limits, within a contest when given",
+        "description": "Archived questions are only found with the admin token",
+        "responses": {
+          "200": {
+            "description": "Accepted languages",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuestionLanguagesResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The question is not part of the contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question or contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid contest_id",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "contest_id",
+            "in": "query",
+            "required": false,
+            "schema": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Contest the submission would be made in, whose languages apply too"
+          }
+        ]
+      }
+    },
+    "/v2/questions/{id}/languages/{language}": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        },
+        {
+          "name": "language",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "get": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "checkQuestionLanguage",
+        "summary": "Check whether a question accepts submissions in a language",
+        "description": "Runs the check submissions go through, the error names the languages to use instead",
+        "responses": {
+          "200": {
+            "description": "The language is accepted",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuestionLanguage"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The question or the contest does not accept the language, the language is disabled or the question is not part of the contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question, language or contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid contest_id",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        },
+        "parameters": [
+          {
+            "name": "contest_id",
+            "in": "query",
+            "required": false,
+            "schema": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Contest the submission would be made in, whose languages apply too"
+          }
+        ]
+      }
+    },
+    "/v2/questions/{id}/export": {
+      "get": {
+        "tags": [
+          "questions"
+        ],
+        "operationId": "exportQuestion",
+        "summary": "Download the question as an archive createQuestion takes back",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Zip archive",
+            "content": {
+              "application/zip": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions/{id}/testcases": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "put": {
+        "tags": [
+          "testcases"
+        ],
+        "operationId": "replaceTestcases",
+        "summary": "Replace every testcase with those of an archive",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "dryRun",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Only validate the archive, nothing is stored"
+          },
+          {
+            "name": "rejudge",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Rejudge every submission of the question once the edit is done"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "testcases": {
+                    "type": "string",
+                    "format": "binary",
+                    "description": "Testcase archive, .zip, .tar.gz or .tar.xz, in the standard (input/inputN.txt, output/outputN.txt) or flat (N.in, N.out or N.ans) layout, with an optional manifest.json"
+                  }
+                },
+                "required": [
+                  "testcases"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "New testcases, left out on dry runs",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcaseListResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Unreadable or invalid archive",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "413": {
+            "description": "Archive too large",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "post": {
+        "tags": [
+          "testcases"
+        ],
+        "operationId": "addTestcase",
+        "summary": "Append a testcase",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "rejudge",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Rejudge every submission of the question once the edit is done"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "input": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "output": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "description": {
+                    "type": "string"
+                  }
+                },
+                "required": [
+                  "input",
+                  "output"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcaseListResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Missing or oversized file",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions/{id}/testcases/order": {
+      "put": {
+        "tags": [
+          "testcases"
+        ],
+        "operationId": "reorderTestcases",
+        "summary": "Reorder the testcases",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          },
+          {
+            "name": "rejudge",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Rejudge every submission of the question once the edit is done"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ReorderRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Testcases in their new order",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcaseListResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Order does not list every index once",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions/{id}/testcases/{index}": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        },
+        {
+          "name": "index",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "integer",
+            "minimum": 1
+          }
+        }
+      ],
+      "patch": {
+        "tags": [
+          "testcases"
+        ],
+        "operationId": "updateTestcase",
+        "summary": "Replace the files or labels of a testcase, whatever is left out is kept",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "rejudge",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Rejudge every submission of the question once the edit is done"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "input": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "output": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "description": {
+                    "type": "string"
+                  }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcaseListResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Nothing to change",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question or testcase",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "delete": {
+        "tags": [
+          "testcases"
+        ],
+        "operationId": "deleteTestcase",
+        "summary": "Delete a testcase, later ones move up",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "rejudge",
+            "in": "query",
+            "schema": {
+              "type": "boolean"
+            },
+            "description": "Rejudge every submission of the question once the edit is done"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcaseListResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question or testcase",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Last testcase of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/questions/{id}/testcases/{index}/{file}": {
+      "get": {
+        "tags": [
+          "testcases"
+        ],
+        "operationId": "downloadTestcase",
+        "summary": "Download the input or output of a testcase",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          },
+          {
+            "name": "index",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "integer",
+              "minimum": 1
+            }
+          },
+          {
+            "name": "file",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "enum": [
+                "input",
+                "output"
+              ]
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "File content",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question or testcase",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/contests": {
+      "get": {
+        "tags": [
+          "contests"
+        ],
+        "operationId": "listContests",
+        "summary": "List contests",
+        "responses": {
+          "200": {
+            "description": "Contests, newest first",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ContestsResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "Database failure",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "post": {
+        "tags": [
+          "contests"
+        ],
+        "operationId": "createContest",
+        "summary": "Add a contest",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ContestInput"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "Created",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Contest"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Malformed body",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid fields or unknown questions or languages",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "Database failure",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/contests/{id}": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string",
+            "pattern": "^[0-9a-f]{24}$",
+            "example": "5f8d0d55b54764421b7156c9"
+          }
+        }
+      ],
+      "get": {
+        "tags": [
+          "contests"
+        ],
+        "operationId": "getContest",
+        "summary": "Get a contest",
+        "responses": {
+          "200": {
+            "description": "Contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Contest"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "patch": {
+        "tags": [
+          "contests"
+        ],
+        "operationId": "updateContest",
+        "summary": "Change some fields of a contest",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ContestPatch"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Updated contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Contest"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Malformed body",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid fields or unknown questions or languages",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "delete": {
+        "tags": [
+          "contests"
+        ],
+        "operationId": "deleteContest",
+        "summary": "Delete a contest",
+        "description": "Removes the contest for good, which is refused once submissions were made in it",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "204": {
+            "description": "Deleted"
+          },
+          "401": {
+            "description": "Missing or wrong admin token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Submissions were made in the contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/submissions": {
+      "post": {
+        "tags": [
+          "submissions"
+        ],
+        "operationId": "createSubmission",
+        "summary": "Submit a solution, which is queued for the workers once the question and its contest take its language",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/SubmissionInput"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "Queued",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Submission"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Malformed body, disabled language, language the question or contest does not take or question outside of the contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such question, language or contest",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid fields",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "Database failure",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Shutting down",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/submissions/{id}": {
+      "get": {
+        "tags": [
+          "submissions"
+        ],
+        "operationId": "getSubmission",
+        "summary": "Verdict of a submission, QU while it waits for a worker",
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Submission",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Submission"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such submission",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/rejudges": {
+      "post": {
+        "tags": [
+          "rejudges"
+        ],
+        "operationId": "createRejudge",
+        "summary": "Send matching submissions back to the judge",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/RejudgeRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": {
+            "description": "Submissions queued",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RejudgeCreatedResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "No filter given",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "422": {
+            "description": "Invalid filter",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Shutting down",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/rejudges/{id}": {
+      "get": {
+        "tags": [
+          "rejudges"
+        ],
+        "operationId": "getRejudge",
+        "summary": "Progress of a rejudge and the verdicts it changed",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Progress",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RejudgeStatusResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "No such rejudge",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v2/workers": {
+      "get": {
+        "tags": [
+          "workers"
+        ],
+        "operationId": "listWorkers",
+        "summary": "Live workers, the languages each passed the self-test of, and the languages no worker can run",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Workers and language coverage",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/WorkersResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/addLanguage": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1AddLanguage",
+        "summary": "Use POST /v2/languages",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/AddLanguageRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Added",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AddLanguageResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/editLanguage": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1EditLanguage",
+        "summary": "Use PATCH /v2/languages/{id}",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/EditLanguageRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Edited",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/deleteLanguage": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1DeleteLanguage",
+        "summary": "Use DELETE /v2/languages/{id}",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/DeleteLanguageRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Deleted",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/addQuestion": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1AddQuestion",
+        "summary": "Use POST /v2/questions",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "testcases": {
+                    "type": "string",
+                    "format": "binary",
+                    "description": "Testcase archive, .zip, .tar.gz or .tar.xz, in the standard (input/inputN.txt, output/outputN.txt) or flat (N.in, N.out or N.ans) layout, with an optional manifest.json"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "time": {
+                    "type": "integer"
+                  },
+                  "dryRun": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "testcases"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Added",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AddQuestionResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, with the report when the archive is invalid",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AddQuestionResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/editTestcases": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1EditTestcases",
+        "summary": "Use PUT /v2/questions/{id}/testcases",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "testcases": {
+                    "type": "string",
+                    "format": "binary",
+                    "description": "Testcase archive, .zip, .tar.gz or .tar.xz, in the standard (input/inputN.txt, output/outputN.txt) or flat (N.in, N.out or N.ans) layout, with an optional manifest.json"
+                  },
+                  "dryRun": {
+                    "type": "boolean"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "testcases"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Replaced",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/EditTestcasesResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, with the report when the archive is invalid",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/EditTestcasesResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/editQuestion": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1EditQuestion",
+        "summary": "Use PATCH /v2/questions/{id}",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "time": {
+                    "type": "integer"
+                  }
+                },
+                "required": [
+                  "id",
+                  "name",
+                  "time"
+                ]
+              }
+            },
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "time": {
+                    "type": "integer"
+                  }
+                },
+                "required": [
+                  "id",
+                  "name",
+                  "time"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Edited",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/deleteQuestion": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1DeleteQuestion",
+        "summary": "Use DELETE /v2/questions/{id}",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  }
+                },
+                "required": [
+                  "id"
+                ]
+              }
+            },
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  }
+                },
+                "required": [
+                  "id"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Deleted",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/addTestcase": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1AddTestcase",
+        "summary": "Use POST /v2/questions/{id}/testcases",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "input": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "output": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "description": {
+                    "type": "string"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "input",
+                  "output"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcasesResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/replaceTestcase": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1ReplaceTestcase",
+        "summary": "Use PATCH /v2/questions/{id}/testcases/{index}",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "index": {
+                    "type": "integer"
+                  },
+                  "input": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "output": {
+                    "type": "string",
+                    "format": "binary"
+                  },
+                  "name": {
+                    "type": "string"
+                  },
+                  "description": {
+                    "type": "string"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "index"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcasesResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/deleteTestcase": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1DeleteTestcase",
+        "summary": "Use DELETE /v2/questions/{id}/testcases/{index}",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "index": {
+                    "type": "integer"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "index"
+                ]
+              }
+            },
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "index": {
+                    "type": "integer"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "index"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcasesResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/reorderTestcases": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1ReorderTestcases",
+        "summary": "Use PUT /v2/questions/{id}/testcases/order",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "order": {
+                    "type": "string",
+                    "example": "3,1,2"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "order"
+                ]
+              }
+            },
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "id": {
+                    "$ref": "#/components/schemas/ObjectID"
+                  },
+                  "order": {
+                    "type": "string",
+                    "example": "3,1,2"
+                  },
+                  "rejudge": {
+                    "type": "boolean"
+                  }
+                },
+                "required": [
+                  "id",
+                  "order"
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Testcases of the question",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TestcasesResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/downloadTestcase": {
+      "get": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1DownloadTestcase",
+        "summary": "Use GET /v2/questions/{id}/testcases/{index}/{file}",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "query",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          },
+          {
+            "name": "index",
+            "in": "query",
+            "required": true,
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "file",
+            "in": "query",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "enum": [
+                "input",
+                "output"
+              ]
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "File content",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/exportQuestion": {
+      "get": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1ExportQuestion",
+        "summary": "Use GET /v2/questions/{id}/export",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "query",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Zip archive",
+            "content": {
+              "application/zip": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/rejudge": {
+      "post": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1Rejudge",
+        "summary": "Use POST /v2/rejudges",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/RejudgeRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Submissions queued",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RejudgeResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Shutting down",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/rejudgeStatus": {
+      "get": {
+        "tags": [
+          "v1 (deprecated)"
+        ],
+        "deprecated": true,
+        "operationId": "v1RejudgeStatus",
+        "summary": "Use GET /v2/rejudges/{id}",
+        "security": [
+          {
+            "adminToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "id",
+            "in": "query",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "pattern": "^[0-9a-f]{24}$",
+              "example": "5f8d0d55b54764421b7156c9"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Progress",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RejudgeStatusResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Failure, always 400 for compatibility",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "Admin token missing",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TemplateResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "ObjectID": {
+        "type": "string",
+        "pattern": "^[0-9a-f]{24}$",
+        "example": "5f8d0d55b54764421b7156c9"
+      },
+      "Language": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "time",
+          "filename",
+          "compile",
+          "execute",
+          "artifacts",
+          "limits",
+          "disabled",
+          "archived"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "name": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "description": "Time limit multiplier of the language"
+          },
+          "filename": {
+            "type": "string"
+          },
+          "compile": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Command"
+              }
+            ],
+            "nullable": true,
+            "description": "Null for languages run from source"
+          },
+          "execute": {
+            "$ref": "#/components/schemas/Command"
+          },
+          "artifacts": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "Files or glob patterns carried from the compile folder to the run folder, everything when empty",
+            "example": [
+              "*.class"
+            ]
+          },
+          "limits": {
+            "$ref": "#/components/schemas/LanguageLimits"
+          },
+          "probe": {
+            "$ref": "#/components/schemas/LanguageProbe"
+          },
+          "self_test": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/SelfTest"
+              }
+            ],
+            "description": "Missing until a worker ran the probe of the language"
+          },
+          "disabled": {
+            "type": "boolean",
+            "description": "Refused for submissions, only admins set it"
+          },
+          "archived": {
+            "type": "boolean",
+            "description": "Hidden from contestants, still judged"
+          },
+          "deleted_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Set once soft deleted"
+          }
+        }
+      },
+      "LanguageInput": {
+        "type": "object",
+        "required": [
+          "name",
+          "time",
+          "filename",
+          "execute"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "minimum": 1
+          },
+          "filename": {
+            "type": "string"
+          },
+          "compile": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Command"
+              }
+            ],
+            "nullable": true,
+            "description": "Null for languages run from source"
+          },
+          "execute": {
+            "$ref": "#/components/schemas/Command"
+          },
+          "artifacts": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "Files or glob patterns carried from the compile folder to the run folder, everything when empty",
+            "example": [
+              "*.class"
+            ]
+          },
+          "limits": {
+            "$ref": "#/components/schemas/LanguageLimits"
+          },
+          "probe": {
+            "$ref": "#/components/schemas/LanguageProbe"
+          },
+          "disabled": {
+            "type": "boolean",
+            "description": "Enabling a language that failed its self-test is refused"
+          }
+        }
+      },
+      "LanguagePatch": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "minimum": 1
+          },
+          "filename": {
+            "type": "string"
+          },
+          "compile": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/Command"
+              }
+            ],
+            "description": "An empty argv removes the compile step"
+          },
+          "execute": {
+            "$ref": "#/components/schemas/Command"
+          },
+          "artifacts": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "Files or glob patterns carried from the compile folder to the run folder, everything when empty",
+            "example": [
+              "*.class"
+            ]
+          },
+          "limits": {
+            "$ref": "#/components/schemas/LanguageLimits"
+          },
+          "probe": {
+            "$ref": "#/components/schemas/LanguageProbe"
+          },
+          "disabled": {
+            "type": "boolean",
+            "description": "Enabling a language that failed its self-test is refused"
+          },
+          "archived": {
+            "type": "boolean",
+            "description": "Hide from contestants or show again"
+          }
+        }
+      },
+      "Command": {
+        "type": "object",
+        "required": [
+          "argv"
+        ],
+        "description": "Command run without a shell. Arguments may hold the placeholders {src}, {workdir}, {binary} and {memlimit}.",
+        "properties": {
+          "argv": {
+            "type": "array",
+            "minItems": 1,
+            "items": {
+              "type": "string"
+            },
+            "example": [
+              "g++",
+              "-O2",
+              "-o",
+              "{binary}",
+              "{src}"
+            ]
+          },
+          "env": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            },
+            "description": "Variables added to PATH and HOME, the only ones inherited"
+          }
+        }
+      },
+      "LanguageLimits": {
+        "type": "object",
+        "description": "Limits of the language, zero keeps the default",
+        "properties": {
+          "compile_time": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Seconds, 30 by default"
+          },
+          "compile_memory": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Megabytes, 1024 by default"
+          },
+          "memory": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Megabytes given to the run step as {memlimit}, 256 by default"
+          },
+          "output": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "Megabytes the run step may print before it is stopped as OLE, 64 by default"
+          }
+        }
+      },
+      "LanguageProbe": {
+        "type": "object",
+        "required": [
+          "source"
+        ],
+        "description": "Sample program checking that a language works, it must print back whatever it reads on its standard input",
+        "properties": {
+          "source": {
+            "type": "string"
+          },
+          "version": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "Command printing the version of the compiler",
+            "example": [
+              "g++",
+              "--version"
+            ]
+          }
+        }
+      },
+      "SelfTest": {
+        "type": "object",
+        "required": [
+          "passed",
+          "verdict",
+          "checked_at"
+        ],
+        "properties": {
+          "passed": {
+            "type": "boolean"
+          },
+          "verdict": {
+            "type": "string",
+            "enum": [
+              "AC",
+              "WA",
+              "TLE",
+              "RE",
+              "CE",
+              "OLE"
+            ]
+          },
+          "version": {
+            "type": "string",
+            "description": "First line printed by the version command"
+          },
+          "output": {
+            "type": "string",
+            "description": "Compiler output or why the run failed"
+          },
+          "checked_at": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "AddLanguageRequest": {
+        "type": "object",
+        "required": [
+          "name",
+          "time",
+          "filename",
+          "compile",
+          "execute"
+        ],
+        "description": "Language with commands as single strings, split on spaces. A compile command of \"compilation-not-needed\" means no compile step.",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "minimum": 1
+          },
+          "filename": {
+            "type": "string"
+          },
+          "compile": {
+            "type": "string"
+          },
+          "execute": {
+            "type": "string"
+          }
+        }
+      },
+      "EditLanguageRequest": {
+        "allOf": [
+          {
+            "$ref": "#/components/schemas/AddLanguageRequest"
+          },
+          {
+            "type": "object",
+            "required": [
+              "id"
+            ],
+            "properties": {
+              "id": {
+                "$ref": "#/components/schemas/ObjectID"
+              }
+            }
+          }
+        ]
+      },
+      "DeleteLanguageRequest": {
+        "type": "object",
+        "required": [
+          "id"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          }
+        }
+      },
+      "Testcase": {
+        "type": "object",
+        "required": [
+          "index",
+          "number"
+        ],
+        "properties": {
+          "index": {
+            "type": "integer",
+            "description": "Display index, from 1"
+          },
+          "number": {
+            "type": "integer",
+            "description": "Number the setter gave it in the archive"
+          },
+          "name": {
+            "type": "string"
+          },
+          "description": {
+            "type": "string"
+          }
+        }
+      },
+      "Question": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "time",
+          "num_testcases",
+          "testcases",
+          "archived"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "name": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "description": "Time limit in seconds"
+          },
+          "num_testcases": {
+            "type": "integer"
+          },
+          "testcases": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Testcase"
+            }
+          },
+          "archived": {
+            "type": "boolean",
+            "description": "Hidden from contestants, still judged"
+          },
+          "deleted_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Set once soft deleted"
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Only languages submissions may use, every enabled language when empty"
+          },
+          "time_overrides": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/TimeOverride"
+            },
+            "description": "At most one per language, only for allowed languages"
+          }
+        }
+      },
+      "QuestionPatch": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "minimum": 1
+          },
+          "archived": {
+            "type": "boolean",
+            "description": "Hide from contestants or show again"
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Only languages submissions may use, an empty list accepts every language again"
+          },
+          "time_overrides": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/TimeOverride"
+            },
+            "description": "At most one per language, only for allowed languages"
+          }
+        }
+      },
+      "ArchiveIssue": {
+        "type": "object",
+        "required": [
+          "entry",
+          "reason"
+        ],
+        "properties": {
+          "entry": {
+            "type": "string"
+          },
+          "reason": {
+            "type": "string"
+          }
+        }
+      },
+      "ArchiveReport": {
+        "type": "object",
+        "required": [
+          "valid",
+          "layout",
+          "testcases",
+          "mapping",
+          "errors",
+          "warnings"
+        ],
+        "properties": {
+          "valid": {
+            "type": "boolean"
+          },
+          "layout": {
+            "type": "string",
+            "enum": [
+              "standard",
+              "flat",
+              ""
+            ]
+          },
+          "root": {
+            "type": "string"
+          },
+          "testcases": {
+            "type": "integer"
+          },
+          "mapping": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Testcase"
+            }
+          },
+          "errors": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ArchiveIssue"
+            },
+            "nullable": true
+          },
+          "warnings": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ArchiveIssue"
+            },
+            "nullable": true
+          }
+        }
+      },
+      "Error": {
+        "type": "object",
+        "required": [
+          "code",
+          "message"
+        ],
+        "properties": {
+          "code": {
+            "type": "string",
+            "enum": [
+              "invalid_request",
+              "validation_failed",
+              "unauthorized",
+              "not_found",
+              "conflict",
+              "payload_too_large",
+              "unavailable",
+              "internal"
+            ]
+          },
+          "message": {
+            "type": "string"
+          },
+          "fields": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            },
+            "description": "Error of each invalid field"
+          },
+          "report": {
+            "$ref": "#/components/schemas/ArchiveReport"
+          }
+        }
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "required": [
+          "error"
+        ],
+        "properties": {
+          "error": {
+            "$ref": "#/components/schemas/Error"
+          }
+        }
+      },
+      "TemplateResponse": {
+        "type": "object",
+        "required": [
+          "success"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean"
+          },
+          "error": {
+            "type": "string"
+          }
+        }
+      },
+      "AddLanguageResponse": {
+        "type": "object",
+        "required": [
+          "success",
+          "id"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean"
+          },
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          }
+        }
+      },
+      "AddQuestionResponse": {
+        "type": "object",
+        "required": [
+          "success",
+          "id"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean"
+          },
+          "id": {
+            "type": "string"
+          },
+          "error": {
+            "type": "string"
+          },
+          "report": {
+            "$ref": "#/components/schemas/ArchiveReport"
+          }
+        }
+      },
+      "EditTestcasesResponse": {
+        "type": "object",
+        "required": [
+          "success"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean"
+          },
+          "error": {
+            "type": "string"
+          },
+          "report": {
+            "$ref": "#/components/schemas/ArchiveReport"
+          }
+        }
+      },
+      "TestcasesResponse": {
+        "type": "object",
+        "required": [
+          "success",
+          "testcases"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean"
+          },
+          "testcases": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Testcase"
+            }
+          }
+        }
+      },
+      "LanguagesResponse": {
+        "type": "object",
+        "required": [
+          "languages"
+        ],
+        "properties": {
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Language"
+            }
+          }
+        }
+      },
+      "QuestionsResponse": {
+        "type": "object",
+        "required": [
+          "questions"
+        ],
+        "properties": {
+          "questions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Question"
+            }
+          }
+        }
+      },
+      "QuestionCreatedResponse": {
+        "type": "object",
+        "required": [
+          "report"
+        ],
+        "properties": {
+          "question": {
+            "$ref": "#/components/schemas/Question"
+          },
+          "report": {
+            "$ref": "#/components/schemas/ArchiveReport"
+          }
+        }
+      },
+      "TestcaseListResponse": {
+        "type": "object",
+        "required": [
+          "testcases"
+        ],
+        "properties": {
+          "testcases": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Testcase"
+            },
+            "nullable": true
+          },
+          "report": {
+            "$ref": "#/components/schemas/ArchiveReport"
+          }
+        }
+      },
+      "ReorderRequest": {
+        "type": "object",
+        "required": [
+          "order"
+        ],
+        "properties": {
+          "order": {
+            "type": "array",
+            "items": {
+              "type": "integer"
+            },
+            "description": "Current indices in their new order"
+          }
+        }
+      },
+      "SubmissionInput": {
+        "type": "object",
+        "required": [
+          "ques_id",
+          "lang_id",
+          "source"
+        ],
+        "properties": {
+          "ques_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "lang_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "contest_id": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/ObjectID"
+              }
+            ],
+            "description": "Contest the submission is made in, the question has to be part of it and its languages apply"
+          },
+          "source": {
+            "type": "string",
+            "maxLength": 65536,
+            "description": "Solution, saved under the file name of the language"
+          }
+        }
+      },
+      "Submission": {
+        "type": "object",
+        "required": [
+          "id",
+          "lang_id",
+          "ques_id",
+          "verdict",
+          "testcases"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "lang_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "ques_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "contest_id": {
+            "allOf": [
+              {
+                "$ref": "#/components/schemas/ObjectID"
+              }
+            ],
+            "description": "Contest the submission was made in, missing outside of one"
+          },
+          "verdict": {
+            "type": "string",
+            "enum": [
+              "QU",
+              "AC",
+              "WA",
+              "TLE",
+              "RE",
+              "CE",
+              "OLE"
+            ]
+          },
+          "testcases": {
+            "type": "object",
+            "description": "Verdict by display index of the testcase",
+            "additionalProperties": {
+              "type": "string"
+            }
+          },
+          "results": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/TestcaseResult"
+            }
+          }
+        }
+      },
+      "TestcaseResult": {
+        "type": "object",
+        "required": [
+          "index",
+          "number",
+          "verdict",
+          "time_ms"
+        ],
+        "properties": {
+          "index": {
+            "type": "integer",
+            "description": "Display index of the testcase"
+          },
+          "number": {
+            "type": "integer",
+            "description": "Number the setter gave the testcase"
+          },
+          "verdict": {
+            "type": "string"
+          },
+          "time_ms": {
+            "type": "integer"
+          }
+        }
+      },
+      "RejudgeRequest": {
+        "type": "object",
+        "description": "At least one filter is required",
+        "properties": {
+          "submission_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "question_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "verdict": {
+            "type": "string",
+            "enum": [
+              "AC",
+              "WA",
+              "TLE",
+              "RE",
+              "CE",
+              "OLE"
+            ]
+          },
+          "since": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "RejudgeResponse": {
+        "type": "object",
+        "required": [
+          "success",
+          "id",
+          "submissions"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean"
+          },
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "submissions": {
+            "type": "integer"
+          }
+        }
+      },
+      "RejudgeCreatedResponse": {
+        "type": "object",
+        "required": [
+          "id",
+          "submissions"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "submissions": {
+            "type": "integer"
+          }
+        }
+      },
+      "Rejudge": {
+        "type": "object",
+        "required": [
+          "id",
+          "filter",
+          "submissions",
+          "created_at",
+          "queued"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "filter": {
+            "$ref": "#/components/schemas/RejudgeRequest"
+          },
+          "submissions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            }
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "queued": {
+            "type": "boolean",
+            "description": "Whether every submission was queued again, false while the rejudge is still being finished"
+          }
+        }
+      },
+      "VerdictChange": {
+        "type": "object",
+        "required": [
+          "submission_id",
+          "before",
+          "after"
+        ],
+        "properties": {
+          "submission_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "before": {
+            "type": "string"
+          },
+          "after": {
+            "type": "string"
+          }
+        }
+      },
+      "RejudgeStatusResponse": {
+        "type": "object",
+        "required": [
+          "rejudge",
+          "pending",
+          "unchanged",
+          "changed"
+        ],
+        "properties": {
+          "success": {
+            "type": "boolean",
+            "description": "Only sent by the v1 route"
+          },
+          "rejudge": {
+            "$ref": "#/components/schemas/Rejudge"
+          },
+          "pending": {
+            "type": "integer"
+          },
+          "unchanged": {
+            "type": "integer"
+          },
+          "changed": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/VerdictChange"
+            }
+          }
+        }
+      },
+      "Worker": {
+        "type": "object",
+        "required": [
+          "id",
+          "host",
+          "slots",
+          "languages",
+          "failed",
+          "started_at",
+          "heartbeat_at",
+          "expires_at"
+        ],
+        "description": "Judge process, advertised again every heartbeat",
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "host": {
+            "type": "string"
+          },
+          "slots": {
+            "type": "integer",
+            "description": "Submissions it judges at once"
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Languages whose probe passed on the worker, only their submissions are routed to it"
+          },
+          "failed": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Languages whose probe failed on the worker, which keep working on other workers"
+          },
+          "started_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "heartbeat_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "expires_at": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Considered gone after this without a heartbeat, its claimed jobs go back to the queue"
+          }
+        }
+      },
+      "LanguageCoverage": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "disabled",
+          "probe",
+          "workers",
+          "failing"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "name": {
+            "type": "string"
+          },
+          "disabled": {
+            "type": "boolean"
+          },
+          "probe": {
+            "type": "boolean",
+            "description": "Languages without a probe cannot be verified, so no worker runs them"
+          },
+          "workers": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "IDs of the live workers able to run it"
+          },
+          "failing": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "IDs of the live workers its probe failed on"
+          }
+        }
+      },
+      "WorkersResponse": {
+        "type": "object",
+        "required": [
+          "workers",
+          "languages",
+          "uncovered"
+        ],
+        "properties": {
+          "workers": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Worker"
+            }
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/LanguageCoverage"
+            }
+          },
+          "uncovered": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/LanguageCoverage"
+            },
+            "description": "Languages no live worker can run, their submissions wait in the queue"
+          }
+        }
+      },
+      "ComponentHealth": {
+        "type": "object",
+        "required": [
+          "name",
+          "status",
+          "latency_ms"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "ok",
+              "fail"
+            ]
+          },
+          "latency_ms": {
+            "type": "number"
+          },
+          "error": {
+            "type": "string"
+          }
+        }
+      },
+      "HealthResponse": {
+        "type": "object",
+        "required": [
+          "status"
+        ],
+        "properties": {
+          "status": {
+            "type": "string",
+            "enum": [
+              "ok",
+              "fail"
+            ]
+          },
+          "components": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ComponentHealth"
+            }
+          }
+        }
+      },
+      "TimeOverride": {
+        "type": "object",
+        "required": [
+          "lang_id",
+          "time"
+        ],
+        "properties": {
+          "lang_id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "time": {
+            "type": "integer",
+            "minimum": 1,
+            "description": "Time limit in seconds replacing the question time limit times the language multiplier"
+          }
+        }
+      },
+      "QuestionLanguage": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "filename",
+          "time",
+          "override"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "name": {
+            "type": "string"
+          },
+          "filename": {
+            "type": "string"
+          },
+          "time": {
+            "type": "integer",
+            "description": "Time limit in seconds per testcase"
+          },
+          "override": {
+            "type": "boolean",
+            "description": "The time limit comes from a time override of the question"
+          }
+        }
+      },
+      "QuestionLanguagesResponse": {
+        "type": "object",
+        "required": [
+          "languages"
+        ],
+        "properties": {
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/QuestionLanguage"
+            }
+          }
+        }
+      },
+      "Contest": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "questions"
+        ],
+        "properties": {
+          "id": {
+            "$ref": "#/components/schemas/ObjectID"
+          },
+          "name": {
+            "type": "string"
+          },
+          "questions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            }
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Only languages submissions in the contest may use, on top of those their question allows. Empty or missing accepts every language."
+          }
+        }
+      },
+      "ContestInput": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": [
+          "name"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "questions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Existing questions, deleted or not"
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Only languages submissions in the contest may use, on top of those their question allows. Empty or missing accepts every language."
+          }
+        }
+      },
+      "ContestPatch": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "questions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            }
+          },
+          "languages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ObjectID"
+            },
+            "description": "Only languages submissions in the contest may use, an empty list accepts every language again"
+          }
+        }
+      },
+      "ContestsResponse": {
+        "type": "object",
+        "required": [
+          "contests"
+        ],
+        "properties": {
+          "contests": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Contest"
+            }
+          }
+        }
+      }
+    },
+    "securitySchemes": {
+      "adminToken": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "Admin token from the admin_token setting"
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// routeVariable : Variables of route templates, with their pattern if any
+var routeVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := newTestServer(t)
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	rec := s.get("/openapi.json", false)
+	expectStatus(t, rec, http.StatusOK)
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not JSON: %v", err)
+	}
+
+	routed := map[string]bool{}
+	err := s.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Prefixes of subrouters, their routes are walked on their own
+			return nil
+		}
+		path := routeVariable.ReplaceAllString(template, "{$1}")
+		for _, method := range methods {
+			method = strings.ToLower(method)
+			routed[method+" "+path] = true
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("%s %s is routed but missing in the spec", strings.ToUpper(method), path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routed) == 0 {
+		t.Fatal("no route walked")
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			if !routed[method+" "+path] {
+				t.Errorf("%s %s is in the spec but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
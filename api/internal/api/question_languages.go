@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned when a submission does not fit its question or contest
+var (
+	ErrLanguageNotAllowed = errors.New("the question or contest does not accept this language")
+	ErrLanguageDisabled   = errors.New("the language is disabled")
+)
+
+// QuestionLanguage : Language a question accepts, with the time limit
+// solutions in it get per testcase
+type QuestionLanguage struct {
+	ID       primitive.ObjectID `json:"id"`
+	Name     string             `json:"name"`
+	Filename string             `json:"filename"`
+	Time     int                `json:"time"`
+	Override bool               `json:"override"` // Time comes from a time override of the question
+}
+
+// QuestionLanguagesResponse : Languages a question accepts
+type QuestionLanguagesResponse struct {
+	Languages []QuestionLanguage `json:"languages"`
+}
+
+// timeOverridesRule : Every override needs a language, at most one per
+// language, and a time limit of at least a second
+func timeOverridesRule(value interface{}) error {
+	overrides, ok := value.(*[]TimeOverride)
+	if !ok || overrides == nil {
+		return nil
+	}
+	seen := make(map[primitive.ObjectID]bool)
+	for _, override := range *overrides {
+		switch {
+		case override.LanguageID.IsZero():
+			return errors.New("lang_id is required")
+		case seen[override.LanguageID]:
+			return fmt.Errorf("language %s is overridden twice", override.LanguageID.Hex())
+		case override.Time < 1:
+			return fmt.Errorf("time of language %s must be at least 1", override.LanguageID.Hex())
+		}
+		seen[override.LanguageID] = true
+	}
+	return nil
+}
+
+// allows : Whether the question accepts submissions in the language
+func (q Question) allows(ID primitive.ObjectID) bool {
+	if len(q.Languages) <= 0 {
+		return true
+	}
+	for _, allowed := range q.Languages {
+		if allowed == ID {
+			return true
+		}
+	}
+	return false
+}
+
+// timeOverride : Time limit in seconds the question sets for the
+// language, zero without an override
+func (q Question) timeOverride(ID primitive.ObjectID) int {
+	for _, override := range q.TimeOverrides {
+		if override.LanguageID == ID {
+			return override.Time
+		}
+	}
+	return 0
+}
+
+// timeLimit : Time a solution in the language gets per testcase, the
+// override of the question if any, otherwise its time limit times the
+// multiplier of the language
+func (q Question) timeLimit(language Language) time.Duration {
+	if seconds := q.timeOverride(language.ID); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return language.runnerLanguage().TimeLimit(q.Time)
+}
+
+// validateQuestionLanguages : Checks that the languages the patch refers
+// to exist and that overrides only concern languages the question allows
+func (api *API) validateQuestionLanguages(ctx context.Context, ID primitive.ObjectID, patch QuestionPatch) error {
+	question, err := api.Questions.Get(ctx, ID)
+	if err != nil {
+		return err
+	}
+	if patch.Languages != nil {
+		question.Languages = *patch.Languages
+	}
+	if patch.TimeOverrides != nil {
+		question.TimeOverrides = *patch.TimeOverrides
+	}
+
+	referenced := append([]primitive.ObjectID{}, question.Languages...)
+	for _, override := range question.TimeOverrides {
+		referenced = append(referenced, override.LanguageID)
+	}
+	languages, err := api.Languages.Find(ctx, referenced)
+	if err != nil {
+		return err
+	}
+	known := make(map[primitive.ObjectID]bool)
+	for _, language := range languages {
+		known[language.ID] = true
+	}
+
+	fieldErrs := validation.Errors{}
+	for _, ID := range question.Languages {
+		if !known[ID] {
+			fieldErrs["languages"] = fmt.Errorf("no such language %s", ID.Hex())
+		}
+	}
+	for _, override := range question.TimeOverrides {
+		switch {
+		case !known[override.LanguageID]:
+			fieldErrs["time_overrides"] = fmt.Errorf("no such language %s", override.LanguageID.Hex())
+		case !question.allows(override.LanguageID):
+			fieldErrs["time_overrides"] = fmt.Errorf("language %s is not allowed by the question", override.LanguageID.Hex())
+		}
+	}
+	return fieldErrs.Filter()
+}
+
+// questionLanguages : Enabled languages the question accepts, by name,
+// within the contest when there is one
+func (api *API) questionLanguages(ctx context.Context, question Question, contest *Contest) ([]QuestionLanguage, error) {
+	languages, err := api.Languages.List(ctx, visibleToContestants)
+	if err != nil {
+		return nil, err
+	}
+	accepted := []QuestionLanguage{}
+	for _, language := range languages {
+		if !language.Disabled && question.allows(language.ID) && contest.allows(language.ID) {
+			accepted = append(accepted, question.accepts(language))
+		}
+	}
+	return accepted, nil
+}
+
+// accepts : Language as the question accepts it
+func (q Question) accepts(language Language) QuestionLanguage {
+	return QuestionLanguage{
+		ID:       language.ID,
+		Name:     language.Name,
+		Filename: language.Filename,
+		Time:     int(q.timeLimit(language) / time.Second),
+		Override: q.timeOverride(language.ID) > 0,
+	}
+}
+
+// submissionLanguage : Language a submission to the question is made in,
+// refused with the reason and the languages to use instead when the
+// question or the contest it is made in does not take it
+func (api *API) submissionLanguage(ctx context.Context, question Question, contest *Contest, ID primitive.ObjectID) (Language, error) {
+	language, err := api.Languages.Get(ctx, ID)
+	if err != nil {
+		return language, err
+	}
+	if language.Archived {
+		return language, ErrNoSuchLanguage
+	}
+	if language.Disabled {
+		return language, fmt.Errorf("%w: %s does not take submissions right now", ErrLanguageDisabled, language.Name)
+	}
+	if question.allows(ID) && contest.allows(ID) {
+		return language, nil
+	}
+
+	refusal := "for " + question.Name
+	if question.allows(ID) {
+		refusal = "in " + contest.Name
+	}
+	accepted, err := api.questionLanguages(ctx, question, contest)
+	if err != nil {
+		return language, err
+	}
+	if len(accepted) <= 0 {
+		return language, fmt.Errorf("%w: %s is not allowed %s, and no enabled language is allowed right now",
+			ErrLanguageNotAllowed, language.Name, refusal)
+	}
+	names := make([]string, len(accepted))
+	for i, other := range accepted {
+		names[i] = other.Name
+	}
+	return language, fmt.Errorf("%w: %s is not allowed %s, use one of %s",
+		ErrLanguageNotAllowed, language.Name, refusal, strings.Join(names, ", "))
+}
+
+// visibleQuestion : Question given in the {id} part of the route, archived
+// ones only for admins
+func (api *API) visibleQuestion(r *http.Request) (Question, error) {
+	question, err := api.pathQuestion(r)
+	if err == nil && question.Archived && api.requestVisibility(r) == visibleToContestants {
+		err = ErrNoSuchQuestion
+	}
+	return question, err
+}
+
+func (api *API) listQuestionLanguagesV2(w http.ResponseWriter, r *http.Request) {
+	question, err := api.visibleQuestion(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	contest, err := api.questionContest(r.Context(), question, r.URL.Query().Get("contest_id"))
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	languages, err := api.questionLanguages(r.Context(), question, contest)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, QuestionLanguagesResponse{Languages: languages})
+}
+
+// getQuestionLanguageV2 : Whether a submission in the language would be
+// accepted, in the contest of the "contest_id" query when given, checked
+// the same way as at submit time
+func (api *API) getQuestionLanguageV2(w http.ResponseWriter, r *http.Request) {
+	question, err := api.visibleQuestion(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	ID, err := parseID(mux.Vars(r)["language"])
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	contest, err := api.questionContest(r.Context(), question, r.URL.Query().Get("contest_id"))
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	language, err := api.submissionLanguage(r.Context(), question, contest, ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, question.accepts(language))
+}
@@ -1,721 +1,329 @@
 package api
 
 import (
-	"archive/zip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"regexp"
 	"strconv"
-	"strings"
+	"time"
 
+	validation "github.com/go-ozzo/ozzo-validation/v3"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AddQuestionResponse : ID of the added question
 type AddQuestionResponse struct {
-	Success bool   `json:"success"`
-	ID      string `json:"id"`
+	Success bool           `json:"success"`
+	ID      string         `json:"id"`
+	Error   string         `json:"error,omitempty"`
+	Report  *ArchiveReport `json:"report,omitempty"`
 }
 
-var validInputFile = regexp.MustCompile(`^input/input([0-9]+)\.([a-zA-Z]+)$`)
-var validOutputFile = regexp.MustCompile(`^output/output([0-9]+)\.([a-zA-Z]+)$`)
-
-func (api *API) addQuestionHandler(w http.ResponseWriter, r *http.Request) {
-	// Time limit for the question in seconds
-	timeStr := r.FormValue("time")
-	if len(timeStr) <= 0 {
-		api.Log.Info("Time field missing")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
-	time, err := strconv.Atoi(timeStr)
-	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
+// EditTestcasesResponse : Validation report of the new testcases
+type EditTestcasesResponse struct {
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+	Report  *ArchiveReport `json:"report,omitempty"`
+}
 
-	// Name for question
-	name := r.FormValue("name")
-	if len(name) <= 0 {
-		api.Log.Info("Name field missing")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
+// maxFormOverhead : Room left in a request body for the form fields
+// sent alongside the testcase archive
+const maxFormOverhead = 1 << 20
+
+// QuestionPatch : Fields of a question to change, missing ones are kept
+type QuestionPatch struct {
+	Name          *string               `json:"name"`
+	Time          *int                  `json:"time"`
+	Archived      *bool                 `json:"archived"`
+	Languages     *[]primitive.ObjectID `json:"languages"` // Empty to allow every language again
+	TimeOverrides *[]TimeOverride       `json:"time_overrides"`
+}
 
-	// ObjectID for the new question
-	ID := primitive.NewObjectID()
+func (p QuestionPatch) validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.NilOrNotEmpty),
+		validation.Field(&p.Time, validation.NilOrNotEmpty, validation.Min(1)),
+		validation.Field(&p.TimeOverrides, validation.By(timeOverridesRule)),
+	)
+}
 
-	// Zip file for the testcases of the new question
-	file, handler, err := r.FormFile("testcases")
-	defer file.Close()
-	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+// apply : Changes the fields of the question the patch sets
+func (p QuestionPatch) apply(question *Question) {
+	if p.Name != nil {
+		question.Name = *p.Name
 	}
-	if !strings.HasSuffix(handler.Filename, ".zip") {
-		api.Log.Info("Testcases should be a zip file, please refer <link> for more info")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.Time != nil {
+		question.Time = *p.Time
 	}
-
-	// Target destination paths for the testcases
-	folderPath := fmt.Sprintf("testcases/%s/", ID.Hex())
-	filePath := folderPath + handler.Filename
-	if err = os.MkdirAll(folderPath, os.ModePerm); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.Archived != nil {
+		question.Archived = *p.Archived
 	}
-	if err = os.MkdirAll(folderPath+"input/", os.ModePerm); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.Languages != nil {
+		question.Languages = *p.Languages
 	}
-	if err = os.MkdirAll(folderPath+"output/", os.ModePerm); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.TimeOverrides != nil {
+		question.TimeOverrides = *p.TimeOverrides
 	}
+}
 
-	// Target path for saving the zip
-	f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
-	defer f.Close()
-	if err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+// update : $set document of the patch
+func (p QuestionPatch) update() bson.M {
+	set := bson.M{}
+	if p.Name != nil {
+		set["name"] = *p.Name
 	}
-	_, err = io.Copy(f, file)
-	if err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.Time != nil {
+		set["time"] = *p.Time
 	}
-	defer os.Remove(filePath)
-
-	// Zip reader object
-	zipr, err := zip.OpenReader(filePath)
-	if err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.Archived != nil {
+		set["archived"] = *p.Archived
 	}
-	defer zipr.Close()
-
-	validZip := true
-	inputFiles := make(map[int]bool)
-	outputFiles := make(map[int]bool)
-
-	for _, item := range zipr.File {
-		if item.Name == "input/" || item.Name == "output/" {
-			continue
-		}
-		if validInputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "input/input")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if _, ok := inputFiles[fileNumber]; ok && inputFiles[fileNumber] {
-				validZip = false
-				break
-			} else {
-				inputFiles[fileNumber] = true
-			}
-		} else if validOutputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "output/output")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if _, ok := outputFiles[fileNumber]; ok && outputFiles[fileNumber] {
-				validZip = false
-				break
-			} else {
-				outputFiles[fileNumber] = true
-			}
-		} else {
-			validZip = false
-			break
-		}
+	if p.Languages != nil {
+		set["languages"] = *p.Languages
 	}
-
-	if !validZip {
-		os.RemoveAll(folderPath)
-		api.Log.Info("Not a valid zip")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if p.TimeOverrides != nil {
+		set["time_overrides"] = *p.TimeOverrides
 	}
+	return bson.M{"$set": set}
+}
 
-	json.NewEncoder(w).Encode(AddQuestionResponse{
-		Success: true,
-		ID:      ID.Hex(),
-	})
-
-	api.Log.Info(fmt.Sprintf("Copying files for question %s...", ID.Hex()))
-
-	finalTestcases := make(map[int]int)
-	currentTestcase := 0
-
-	for _, item := range zipr.File {
-		if item.Name == "input/" || item.Name == "output/" {
-			continue
-		}
-		if validInputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "input/input")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			vali, oki := inputFiles[fileNumber]
-			valo, oko := outputFiles[fileNumber]
-			if oki && vali && oko && valo {
-				currentTestcase++
-				finalTestcases[fileNumber] = currentTestcase
-			}
-		}
+// updateQuestion : Applies the patch to the question with the given ID
+func (api *API) updateQuestion(ctx context.Context, ID primitive.ObjectID, patch QuestionPatch) (Question, error) {
+	if err := patch.validate(); err != nil {
+		return Question{}, err
 	}
-
-	for _, item := range zipr.File {
-		if item.Name == "input/" || item.Name == "output/" {
-			continue
+	if patch.Languages != nil || patch.TimeOverrides != nil {
+		if err := api.validateQuestionLanguages(ctx, ID, patch); err != nil {
+			return Question{}, err
 		}
-		if validInputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "input/input")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if val, ok := finalTestcases[fileNumber]; ok {
-				targetFile, err := os.OpenFile(fmt.Sprintf("%sinput/input%d.txt", folderPath, val), os.O_RDWR|os.O_CREATE, 0666)
-				if err != nil {
-					validZip = false
-					break
-				}
-				srcZipFile, err := item.Open()
-				_, err = io.Copy(targetFile, srcZipFile)
-				if err != nil {
-					validZip = false
-					break
-				}
-				targetFile.Close()
-				srcZipFile.Close()
-			}
-		} else if validOutputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "output/output")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if val, ok := finalTestcases[fileNumber]; ok {
-				targetFile, err := os.OpenFile(fmt.Sprintf("%soutput/output%d.txt", folderPath, val), os.O_RDWR|os.O_CREATE, 0666)
-				if err != nil {
-					validZip = false
-					break
-				}
-				srcZipFile, err := item.Open()
-				_, err = io.Copy(targetFile, srcZipFile)
-				if err != nil {
-					validZip = false
-					break
-				}
-				targetFile.Close()
-				srcZipFile.Close()
-			}
-		} else {
-			validZip = false
-			break
-		}
-	}
-
-	if !validZip {
-		os.RemoveAll(folderPath)
-		api.Log.Info("Not a valid zip")
-		return
-	}
-
-	question := Question{
-		ID:           ID,
-		Time:         time,
-		Name:         name,
-		NumTestcases: currentTestcase,
 	}
 
-	_, err = api.Db.Collection("questions").InsertOne(r.Context(), question)
-	if err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
-		return
-	}
-
-	api.Log.Info(fmt.Sprintf("Extraction done for question %s...", ID.Hex()))
+	return api.Questions.Update(ctx, ID, patch)
 }
 
-func (api *API) editTestcasesHandler(w http.ResponseWriter, r *http.Request) {
-	// ID of the question whose testcases need to be edited
-	id := r.FormValue("id")
-	if len(id) <= 0 {
-		api.Log.Info("Missing id field")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
-	ID, err := primitive.ObjectIDFromHex(id)
+// importTestcases : Saves and validates the "testcases" archive of the
+// request, then extracts it as the testcases of ID unless dryRun is set.
+// check runs once the archive is valid, before anything is extracted, and
+// record stores the extracted testcases before they replace the old ones.
+func (api *API) importTestcases(r *http.Request, ID primitive.ObjectID, dryRun bool, check func(*testcaseArchive) error, record func([]Testcase) error) (*ArchiveReport, []Testcase, error) {
+	filePath, format, err := saveTestcaseUpload(r, api.Limits)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+		if errors.Is(err, ErrArchiveTooLarge) {
+			return nil, nil, err
+		}
+		return nil, nil, badRequest(err)
 	}
+	defer os.Remove(filePath)
 
-	// Checking if the question with this ID exists
-	singleResult := api.Db.Collection("questions").FindOne(r.Context(), bson.M{"_id": bson.M{"$eq": ID}})
-	if singleResult.Err() != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
+	outcome := importError
+	defer observeImport(time.Now(), &outcome)
 
-	// Zip file for the testcases of the new question
-	file, handler, err := r.FormFile("testcases")
-	defer file.Close()
+	archive, err := openTestcaseArchive(filePath, format, api.Limits)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+		outcome = importInvalid
+		return nil, nil, badRequest(err)
+	}
+	defer archive.Close()
+
+	if !archive.report.Valid {
+		outcome = importInvalid
+		return &archive.report, nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    CodeInvalidRequest,
+			Message: ErrInvalidArchive.Error(),
+			Report:  &archive.report,
+			cause:   ErrInvalidArchive,
+		}
 	}
-	if !strings.HasSuffix(handler.Filename, ".zip") {
-		api.Log.Info("Testcases should be a zip file, please refer <link> for more info")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if err = check(archive); err != nil {
+		outcome = importInvalid
+		return &archive.report, nil, err
 	}
 
-	// Target destination paths for the testcases
-	folderPath := fmt.Sprintf("testcases/%s/", ID.Hex())
-	filePath := folderPath + handler.Filename
-	if err = os.MkdirAll(folderPath, os.ModePerm); err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	// Dry runs stop once the archive has been validated
+	if dryRun {
+		outcome = importDryRun
+		return &archive.report, nil, nil
 	}
 
-	// Target path for saving the zip
-	f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
-	defer f.Close()
+	api.log(r.Context()).Info(fmt.Sprintf("Copying files for question %s...", ID.Hex()))
+
+	// Old testcases are only replaced once the new ones are fully
+	// extracted, and come back when recording the new ones fails
+	stage, err := api.stageTestcases(ID)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+		return &archive.report, nil, err
 	}
-	_, err = io.Copy(f, file)
+	testcases, err := archive.extract(stage.staging)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+		stage.discard()
+		return &archive.report, nil, err
 	}
-	defer os.Remove(filePath)
-
-	// Zip reader object
-	zipr, err := zip.OpenReader(filePath)
+	err = stage.commit(func() error {
+		return record(testcases)
+	})
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+		return &archive.report, nil, err
 	}
-	defer zipr.Close()
+	outcome = importSuccess
 
-	validZip := true
-	inputFiles := make(map[int]bool)
-	outputFiles := make(map[int]bool)
+	api.log(r.Context()).Info(fmt.Sprintf("Extraction done for question %s...", ID.Hex()))
+	return &archive.report, testcases, nil
+}
 
-	for _, item := range zipr.File {
-		if item.Name == "input/" || item.Name == "output/" {
-			continue
+// createQuestion : New question from the uploaded archive and the "name"
+// and "time" form fields. Exported questions carry their name and time
+// limit in the manifest, the form fields win and the configured default
+// applies when neither has a time limit.
+func (api *API) createQuestion(r *http.Request, dryRun bool) (Question, *ArchiveReport, error) {
+	question := Question{ID: primitive.NewObjectID()}
+
+	report, _, err := api.importTestcases(r, question.ID, dryRun, func(archive *testcaseArchive) error {
+		question.Name = r.FormValue("name")
+		if len(question.Name) <= 0 {
+			question.Name = archive.metadata.Name
 		}
-		if validInputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "input/input")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if _, ok := inputFiles[fileNumber]; ok && inputFiles[fileNumber] {
-				validZip = false
-				break
-			} else {
-				inputFiles[fileNumber] = true
-			}
-		} else if validOutputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "output/output")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if _, ok := outputFiles[fileNumber]; ok && outputFiles[fileNumber] {
-				validZip = false
-				break
-			} else {
-				outputFiles[fileNumber] = true
-			}
-		} else {
-			validZip = false
-			break
+		question.Time = api.Config.Limits.TimeLimit
+		if archive.metadata.Time > 0 {
+			question.Time = archive.metadata.Time
 		}
-	}
+		var timeErr error
+		if timeStr := r.FormValue("time"); len(timeStr) > 0 {
+			question.Time, timeErr = strconv.Atoi(timeStr)
+		}
+		if timeErr != nil {
+			return validation.Errors{"time": errors.New("must be an integer")}
+		}
+		return validation.ValidateStruct(&question,
+			validation.Field(&question.Name, validation.Required),
+			validation.Field(&question.Time, validation.Min(1)),
+		)
+	}, func(testcases []Testcase) error {
+		question.NumTestcases = len(testcases)
+		question.Testcases = testcases
+		return api.Questions.Insert(r.Context(), question)
+	})
+	return question, report, err
+}
 
-	if !validZip {
-		api.Log.Info("Not a valid zip")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+// replaceQuestionTestcases : Replaces every testcase of the question with
+// those of the uploaded archive
+func (api *API) replaceQuestionTestcases(r *http.Request, ID primitive.ObjectID, dryRun bool) (*ArchiveReport, []Testcase, error) {
+	holder, unlock, err := api.lockTestcases(r.Context(), ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+	if _, err = api.Questions.Get(r.Context(), ID); err != nil {
+		return nil, nil, err
 	}
 
-	json.NewEncoder(w).Encode(TemplateResponse{
-		Success: true,
+	return api.importTestcases(r, ID, dryRun, func(*testcaseArchive) error {
+		return nil
+	}, func(testcases []Testcase) error {
+		return api.Questions.SetTestcases(r.Context(), ID, holder, testcases)
 	})
+}
 
-	api.Log.Info(fmt.Sprintf("Copying files for question %s...", ID.Hex()))
+func (api *API) addQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, api.Limits.MaxArchiveSize+maxFormOverhead)
 
-	// Cleaning out old testcases
-	if err = os.RemoveAll(folderPath + "input/"); err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
-		return
-	}
-	if err = os.RemoveAll(folderPath + "output/"); err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
-		return
-	}
-	if err = os.MkdirAll(folderPath+"input/", os.ModePerm); err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
+	// Only validate the testcases, without storing anything
+	dryRun := isDryRun(r)
+
+	question, report, err := api.createQuestion(r, dryRun)
+	if apiErr := toAPIError(err); err != nil && apiErr.Report != nil {
+		api.log(r.Context()).Info(err.Error())
+		writeJSON(w, http.StatusBadRequest, AddQuestionResponse{
+			Success: false,
+			Error:   apiErr.Message,
+			Report:  apiErr.Report,
+		})
 		return
 	}
-	if err = os.MkdirAll(folderPath+"output/", os.ModePerm); err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
+	if err != nil {
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	finalTestcases := make(map[int]int)
-	currentTestcase := 0
-
-	for _, item := range zipr.File {
-		if item.Name == "input/" || item.Name == "output/" {
-			continue
-		}
-		if validInputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "input/input")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			vali, oki := inputFiles[fileNumber]
-			valo, oko := outputFiles[fileNumber]
-			if oki && vali && oko && valo {
-				currentTestcase++
-				finalTestcases[fileNumber] = currentTestcase
-			}
-		}
+	response := AddQuestionResponse{
+		Success: true,
+		Report:  report,
 	}
-
-	for _, item := range zipr.File {
-		if item.Name == "input/" || item.Name == "output/" {
-			continue
-		}
-		if validInputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "input/input")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if val, ok := finalTestcases[fileNumber]; ok {
-				targetFile, err := os.OpenFile(fmt.Sprintf("%sinput/input%d.txt", folderPath, val), os.O_RDWR|os.O_CREATE, 0666)
-				if err != nil {
-					validZip = false
-					break
-				}
-				srcZipFile, err := item.Open()
-				_, err = io.Copy(targetFile, srcZipFile)
-				if err != nil {
-					validZip = false
-					break
-				}
-				targetFile.Close()
-				srcZipFile.Close()
-			}
-		} else if validOutputFile.Match([]byte(item.Name)) {
-			testcaseFile := strings.TrimPrefix(item.Name, "output/output")
-			dotIndex := strings.Index(testcaseFile, ".")
-			if dotIndex != -1 {
-				testcaseFile = testcaseFile[0:dotIndex]
-			}
-			if len(testcaseFile) <= 0 {
-				validZip = false
-				break
-			}
-			fileNumber, err := strconv.Atoi(testcaseFile)
-			if err != nil {
-				validZip = false
-				break
-			}
-			if val, ok := finalTestcases[fileNumber]; ok {
-				targetFile, err := os.OpenFile(fmt.Sprintf("%soutput/output%d.txt", folderPath, val), os.O_RDWR|os.O_CREATE, 0666)
-				if err != nil {
-					validZip = false
-					break
-				}
-				srcZipFile, err := item.Open()
-				_, err = io.Copy(targetFile, srcZipFile)
-				if err != nil {
-					validZip = false
-					break
-				}
-				targetFile.Close()
-				srcZipFile.Close()
-			}
-		} else {
-			validZip = false
-			break
-		}
+	if !dryRun {
+		response.ID = question.ID.Hex()
 	}
+	json.NewEncoder(w).Encode(response)
+}
 
-	if !validZip {
-		os.RemoveAll(folderPath)
-		api.Log.Info("Not a valid zip")
-		return
-	}
+func (api *API) editTestcasesHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, api.Limits.MaxArchiveSize+maxFormOverhead)
+
+	// Only validate the testcases, without storing anything
+	dryRun := isDryRun(r)
 
-	_, err = api.Db.Collection("questions").UpdateOne(r.Context(), bson.M{"_id": bson.M{"$eq": ID}}, bson.M{"$set": bson.M{"num_testcases": currentTestcase}})
+	// ID of the question whose testcases need to be edited
+	ID, err := parseID(r.FormValue("id"))
 	if err != nil {
-		os.RemoveAll(folderPath)
-		api.Log.Info(err.Error())
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	api.Log.Info(fmt.Sprintf("Extraction done for question %s...", ID.Hex()))
-}
-
-func (api *API) editQuestionHandler(w http.ResponseWriter, r *http.Request) {
-	// ID of the question whose testcases need to be edited
-	id := r.FormValue("id")
-	if len(id) <= 0 {
-		api.Log.Info("Missing id field")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
+	report, _, err := api.replaceQuestionTestcases(r, ID, dryRun)
+	if apiErr := toAPIError(err); err != nil && apiErr.Report != nil {
+		api.log(r.Context()).Info(err.Error())
+		writeJSON(w, http.StatusBadRequest, EditTestcasesResponse{
 			Success: false,
+			Error:   apiErr.Message,
+			Report:  apiErr.Report,
 		})
 		return
 	}
-	ID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	// Time limit for the question in seconds
-	timeStr := r.FormValue("time")
-	if len(timeStr) <= 0 {
-		api.Log.Info("Time field missing")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	if !dryRun {
+		api.rejudgeIfAsked(r, ID)
 	}
-	time, err := strconv.Atoi(timeStr)
+
+	json.NewEncoder(w).Encode(EditTestcasesResponse{
+		Success: true,
+		Report:  report,
+	})
+}
+
+func (api *API) editQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	// ID of the question to edit
+	ID, err := parseID(r.FormValue("id"))
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	// Name for question
+	// Both the name and the time limit in seconds are required
 	name := r.FormValue("name")
-	if len(name) <= 0 {
-		api.Log.Info("Name field missing")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
+	timeStr := r.FormValue("time")
+	timeLimit, timeErr := strconv.Atoi(timeStr)
+	errs := validation.Errors{
+		"name": validation.Validate(name, validation.Required),
+		"time": validation.Validate(timeStr, validation.Required),
 	}
-
-	updateResult, err := api.Db.Collection("questions").UpdateOne(r.Context(), bson.M{"_id": bson.M{"$eq": ID}}, bson.M{"$set": bson.M{"time": time, "name": name}})
-	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+	if len(timeStr) > 0 && timeErr != nil {
+		errs["time"] = errors.New("must be an integer")
+	}
+	if err = errs.Filter(); err != nil {
+		api.writeV1Error(w, r, err)
 		return
 	}
-	if updateResult.MatchedCount <= 0 {
-		api.Log.Info("No such question with this ID")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+
+	if _, err = api.updateQuestion(r.Context(), ID, QuestionPatch{Name: &name, Time: &timeLimit}); err != nil {
+		api.writeV1Error(w, r, err)
 		return
 	}
 
@@ -725,41 +333,15 @@ func (api *API) editQuestionHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) deleteQuestionHandler(w http.ResponseWriter, r *http.Request) {
-	// ID of the question whose testcases need to be edited
-	id := r.FormValue("id")
-	if len(id) <= 0 {
-		api.Log.Info("Missing id field")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
-	ID, err := primitive.ObjectIDFromHex(id)
+	// ID of the question to delete
+	ID, err := parseID(r.FormValue("id"))
 	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+		api.writeV1Error(w, r, err)
 		return
 	}
 
-	deleteResult, err := api.Db.Collection("questions").DeleteOne(r.Context(), bson.M{"_id": bson.M{"$eq": ID}})
-	if err != nil {
-		api.Log.Info(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
-		return
-	}
-	if deleteResult.DeletedCount <= 0 {
-		api.Log.Info("No such question with this ID")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TemplateResponse{
-			Success: false,
-		})
+	if err = api.Questions.SoftDelete(r.Context(), ID); err != nil {
+		api.writeV1Error(w, r, err)
 		return
 	}
 
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testcaseNumbers : Numbers of the testcases in display order
+func testcaseNumbers(testcases []Testcase) []int {
+	numbers := make([]int, len(testcases))
+	for i, testcase := range testcases {
+		numbers[i] = testcase.Number
+	}
+	return numbers
+}
+
+// expectTestcases : Fails the test unless the testcases carry the numbers
+// in display order, indexed from 1
+func expectTestcases(t *testing.T, testcases []Testcase, numbers ...int) {
+	t.Helper()
+	if len(testcases) != len(numbers) {
+		t.Fatalf("testcases numbered %v, expected %v", testcaseNumbers(testcases), numbers)
+	}
+	for i, testcase := range testcases {
+		if testcase.Index != i+1 || testcase.Number != numbers[i] {
+			t.Fatalf("testcases numbered %v, expected %v", testcaseNumbers(testcases), numbers)
+		}
+	}
+}
+
+func TestLegacyQuestionRoutes(t *testing.T) {
+	s := newTestServer(t)
+	archive := map[string][]byte{"testcases": testcasesZip(t, "1", "2")}
+
+	var added AddQuestionResponse
+	expectJSON(t, s.form(http.MethodPost, "/addQuestion", map[string]string{"name": "Echo", "time": "2", "dryRun": "true"}, archive, false), http.StatusOK, &added)
+	if !added.Success || added.ID != "" || added.Report == nil || added.Report.Testcases != 2 {
+		t.Fatalf("dry run answered %+v", added)
+	}
+	expectJSON(t, s.form(http.MethodPost, "/addQuestion", map[string]string{"name": "Echo"},
+		map[string][]byte{"testcases": zipOf(t, map[string]string{"notes.md": "nothing"})}, false), http.StatusBadRequest, &added)
+	if added.Success || added.Report == nil || added.Report.Valid {
+		t.Fatalf("invalid archive answered %+v", added)
+	}
+	expectV1(t, s.form(http.MethodPost, "/addQuestion", map[string]string{"name": "Echo", "time": "soon"}, archive, false), http.StatusBadRequest, false, nil)
+
+	expectV1(t, s.form(http.MethodPost, "/addQuestion", map[string]string{"name": "Echo", "time": "2"}, archive, false), http.StatusOK, true, &added)
+	ID, err := parseID(added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	question, err := s.Questions.Get(context.Background(), ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if question.Name != "Echo" || question.Time != 2 || question.NumTestcases != 2 {
+		t.Fatalf("stored %+v", question)
+	}
+
+	expectV1(t, s.form(http.MethodPost, "/editQuestion", map[string]string{"id": added.ID, "name": "Echo back", "time": "3"}, nil, false), http.StatusOK, true, nil)
+	expectV1(t, s.form(http.MethodPost, "/editQuestion", map[string]string{"id": added.ID, "name": "Echo back"}, nil, false), http.StatusBadRequest, false, nil)
+	var refused TemplateResponse
+	expectV1(t, s.form(http.MethodPost, "/editQuestion", map[string]string{"id": added.ID, "name": "Echo back", "time": "soon"}, nil, false), http.StatusBadRequest, false, &refused)
+	if refused.Error != "time: must be an integer." {
+		t.Fatalf("non-numeric time refused with %q", refused.Error)
+	}
+	expectV1(t, s.form(http.MethodPost, "/editQuestion", map[string]string{"id": primitive.NewObjectID().Hex(), "name": "Echo", "time": "1"}, nil, false), http.StatusBadRequest, false, nil)
+	if question, _ = s.Questions.Get(context.Background(), ID); question.Name != "Echo back" || question.Time != 3 {
+		t.Fatalf("edited to %+v", question)
+	}
+
+	// Testcases are only changed by admins
+	for target, files := range map[string]map[string][]byte{
+		"/editTestcases":    {"testcases": testcasesZip(t, "a")},
+		"/addTestcase":      {"input": []byte("d"), "output": []byte("d")},
+		"/replaceTestcase":  {"input": []byte("z")},
+		"/reorderTestcases": nil,
+		"/deleteTestcase":   nil,
+	} {
+		expectV1(t, s.form(http.MethodPost, target, map[string]string{"id": added.ID, "index": "1", "order": "2,1"}, files, false), http.StatusUnauthorized, false, nil)
+	}
+	if question, _ = s.Questions.Get(context.Background(), ID); question.NumTestcases != 2 {
+		t.Fatalf("changed by anyone to %+v", question)
+	}
+
+	var edited EditTestcasesResponse
+	expectJSON(t, s.form(http.MethodPost, "/editTestcases", map[string]string{"id": added.ID},
+		map[string][]byte{"testcases": testcasesZip(t, "a", "b", "c")}, true), http.StatusOK, &edited)
+	if !edited.Success || edited.Report.Testcases != 3 {
+		t.Fatalf("testcases edited with %+v", edited)
+	}
+
+	var testcases TestcasesResponse
+	expectV1(t, s.form(http.MethodPost, "/addTestcase", map[string]string{"id": added.ID, "name": "Last"},
+		map[string][]byte{"input": []byte("d"), "output": []byte("d")}, true), http.StatusOK, true, &testcases)
+	expectTestcases(t, testcases.Testcases, 1, 2, 3, 4)
+	expectV1(t, s.form(http.MethodPost, "/addTestcase", map[string]string{"id": added.ID},
+		map[string][]byte{"input": []byte("e")}, true), http.StatusBadRequest, false, nil)
+
+	expectV1(t, s.form(http.MethodPost, "/replaceTestcase", map[string]string{"id": added.ID, "index": "1"},
+		map[string][]byte{"input": []byte("z")}, true), http.StatusOK, true, nil)
+	download := "/downloadTestcase?id=" + added.ID + "&index=1&file=input"
+	expectStatus(t, s.get(download, false), http.StatusUnauthorized)
+	if rec := s.get(download, true); rec.Code != http.StatusOK || rec.Body.String() != "z" {
+		t.Fatalf("downloaded %d %q", rec.Code, rec.Body.String())
+	}
+	expectV1(t, s.get("/downloadTestcase?id="+added.ID+"&index=9&file=input", true), http.StatusBadRequest, false, nil)
+
+	expectV1(t, s.form(http.MethodPost, "/reorderTestcases", map[string]string{"id": added.ID, "order": "4,1,2,3"}, nil, true), http.StatusOK, true, &testcases)
+	expectTestcases(t, testcases.Testcases, 4, 1, 2, 3)
+	expectV1(t, s.form(http.MethodPost, "/reorderTestcases", map[string]string{"id": added.ID, "order": "1,x"}, nil, true), http.StatusBadRequest, false, nil)
+
+	expectV1(t, s.form(http.MethodPost, "/deleteTestcase", map[string]string{"id": added.ID, "index": "1"}, nil, true), http.StatusOK, true, &testcases)
+	expectTestcases(t, testcases.Testcases, 1, 2, 3)
+
+	export := "/exportQuestion?id=" + added.ID
+	expectStatus(t, s.get(export, false), http.StatusUnauthorized)
+	rec := s.get(export, true)
+	expectStatus(t, rec, http.StatusOK)
+	files := zipFiles(t, rec.Body.Bytes())
+	if files["input/input1.txt"] != "z" || files["output/output3.txt"] != "c" || files[manifestFile] == "" {
+		t.Fatalf("exported %v", files)
+	}
+
+	expectV1(t, s.form(http.MethodPost, "/deleteQuestion", map[string]string{"id": added.ID}, nil, false), http.StatusUnauthorized, false, nil)
+	expectV1(t, s.form(http.MethodPost, "/deleteQuestion", map[string]string{"id": added.ID}, nil, true), http.StatusOK, true, nil)
+	expectV1(t, s.form(http.MethodPost, "/deleteQuestion", map[string]string{"id": added.ID}, nil, true), http.StatusBadRequest, false, nil)
+	expectError(t, s.get("/v2/questions/"+added.ID, true), http.StatusNotFound, CodeNotFound)
+}
+
+func TestQuestionRoutes(t *testing.T) {
+	s := newTestServer(t)
+	archive := map[string][]byte{"testcases": testcasesZip(t, "1", "2")}
+
+	var created QuestionCreatedResponse
+	expectJSON(t, s.form(http.MethodPost, "/v2/questions?dryRun=true", map[string]string{"name": "Echo"}, archive, false), http.StatusOK, &created)
+	if created.Question != nil || created.Report == nil || !created.Report.Valid {
+		t.Fatalf("dry run answered %+v", created)
+	}
+	expectError(t, s.form(http.MethodPost, "/v2/questions", map[string]string{"name": "Echo"},
+		map[string][]byte{"testcases": zipOf(t, map[string]string{"notes.md": "nothing"})}, false), http.StatusBadRequest, CodeInvalidRequest)
+	expectError(t, s.form(http.MethodPost, "/v2/questions", map[string]string{"time": "0"}, archive, false),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+	expectError(t, s.form(http.MethodPost, "/v2/questions", map[string]string{"name": "Echo"}, nil, false), http.StatusBadRequest, CodeInvalidRequest)
+
+	rec := s.form(http.MethodPost, "/v2/questions", map[string]string{"name": "Echo"}, archive, false)
+	expectJSON(t, rec, http.StatusCreated, &created)
+	question := *created.Question
+	path := "/v2/questions/" + question.ID.Hex()
+	if location := rec.Header().Get("Location"); location != path {
+		t.Fatalf("created at %q, expected %q", location, path)
+	}
+	if question.Time != s.Config.Limits.TimeLimit {
+		t.Fatalf("time limit %d, expected the default %d", question.Time, s.Config.Limits.TimeLimit)
+	}
+
+	var list QuestionsResponse
+	expectJSON(t, s.get("/v2/questions", false), http.StatusOK, &list)
+	if len(list.Questions) != 1 || list.Questions[0].ID != question.ID {
+		t.Fatalf("listed %v", list.Questions)
+	}
+	expectJSON(t, s.get(path, false), http.StatusOK, &question)
+	expectTestcases(t, question.Testcases, 1, 2)
+	expectError(t, s.get("/v2/questions/nope", false), http.StatusNotFound, CodeNotFound)
+
+	name := "Echo back"
+	expectError(t, s.json(http.MethodPatch, path, QuestionPatch{Name: &name}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.json(http.MethodPatch, path, QuestionPatch{Name: &name}, true), http.StatusOK, &question)
+	if question.Name != name {
+		t.Fatalf("renamed to %q", question.Name)
+	}
+	zero := 0
+	expectError(t, s.json(http.MethodPatch, path, QuestionPatch{Time: &zero}, true), http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	// Archived questions are left to admins
+	archived := true
+	expectError(t, s.json(http.MethodPatch, path, QuestionPatch{Archived: &archived}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectStatus(t, s.json(http.MethodPatch, path, QuestionPatch{Archived: &archived}, true), http.StatusOK)
+	expectError(t, s.get(path, false), http.StatusNotFound, CodeNotFound)
+	expectStatus(t, s.get(path, true), http.StatusOK)
+	expectJSON(t, s.get("/v2/questions", false), http.StatusOK, &list)
+	if len(list.Questions) != 0 {
+		t.Fatalf("contestants see %v", list.Questions)
+	}
+
+	expectError(t, s.get(path+"/export", false), http.StatusUnauthorized, CodeUnauthorized)
+	rec = s.get(path+"/export", true)
+	expectStatus(t, rec, http.StatusOK)
+	if files := zipFiles(t, rec.Body.Bytes()); files["input/input2.txt"] != "2" || files["output/output1.txt"] != "1" {
+		t.Fatalf("exported %v", files)
+	}
+
+	// Soft deletes go to the trash, from which questions are restored
+	expectError(t, s.json(http.MethodDelete, path, nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectStatus(t, s.json(http.MethodDelete, path, nil, true), http.StatusNoContent)
+	expectError(t, s.get(path, true), http.StatusNotFound, CodeNotFound)
+	expectError(t, s.get("/v2/questions?deleted=true", false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.get("/v2/questions?deleted=true", true), http.StatusOK, &list)
+	if len(list.Questions) != 1 {
+		t.Fatalf("trash holds %v", list.Questions)
+	}
+	expectError(t, s.json(http.MethodPost, path+"/restore", nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.json(http.MethodPost, path+"/restore", nil, true), http.StatusOK, &question)
+	expectTestcases(t, question.Testcases, 1, 2)
+	expectError(t, s.json(http.MethodPost, path+"/restore", nil, true), http.StatusConflict, CodeConflict)
+
+	// Purges are refused while a submission answers the question, and
+	// leave a tombstone for the storage purge
+	submission := Submission{ID: primitive.NewObjectID(), LanguageID: primitive.NewObjectID(), QuestionID: question.ID, Verdict: VerdictAccepted}
+	if err := s.submissions().Add(submission); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, s.json(http.MethodDelete, path+"?hard=true", nil, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodDelete, path+"?hard=true", nil, true), http.StatusConflict, CodeConflict)
+	if err := s.Submissions.Delete(context.Background(), submission.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.json(http.MethodDelete, path+"?hard=true", nil, true), http.StatusNoContent)
+	tombstones, err := s.Questions.Tombstones(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tombstones) != 1 || tombstones[0].ID != question.ID {
+		t.Fatalf("tombstones %v", tombstones)
+	}
+}
+
+func TestQuestionLanguageRoutes(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	ruby := s.language("Ruby")
+	question := s.question("Echo", "1")
+	path := "/v2/questions/" + question.ID.Hex() + "/languages"
+
+	var accepted QuestionLanguagesResponse
+	expectJSON(t, s.get(path, false), http.StatusOK, &accepted)
+	if len(accepted.Languages) != 2 {
+		t.Fatalf("accepts %v", accepted.Languages)
+	}
+
+	languages := []primitive.ObjectID{python.ID}
+	overrides := []TimeOverride{{LanguageID: python.ID, Time: 5}}
+	expectStatus(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Languages: &languages, TimeOverrides: &overrides}, true), http.StatusOK)
+	expectJSON(t, s.get(path, false), http.StatusOK, &accepted)
+	if len(accepted.Languages) != 1 || accepted.Languages[0].ID != python.ID || accepted.Languages[0].Time != 5 || !accepted.Languages[0].Override {
+		t.Fatalf("accepts %+v", accepted.Languages)
+	}
+
+	var single QuestionLanguage
+	expectJSON(t, s.get(path+"/"+python.ID.Hex(), false), http.StatusOK, &single)
+	if single.Time != 5 {
+		t.Fatalf("time limit %d, expected the override", single.Time)
+	}
+	expectError(t, s.get(path+"/"+ruby.ID.Hex(), false), http.StatusBadRequest, CodeInvalidRequest)
+	expectError(t, s.get(path+"/"+primitive.NewObjectID().Hex(), false), http.StatusNotFound, CodeNotFound)
+
+	unknown := []primitive.ObjectID{primitive.NewObjectID()}
+	expectError(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Languages: &unknown}, true),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+}
+
+func TestTestcaseRoutes(t *testing.T) {
+	s := newTestServer(t)
+	question := s.question("Echo", "1", "2")
+	path := "/v2/questions/" + question.ID.Hex() + "/testcases"
+
+	// Testcases are only changed by admins
+	expectError(t, s.form(http.MethodPut, path, nil, map[string][]byte{"testcases": testcasesZip(t, "a")}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.form(http.MethodPost, path, nil, map[string][]byte{"input": []byte("d"), "output": []byte("d")}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.form(http.MethodPatch, path+"/1", nil, map[string][]byte{"output": []byte("x")}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodPut, path+"/order", ReorderRequest{Order: []int{2, 1}}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodDelete, path+"/1", nil, false), http.StatusUnauthorized, CodeUnauthorized)
+
+	var list TestcaseListResponse
+	expectJSON(t, s.form(http.MethodPut, path+"?dryRun=true", nil, map[string][]byte{"testcases": testcasesZip(t, "a", "b", "c")}, true), http.StatusOK, &list)
+	if list.Report == nil || list.Report.Testcases != 3 || list.Testcases != nil {
+		t.Fatalf("dry run answered %+v", list)
+	}
+	expectJSON(t, s.form(http.MethodPut, path, nil, map[string][]byte{"testcases": testcasesZip(t, "a", "b", "c")}, true), http.StatusOK, &list)
+	expectTestcases(t, list.Testcases, 1, 2, 3)
+	expectError(t, s.form(http.MethodPut, "/v2/questions/"+primitive.NewObjectID().Hex()+"/testcases", nil,
+		map[string][]byte{"testcases": testcasesZip(t, "a")}, true), http.StatusNotFound, CodeNotFound)
+
+	expectJSON(t, s.form(http.MethodPost, path, map[string]string{"name": "Big"}, map[string][]byte{"input": []byte("d"), "output": []byte("d")}, true), http.StatusCreated, &list)
+	expectTestcases(t, list.Testcases, 1, 2, 3, 4)
+	if list.Testcases[3].Name != "Big" {
+		t.Fatalf("added %+v", list.Testcases[3])
+	}
+	expectError(t, s.form(http.MethodPost, path, nil, map[string][]byte{"output": []byte("e")}, true), http.StatusBadRequest, CodeInvalidRequest)
+
+	expectJSON(t, s.form(http.MethodPatch, path+"/2", map[string]string{"description": "Second"}, map[string][]byte{"output": []byte("x")}, true), http.StatusOK, &list)
+	if list.Testcases[1].Description != "Second" {
+		t.Fatalf("replaced %+v", list.Testcases[1])
+	}
+	expectError(t, s.form(http.MethodPatch, path+"/9", nil, map[string][]byte{"input": []byte("x")}, true), http.StatusNotFound, CodeNotFound)
+
+	expectError(t, s.get(path+"/2/output", false), http.StatusUnauthorized, CodeUnauthorized)
+	if rec := s.get(path+"/2/output", true); rec.Code != http.StatusOK || rec.Body.String() != "x" {
+		t.Fatalf("downloaded %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := s.get(path+"/2/input", true); rec.Code != http.StatusOK || rec.Body.String() != "b" {
+		t.Fatalf("downloaded %d %q", rec.Code, rec.Body.String())
+	}
+	expectError(t, s.get(path+"/9/input", true), http.StatusNotFound, CodeNotFound)
+
+	expectJSON(t, s.json(http.MethodPut, path+"/order", ReorderRequest{Order: []int{4, 3, 2, 1}}, true), http.StatusOK, &list)
+	expectTestcases(t, list.Testcases, 4, 3, 2, 1)
+	expectError(t, s.json(http.MethodPut, path+"/order", ReorderRequest{Order: []int{1, 1, 2, 3}}, true), http.StatusBadRequest, CodeInvalidRequest)
+
+	expectJSON(t, s.json(http.MethodDelete, path+"/1", nil, true), http.StatusOK, &list)
+	expectTestcases(t, list.Testcases, 3, 2, 1)
+	expectJSON(t, s.json(http.MethodDelete, path+"/1", nil, true), http.StatusOK, &list)
+	expectJSON(t, s.json(http.MethodDelete, path+"/1", nil, true), http.StatusOK, &list)
+	expectTestcases(t, list.Testcases, 1)
+	expectError(t, s.json(http.MethodDelete, path+"/1", nil, true), http.StatusConflict, CodeConflict)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"judge-two/internal/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Priorities of judge jobs, workers take the highest one first and the
+// oldest job among equal priorities
+const (
+	PrioritySubmission = 10
+	PriorityRejudge    = 1
+)
+
+// Errors returned by the judge queue
+var (
+	ErrShuttingDown = errors.New("the judge is shutting down, try again shortly")
+	errQueueEmpty   = errors.New("no job the worker can run is waiting")
+)
+
+// enqueueSubmissions : Puts the submissions in the judge queue
+func (api *API) enqueueSubmissions(ctx context.Context, submissions []Submission, priority int, rejudgeID *primitive.ObjectID) error {
+	if api.shuttingDown() {
+		return ErrShuttingDown
+	}
+	if len(submissions) <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	jobs := make([]JudgeJob, len(submissions))
+	for i, submission := range submissions {
+		jobs[i] = JudgeJob{
+			ID:           primitive.NewObjectID(),
+			SubmissionID: submission.ID,
+			LanguageID:   submission.LanguageID,
+			Priority:     priority,
+			EnqueuedAt:   now,
+			RejudgeID:    rejudgeID,
+			Trace:        tracing.Inject(ctx),
+		}
+	}
+	return api.Jobs.Enqueue(ctx, jobs)
+}
+
+// migrateQueue : Fills in the language of jobs queued before workers were
+// routed by language, which no worker would take otherwise
+func (api *API) migrateQueue(ctx context.Context) error {
+	cursor, err := api.Db.Collection("judge_queue").Find(ctx, bson.M{"lang_id": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	var jobs []JudgeJob
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		var submission Submission
+		err = api.Db.Collection("submissions").FindOne(ctx, bson.M{"_id": bson.M{"$eq": job.SubmissionID}}).Decode(&submission)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Nothing left to judge
+			_, err = api.Db.Collection("judge_queue").DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": job.ID}})
+		} else if err == nil {
+			_, err = api.Db.Collection("judge_queue").UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": job.ID}}, bson.M{"$set": bson.M{"lang_id": submission.LanguageID}})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
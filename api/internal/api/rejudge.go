@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"judge-two/internal/tracing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// rejudgeRetry : Time between two attempts at finishing pending rejudges
+const rejudgeRetry = time.Minute
+
+// Errors returned by rejudges
+var (
+	ErrEmptyRejudgeFilter = errors.New("at least one of submission_id, question_id, verdict or since is required")
+	ErrNoSuchRejudge      = errors.New("no such rejudge with this ID")
+)
+
+// RejudgeRequest : Filter picking the submissions to rejudge
+type RejudgeRequest struct {
+	SubmissionID string     `bson:"submission_id,omitempty" json:"submission_id,omitempty"`
+	QuestionID   string     `bson:"question_id,omitempty" json:"question_id,omitempty"`
+	Verdict      string     `bson:"verdict,omitempty" json:"verdict,omitempty"`
+	Since        *time.Time `bson:"since,omitempty" json:"since,omitempty"`
+}
+
+func (r RejudgeRequest) validate() error {
+	if r.SubmissionID == "" && r.QuestionID == "" && r.Verdict == "" && r.Since == nil {
+		return ErrEmptyRejudgeFilter
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Verdict, validation.In(VerdictAccepted, VerdictWrongAnswer, VerdictTimeLimit, VerdictRuntimeError, VerdictCompileError, VerdictOutputLimit)),
+	)
+}
+
+// filter : Submissions the rejudge applies to. Submissions still waiting
+// for a verdict are judged anyway, so they are left out.
+func (r RejudgeRequest) filter() (SubmissionFilter, error) {
+	filter := SubmissionFilter{Verdict: r.Verdict, NotVerdict: VerdictQueued}
+	if r.SubmissionID != "" {
+		ID, err := parseID(r.SubmissionID)
+		if err != nil {
+			return filter, validation.Errors{"submission_id": err}
+		}
+		filter.IDs = []primitive.ObjectID{ID}
+	}
+	if r.QuestionID != "" {
+		ID, err := parseID(r.QuestionID)
+		if err != nil {
+			return filter, validation.Errors{"question_id": err}
+		}
+		filter.QuestionID = ID
+	}
+	if r.SubmissionID == "" {
+		filter.Since = r.Since
+	}
+	return filter, nil
+}
+
+// RejudgeResponse : Submissions sent back to the judge
+type RejudgeResponse struct {
+	Success     bool   `json:"success"`
+	ID          string `json:"id"`
+	Submissions int    `json:"submissions"`
+}
+
+// VerdictChange : Submission whose verdict differs after a rejudge
+type VerdictChange struct {
+	SubmissionID string `json:"submission_id"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+}
+
+// RejudgeStatusResponse : Progress of a rejudge and the verdicts it changed
+type RejudgeStatusResponse struct {
+	Success   bool            `json:"success,omitempty"`
+	Rejudge   Rejudge         `json:"rejudge"`
+	Pending   int             `json:"pending"`
+	Unchanged int             `json:"unchanged"`
+	Changed   []VerdictChange `json:"changed"`
+}
+
+// rejudge : Records the rejudge of every matching submission, then moves
+// their current verdict into their history and queues them again below
+// fresh submissions. A rejudge interrupted on the way stays pending and
+// is finished by resumeRejudges.
+func (api *API) rejudge(ctx context.Context, request RejudgeRequest) (Rejudge, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "rejudge")
+	defer span.End()
+
+	rejudge := Rejudge{
+		ID:          primitive.NewObjectID(),
+		Filter:      request,
+		Submissions: []primitive.ObjectID{},
+		CreatedAt:   time.Now(),
+	}
+	if api.shuttingDown() {
+		return rejudge, ErrShuttingDown
+	}
+
+	filter, err := request.filter()
+	if err != nil {
+		return rejudge, err
+	}
+	submissions, err := api.Submissions.Find(ctx, filter)
+	if err != nil {
+		return rejudge, err
+	}
+	for _, submission := range submissions {
+		rejudge.Submissions = append(rejudge.Submissions, submission.ID)
+	}
+
+	if err = api.Rejudges.Insert(ctx, rejudge); err != nil {
+		return rejudge, err
+	}
+	if err = api.queueRejudge(ctx, &rejudge); err != nil {
+		return rejudge, err
+	}
+
+	api.log(ctx).Info(fmt.Sprintf("Rejudging %d submissions in %s", len(rejudge.Submissions), rejudge.ID.Hex()))
+	return rejudge, nil
+}
+
+// queueRejudge : Requeues the submissions of the rejudge and puts those
+// waiting without a job in the judge queue, then marks it queued. Every
+// step can run again, so a failed rejudge is finished by calling it again.
+func (api *API) queueRejudge(ctx context.Context, rejudge *Rejudge) error {
+	submissions, err := api.Submissions.Find(ctx, SubmissionFilter{IDs: rejudge.Submissions})
+	if err != nil {
+		return err
+	}
+	for _, submission := range submissions {
+		if submission.Verdict == VerdictQueued {
+			continue
+		}
+		judgement := Judgement{
+			Verdict:    submission.Verdict,
+			Testcases:  submission.Testcases,
+			Results:    submission.Results,
+			RejudgeID:  rejudge.ID,
+			ReplacedAt: time.Now(),
+		}
+		if err = api.Submissions.Requeue(ctx, submission.ID, judgement); err != nil {
+			return err
+		}
+	}
+
+	waiting, err := api.Submissions.Find(ctx, SubmissionFilter{IDs: rejudge.Submissions, Verdict: VerdictQueued})
+	if err != nil {
+		return err
+	}
+	queued, err := api.Jobs.Queued(ctx, rejudge.Submissions)
+	if err != nil {
+		return err
+	}
+	missing := waiting[:0]
+	for _, submission := range waiting {
+		if !queued[submission.ID] {
+			missing = append(missing, submission)
+		}
+	}
+	if err = api.enqueueSubmissions(ctx, missing, PriorityRejudge, &rejudge.ID); err != nil {
+		return err
+	}
+
+	rejudge.Queued = true
+	return api.Rejudges.SetQueued(ctx, rejudge.ID)
+}
+
+// resumeRejudges : Finishes the rejudges left pending by a failure or a
+// shutdown midway
+func (api *API) resumeRejudges(ctx context.Context) error {
+	rejudges, err := api.Rejudges.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range rejudges {
+		if err = api.queueRejudge(ctx, &rejudges[i]); err != nil {
+			return err
+		}
+		api.Log.Info("Resumed rejudge", zap.String("rejudge", rejudges[i].ID.Hex()), zap.Int("submissions", len(rejudges[i].Submissions)))
+	}
+	return nil
+}
+
+// runRejudges : Resumes pending rejudges every rejudgeRetry until ctx ends
+func (api *API) runRejudges(ctx context.Context) {
+	ticker := time.NewTicker(rejudgeRetry)
+	defer ticker.Stop()
+	for {
+		if err := api.resumeRejudges(ctx); err != nil && ctx.Err() == nil && !errors.Is(err, ErrShuttingDown) {
+			api.Log.Error("Resuming rejudges failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rejudgeIfAsked : Rejudges the question when the "rejudge" form field of
+// a testcase edit is set, failures only get logged as the edit is done.
+// Rejudges are left to admins as on /rejudge, whatever route the edit
+// came through.
+func (api *API) rejudgeIfAsked(r *http.Request, ID primitive.ObjectID) {
+	if r.FormValue("rejudge") != "true" {
+		return
+	}
+	if !api.isAdmin(r) {
+		api.log(r.Context()).Warn("Rejudge of question " + ID.Hex() + " asked without the admin token, ignored")
+		return
+	}
+	if _, err := api.rejudge(r.Context(), RejudgeRequest{QuestionID: ID.Hex()}); err != nil {
+		api.log(r.Context()).Error(err.Error())
+	}
+}
+
+// rejudgeStatus : Progress of the rejudge with the given ID
+func (api *API) rejudgeStatus(ctx context.Context, ID primitive.ObjectID) (RejudgeStatusResponse, error) {
+	response := RejudgeStatusResponse{
+		Changed: []VerdictChange{},
+	}
+
+	var err error
+	if response.Rejudge, err = api.Rejudges.Get(ctx, ID); err != nil {
+		return response, err
+	}
+	rejudge := response.Rejudge
+
+	submissions, err := api.Submissions.Find(ctx, SubmissionFilter{IDs: rejudge.Submissions})
+	if err != nil {
+		return response, err
+	}
+
+	for _, submission := range submissions {
+		// The history entry left by this rejudge holds the old verdict,
+		// a later rejudge of the same submission may have come since
+		var before *Judgement
+		for i := range submission.History {
+			if submission.History[i].RejudgeID == rejudge.ID {
+				before = &submission.History[i]
+			}
+		}
+		after := submission.Verdict
+		for _, judgement := range submission.History {
+			if before != nil && judgement.ReplacedAt.After(before.ReplacedAt) {
+				after = judgement.Verdict
+				break
+			}
+		}
+
+		switch {
+		case before == nil:
+			continue
+		case after == VerdictQueued:
+			response.Pending++
+		case after == before.Verdict:
+			response.Unchanged++
+		default:
+			response.Changed = append(response.Changed, VerdictChange{
+				SubmissionID: submission.ID.Hex(),
+				Before:       before.Verdict,
+				After:        after,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+func (api *API) rejudgeHandler(w http.ResponseWriter, r *http.Request) {
+	var reqBody RejudgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		api.writeV1Error(w, r, badRequest(err))
+		return
+	}
+	if err := reqBody.validate(); err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	rejudge, err := api.rejudge(r.Context(), reqBody)
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(RejudgeResponse{
+		Success:     true,
+		ID:          rejudge.ID.Hex(),
+		Submissions: len(rejudge.Submissions),
+	})
+}
+
+func (api *API) rejudgeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := parseID(r.FormValue("id"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	response, err := api.rejudgeStatus(r.Context(), ID)
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+	response.Success = true
+
+	json.NewEncoder(w).Encode(response)
+}
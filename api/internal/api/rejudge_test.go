@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// judged : Submission with a verdict, stored as a worker left it
+func (s *testServer) judged(question Question, language Language, verdict string) Submission {
+	submission := Submission{
+		ID:         primitive.NewObjectID(),
+		QuestionID: question.ID,
+		LanguageID: language.ID,
+		Verdict:    verdict,
+		Testcases:  map[int]string{1: verdict},
+		Source:     "print(input())",
+	}
+	if err := s.submissions().Add(submission); err != nil {
+		s.t.Fatal(err)
+	}
+	return submission
+}
+
+func TestRejudgeRoutes(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1")
+	accepted := s.judged(question, python, VerdictAccepted)
+	wrong := s.judged(question, python, VerdictWrongAnswer)
+	s.judged(s.question("Other", "1"), python, VerdictAccepted)
+
+	expectStatus(t, s.json(http.MethodPost, "/rejudge", RejudgeRequest{QuestionID: question.ID.Hex()}, false), http.StatusUnauthorized)
+	expectV1(t, s.json(http.MethodPost, "/rejudge", RejudgeRequest{}, true), http.StatusBadRequest, false, nil)
+	var created RejudgeResponse
+	expectV1(t, s.json(http.MethodPost, "/rejudge", RejudgeRequest{QuestionID: question.ID.Hex()}, true), http.StatusOK, true, &created)
+	if created.Submissions != 2 {
+		t.Fatalf("rejudging %d submissions, expected 2", created.Submissions)
+	}
+	queued, err := s.Jobs.Queued(context.Background(), []primitive.ObjectID{accepted.ID, wrong.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("queued %v", queued)
+	}
+
+	var status RejudgeStatusResponse
+	expectStatus(t, s.get("/rejudgeStatus?id="+created.ID, false), http.StatusUnauthorized)
+	expectJSON(t, s.get("/rejudgeStatus?id="+created.ID, true), http.StatusOK, &status)
+	if !status.Success || status.Pending != 2 || !status.Rejudge.Queued {
+		t.Fatalf("status %+v", status)
+	}
+	expectV1(t, s.get("/rejudgeStatus?id="+primitive.NewObjectID().Hex(), true), http.StatusBadRequest, false, nil)
+
+	// Workers come back with one verdict changed and one the same
+	for _, submission := range []Submission{accepted, wrong} {
+		submission.Verdict = VerdictWrongAnswer
+		claimID := primitive.NewObjectID()
+		if err = s.Submissions.Claim(context.Background(), submission.ID, claimID); err != nil {
+			t.Fatal(err)
+		}
+		if err = s.Submissions.SetVerdict(context.Background(), submission, claimID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := "/v2/rejudges/" + created.ID
+	expectError(t, s.get(path, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectJSON(t, s.get(path, true), http.StatusOK, &status)
+	if status.Pending != 0 || status.Unchanged != 1 || len(status.Changed) != 1 {
+		t.Fatalf("status %+v", status)
+	}
+	if change := status.Changed[0]; change.SubmissionID != accepted.ID.Hex() || change.Before != VerdictAccepted || change.After != VerdictWrongAnswer {
+		t.Fatalf("changed %+v", change)
+	}
+	expectError(t, s.get("/v2/rejudges/"+primitive.NewObjectID().Hex(), true), http.StatusNotFound, CodeNotFound)
+
+	// Rejudges by verdict only take the submissions still holding it
+	expectError(t, s.json(http.MethodPost, "/v2/rejudges", RejudgeRequest{Verdict: VerdictAccepted}, false), http.StatusUnauthorized, CodeUnauthorized)
+	expectError(t, s.json(http.MethodPost, "/v2/rejudges", RejudgeRequest{}, true), http.StatusBadRequest, CodeInvalidRequest)
+	expectError(t, s.json(http.MethodPost, "/v2/rejudges", RejudgeRequest{Verdict: "XX"}, true), http.StatusUnprocessableEntity, CodeValidationFailed)
+	rec := s.json(http.MethodPost, "/v2/rejudges", RejudgeRequest{Verdict: VerdictAccepted}, true)
+	var again RejudgeCreatedResponse
+	expectJSON(t, rec, http.StatusAccepted, &again)
+	if again.Submissions != 1 || rec.Header().Get("Location") != "/v2/rejudges/"+again.ID {
+		t.Fatalf("rejudge %+v at %q", again, rec.Header().Get("Location"))
+	}
+}
+
+func TestRejudgeResumes(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1")
+	submission := s.judged(question, python, VerdictAccepted)
+
+	// A rejudge recorded before a failure is finished by the retry
+	rejudge := Rejudge{ID: primitive.NewObjectID(), Submissions: []primitive.ObjectID{submission.ID}}
+	if err := s.Rejudges.Insert(context.Background(), rejudge); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.resumeRejudges(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := s.Rejudges.Pending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("still pending %v", pending)
+	}
+	stored, err := s.Submissions.Get(context.Background(), submission.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Verdict != VerdictQueued || len(stored.History) != 1 {
+		t.Fatalf("requeued as %+v", stored)
+	}
+	job, err := s.Jobs.Claim(context.Background(), "worker", []primitive.ObjectID{python.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Jobs.Claim(context.Background(), "worker", []primitive.ObjectID{python.ID}); err != errQueueEmpty {
+		t.Fatalf("queued twice, second claim gave %v", err)
+	}
+	if job.RejudgeID == nil || *job.RejudgeID != rejudge.ID || job.Priority != PriorityRejudge {
+		t.Fatalf("claimed %+v", job)
+	}
+}
+
+func TestRejudgeIfAskedNeedsAdmin(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1")
+	submission := s.judged(question, python, VerdictAccepted)
+
+	anonymous := httptest.NewRequest(http.MethodPost, "/?rejudge=true", nil)
+	s.rejudgeIfAsked(anonymous, question.ID)
+	if stored, err := s.Submissions.Get(context.Background(), submission.ID); err != nil || stored.Verdict != VerdictAccepted {
+		t.Fatalf("rejudged without the admin token: %+v, %v", stored, err)
+	}
+
+	admin := httptest.NewRequest(http.MethodPost, "/?rejudge=true", nil)
+	admin.Header.Set("Authorization", "Bearer "+testToken)
+	s.rejudgeIfAsked(admin, question.ID)
+	queued, err := s.Jobs.Queued(context.Background(), []primitive.ObjectID{submission.ID})
+	if err != nil || !queued[submission.ID] {
+		t.Fatalf("not rejudged for an admin: %v, %v", queued, err)
+	}
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LanguageRepo : Where languages are kept. Soft deleted languages are only
+// seen by List with visibleInTrash, Restore and Delete.
+type LanguageRepo interface {
+	// List : Languages of the given visibility, by name
+	List(ctx context.Context, v visibility) ([]Language, error)
+	// Get : Language with the given ID, ErrNoSuchLanguage when missing
+	Get(ctx context.Context, ID primitive.ObjectID) (Language, error)
+	// GetIncludingDeleted : Language with the given ID even when soft
+	// deleted, which submissions queued before the delete are judged in
+	GetIncludingDeleted(ctx context.Context, ID primitive.ObjectID) (Language, error)
+	// Find : Those of the given languages that exist
+	Find(ctx context.Context, IDs []primitive.ObjectID) ([]Language, error)
+	Insert(ctx context.Context, language Language) error
+	// Update : Applies the patch and returns the language as it is after
+	Update(ctx context.Context, ID primitive.ObjectID, patch LanguagePatch) (Language, error)
+	// SetSelfTest : Records the outcome of a self-test, nil clearing it.
+	// A failed self-test never disables the language, only the worker that
+	// ran it leaves the language out of its advertisement.
+	SetSelfTest(ctx context.Context, ID primitive.ObjectID, test *SelfTest) (Language, error)
+	SoftDelete(ctx context.Context, ID primitive.ObjectID) error
+	// Restore : Brings back a soft deleted language, ErrNotDeleted when it
+	// was not deleted
+	Restore(ctx context.Context, ID primitive.ObjectID) error
+	// Delete : Removes the language for good
+	Delete(ctx context.Context, ID primitive.ObjectID) error
+}
+
+// QuestionRepo : Where questions are kept, soft deleted ones the same way
+// as languages
+type QuestionRepo interface {
+	// List : Questions of the given visibility, newest first
+	List(ctx context.Context, v visibility) ([]Question, error)
+	// Get : Question with the given ID, ErrNoSuchQuestion when missing
+	Get(ctx context.Context, ID primitive.ObjectID) (Question, error)
+	// GetIncludingDeleted : Question with the given ID even when soft
+	// deleted, the same way as languages
+	GetIncludingDeleted(ctx context.Context, ID primitive.ObjectID) (Question, error)
+	// Exists : Whether a question has the ID, deleted or not
+	Exists(ctx context.Context, ID primitive.ObjectID) (bool, error)
+	Insert(ctx context.Context, question Question) error
+	// Update : Applies the patch and returns the question as it is after
+	Update(ctx context.Context, ID primitive.ObjectID, patch QuestionPatch) (Question, error)
+	// LockTestcases : Takes the lock on the testcases of the question for
+	// holder until expiresAt, ErrTestcasesLocked while another holder has
+	// it and ErrNoSuchQuestion when there is no such question
+	LockTestcases(ctx context.Context, ID primitive.ObjectID, holder string, expiresAt time.Time) error
+	// UnlockTestcases : Releases the lock if holder still has it
+	UnlockTestcases(ctx context.Context, ID primitive.ObjectID, holder string) error
+	// SetTestcases : Records the testcases of the question, deleted or not,
+	// ErrTestcasesLocked unless holder has the lock on them
+	SetTestcases(ctx context.Context, ID primitive.ObjectID, holder string, testcases []Testcase) error
+	SoftDelete(ctx context.Context, ID primitive.ObjectID) error
+	Restore(ctx context.Context, ID primitive.ObjectID) error
+	// Delete : Removes the question for good and leaves a tombstone for it
+	Delete(ctx context.Context, ID primitive.ObjectID) error
+	// Tombstones : Questions deleted for good whose testcases are left
+	Tombstones(ctx context.Context) ([]QuestionTombstone, error)
+	// ForgetTombstone : Drops the tombstone once the testcases are removed
+	ForgetTombstone(ctx context.Context, ID primitive.ObjectID) error
+}
+
+// SubmissionRepo : Where submissions and their verdicts are kept
+type SubmissionRepo interface {
+	// Get : Submission with the ID, ErrNoSuchSubmission when there is none
+	Get(ctx context.Context, ID primitive.ObjectID) (Submission, error)
+	Insert(ctx context.Context, submission Submission) error
+	// Delete : Removes a submission that never made it to the queue
+	Delete(ctx context.Context, ID primitive.ObjectID) error
+	// Find : Submissions matching every field set in the filter
+	Find(ctx context.Context, filter SubmissionFilter) ([]Submission, error)
+	// Requeue : Moves the verdict of the submission into its history as the
+	// given judgement and marks it queued again, dropping its claim, unless
+	// the submission already holds a judgement of the same rejudge
+	Requeue(ctx context.Context, ID primitive.ObjectID, judgement Judgement) error
+	// Claim : Hands the judgement of the submission to the claim of a job,
+	// so earlier claims can no longer record a verdict
+	Claim(ctx context.Context, ID primitive.ObjectID, claimID primitive.ObjectID) error
+	// SetVerdict : Records the verdict, testcases and results of the
+	// submission judged under the claim, errClaimLost when another claim
+	// took the submission over
+	SetVerdict(ctx context.Context, submission Submission, claimID primitive.ObjectID) error
+	// UsesLanguage : Whether any submission is made in the language
+	UsesLanguage(ctx context.Context, ID primitive.ObjectID) (bool, error)
+	// UsesQuestion : Whether any submission answers the question
+	UsesQuestion(ctx context.Context, ID primitive.ObjectID) (bool, error)
+	// UsesContest : Whether any submission is made in the contest
+	UsesContest(ctx context.Context, ID primitive.ObjectID) (bool, error)
+}
+
+// ContestRepo : Where contests are kept
+type ContestRepo interface {
+	// List : Every contest, newest first
+	List(ctx context.Context) ([]Contest, error)
+	// Get : Contest with the ID, ErrNoSuchContest when there is none
+	Get(ctx context.Context, ID primitive.ObjectID) (Contest, error)
+	Insert(ctx context.Context, contest Contest) error
+	// Update : Applies the patch and returns the contest as it is after
+	Update(ctx context.Context, ID primitive.ObjectID, patch ContestPatch) (Contest, error)
+	// Delete : Removes the contest for good
+	Delete(ctx context.Context, ID primitive.ObjectID) error
+	// UsesLanguage : Whether any contest lists the language
+	UsesLanguage(ctx context.Context, ID primitive.ObjectID) (bool, error)
+	// UsesQuestion : Whether any contest holds the question
+	UsesQuestion(ctx context.Context, ID primitive.ObjectID) (bool, error)
+}
+
+// JobRepo : The judge queue. Workers claim the job of the highest priority
+// in a language they advertise, the oldest among equal priorities.
+type JobRepo interface {
+	Enqueue(ctx context.Context, jobs []JudgeJob) error
+	// Queued : Those of the submissions with a job, claimed or not
+	Queued(ctx context.Context, IDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	// Claim : Marks the next job in one of the languages as taken by the
+	// worker under a new claim ID, errQueueEmpty when there is none
+	Claim(ctx context.Context, workerID string, languages []primitive.ObjectID) (JudgeJob, error)
+	// Finish : Removes the job, as long as the worker still holds it
+	Finish(ctx context.Context, ID primitive.ObjectID, workerID string) error
+	// Release : Puts back the job, as long as the worker still holds it
+	Release(ctx context.Context, ID primitive.ObjectID, workerID string) error
+	// ReleaseExcept : Puts back the jobs claimed by workers other than the
+	// live ones and returns how many
+	ReleaseExcept(ctx context.Context, live []string) (int64, error)
+}
+
+// RejudgeRepo : Where rejudges are kept
+type RejudgeRepo interface {
+	Insert(ctx context.Context, rejudge Rejudge) error
+	// Get : Rejudge with the ID, ErrNoSuchRejudge when there is none
+	Get(ctx context.Context, ID primitive.ObjectID) (Rejudge, error)
+	// SetQueued : Marks every submission of the rejudge as requeued
+	SetQueued(ctx context.Context, ID primitive.ObjectID) error
+	// Pending : Rejudges not marked queued yet
+	Pending(ctx context.Context) ([]Rejudge, error)
+}
+
+// WorkerRepo : Where workers advertise the languages they judge
+type WorkerRepo interface {
+	// Heartbeat : Replaces the advertisement of the worker
+	Heartbeat(ctx context.Context, worker Worker) error
+	// Live : Workers whose advertisement has not expired at now, by ID
+	Live(ctx context.Context, now time.Time) ([]Worker, error)
+	// Retire : Withdraws the advertisement of the worker
+	Retire(ctx context.Context, ID string) error
+}
+
+// SubmissionFilter : Submissions to find, zero fields match everything
+type SubmissionFilter struct {
+	IDs        []primitive.ObjectID
+	QuestionID primitive.ObjectID
+	Verdict    string
+	NotVerdict string
+	Since      *time.Time // Made at or after
+}
+
+// matches : Whether the submission passes the filter
+func (f SubmissionFilter) matches(submission Submission) bool {
+	if f.IDs != nil && !containsID(f.IDs, submission.ID) {
+		return false
+	}
+	if !f.QuestionID.IsZero() && submission.QuestionID != f.QuestionID {
+		return false
+	}
+	if f.Verdict != "" && submission.Verdict != f.Verdict {
+		return false
+	}
+	if f.NotVerdict != "" && submission.Verdict == f.NotVerdict {
+		return false
+	}
+	// Submission IDs start with their creation time
+	return f.Since == nil || !submission.ID.Timestamp().Before(f.Since.Truncate(time.Second))
+}
+
+// mongo : Filter over the submissions collection
+func (f SubmissionFilter) mongo() bson.M {
+	filter := bson.M{}
+	ID := bson.M{}
+	if f.IDs != nil {
+		ID["$in"] = f.IDs
+	}
+	if f.Since != nil {
+		ID["$gte"] = primitive.NewObjectIDFromTimestamp(*f.Since)
+	}
+	if len(ID) > 0 {
+		filter["_id"] = ID
+	}
+	if !f.QuestionID.IsZero() {
+		filter["ques_id"] = bson.M{"$eq": f.QuestionID}
+	}
+	verdict := bson.M{}
+	if f.Verdict != "" {
+		verdict["$eq"] = f.Verdict
+	}
+	if f.NotVerdict != "" {
+		verdict["$ne"] = f.NotVerdict
+	}
+	if len(verdict) > 0 {
+		filter["verdict"] = verdict
+	}
+	return filter
+}
+
+// containsID : Whether ID is one of IDs
+func containsID(IDs []primitive.ObjectID, ID primitive.ObjectID) bool {
+	for _, other := range IDs {
+		if other == ID {
+			return true
+		}
+	}
+	return false
+}
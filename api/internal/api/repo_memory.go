@@ -0,0 +1,843 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// clone : Copies in to out through BSON, so callers never share slices or
+// maps with the store and values come back the way MongoDB returns them.
+// out is cleared first, so fields left out of in as empty end up empty.
+func clone(in interface{}, out interface{}) error {
+	raw, err := bson.Marshal(in)
+	if err != nil {
+		return err
+	}
+	target := reflect.ValueOf(out).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	return bson.Unmarshal(raw, out)
+}
+
+// lessID : Whether a was created before b, the order of ObjectIDs
+func lessID(a primitive.ObjectID, b primitive.ObjectID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// memoryLanguages : Languages kept in memory, for tests
+type memoryLanguages struct {
+	mu        sync.RWMutex
+	languages map[primitive.ObjectID]Language
+}
+
+// NewMemoryLanguageRepo : Empty language store living in memory
+func NewMemoryLanguageRepo() LanguageRepo {
+	return &memoryLanguages{languages: make(map[primitive.ObjectID]Language)}
+}
+
+func (m *memoryLanguages) List(ctx context.Context, v visibility) ([]Language, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	languages := []Language{}
+	for _, stored := range m.languages {
+		if !v.matches(stored.Archived, stored.DeletedAt) {
+			continue
+		}
+		var language Language
+		if err := clone(stored, &language); err != nil {
+			return nil, err
+		}
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i].Name < languages[j].Name })
+	return languages, nil
+}
+
+func (m *memoryLanguages) Get(ctx context.Context, ID primitive.ObjectID) (Language, error) {
+	return m.get(ID, false)
+}
+
+func (m *memoryLanguages) GetIncludingDeleted(ctx context.Context, ID primitive.ObjectID) (Language, error) {
+	return m.get(ID, true)
+}
+
+// get : Copy of the language, soft deleted ones only along with deleted
+func (m *memoryLanguages) get(ID primitive.ObjectID, deleted bool) (Language, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var language Language
+	stored, ok := m.languages[ID]
+	if !ok || (stored.DeletedAt != nil && !deleted) {
+		return language, ErrNoSuchLanguage
+	}
+	err := clone(stored, &language)
+	return language, err
+}
+
+func (m *memoryLanguages) Find(ctx context.Context, IDs []primitive.ObjectID) ([]Language, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	languages := []Language{}
+	for _, stored := range m.languages {
+		if stored.DeletedAt != nil || !containsID(IDs, stored.ID) {
+			continue
+		}
+		var language Language
+		if err := clone(stored, &language); err != nil {
+			return nil, err
+		}
+		languages = append(languages, language)
+	}
+	return languages, nil
+}
+
+func (m *memoryLanguages) Insert(ctx context.Context, language Language) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored Language
+	if err := clone(language, &stored); err != nil {
+		return err
+	}
+	m.languages[language.ID] = stored
+	return nil
+}
+
+func (m *memoryLanguages) Update(ctx context.Context, ID primitive.ObjectID, patch LanguagePatch) (Language, error) {
+	return m.change(ID, patch.apply)
+}
+
+func (m *memoryLanguages) SetSelfTest(ctx context.Context, ID primitive.ObjectID, test *SelfTest) (Language, error) {
+	return m.change(ID, func(language *Language) {
+		language.SelfTest = test
+	})
+}
+
+// change : Applies edit to the stored language and returns it as it is
+// after
+func (m *memoryLanguages) change(ID primitive.ObjectID, edit func(*Language)) (Language, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var language Language
+	stored, ok := m.languages[ID]
+	if !ok || stored.DeletedAt != nil {
+		return language, ErrNoSuchLanguage
+	}
+	if err := clone(stored, &language); err != nil {
+		return language, err
+	}
+	edit(&language)
+	if err := clone(language, &stored); err != nil {
+		return language, err
+	}
+	m.languages[ID] = stored
+	return language, nil
+}
+
+func (m *memoryLanguages) SoftDelete(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	language, ok := m.languages[ID]
+	if !ok || language.DeletedAt != nil {
+		return ErrNoSuchLanguage
+	}
+	now := time.Now().UTC()
+	language.DeletedAt = &now
+	m.languages[ID] = language
+	return nil
+}
+
+func (m *memoryLanguages) Restore(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	language, ok := m.languages[ID]
+	switch {
+	case !ok:
+		return ErrNoSuchLanguage
+	case language.DeletedAt == nil:
+		return ErrNotDeleted
+	}
+	language.DeletedAt = nil
+	m.languages[ID] = language
+	return nil
+}
+
+func (m *memoryLanguages) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.languages[ID]; !ok {
+		return ErrNoSuchLanguage
+	}
+	delete(m.languages, ID)
+	return nil
+}
+
+// memoryQuestions : Questions kept in memory, for tests
+type memoryQuestions struct {
+	mu         sync.RWMutex
+	questions  map[primitive.ObjectID]Question
+	tombstones map[primitive.ObjectID]QuestionTombstone
+}
+
+// NewMemoryQuestionRepo : Empty question store living in memory
+func NewMemoryQuestionRepo() QuestionRepo {
+	return &memoryQuestions{
+		questions:  make(map[primitive.ObjectID]Question),
+		tombstones: make(map[primitive.ObjectID]QuestionTombstone),
+	}
+}
+
+func (m *memoryQuestions) List(ctx context.Context, v visibility) ([]Question, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	questions := []Question{}
+	for _, stored := range m.questions {
+		if !v.matches(stored.Archived, stored.DeletedAt) {
+			continue
+		}
+		var question Question
+		if err := clone(stored, &question); err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	sort.Slice(questions, func(i, j int) bool { return lessID(questions[j].ID, questions[i].ID) })
+	return questions, nil
+}
+
+func (m *memoryQuestions) Get(ctx context.Context, ID primitive.ObjectID) (Question, error) {
+	return m.get(ID, false)
+}
+
+func (m *memoryQuestions) GetIncludingDeleted(ctx context.Context, ID primitive.ObjectID) (Question, error) {
+	return m.get(ID, true)
+}
+
+// get : Copy of the question, soft deleted ones only along with deleted
+func (m *memoryQuestions) get(ID primitive.ObjectID, deleted bool) (Question, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var question Question
+	stored, ok := m.questions[ID]
+	if !ok || (stored.DeletedAt != nil && !deleted) {
+		return question, ErrNoSuchQuestion
+	}
+	err := clone(stored, &question)
+	return question, err
+}
+
+func (m *memoryQuestions) Exists(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.questions[ID]
+	return ok, nil
+}
+
+func (m *memoryQuestions) Insert(ctx context.Context, question Question) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored Question
+	if err := clone(question, &stored); err != nil {
+		return err
+	}
+	m.questions[question.ID] = stored
+	return nil
+}
+
+func (m *memoryQuestions) Update(ctx context.Context, ID primitive.ObjectID, patch QuestionPatch) (Question, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var question Question
+	stored, ok := m.questions[ID]
+	if !ok || stored.DeletedAt != nil {
+		return question, ErrNoSuchQuestion
+	}
+	if err := clone(stored, &question); err != nil {
+		return question, err
+	}
+	patch.apply(&question)
+	if err := clone(question, &stored); err != nil {
+		return question, err
+	}
+	m.questions[ID] = stored
+	return question, nil
+}
+
+func (m *memoryQuestions) LockTestcases(ctx context.Context, ID primitive.ObjectID, holder string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	question, ok := m.questions[ID]
+	if !ok {
+		return ErrNoSuchQuestion
+	}
+	if lock := question.TestcaseLock; lock != nil && lock.Holder != holder && lock.ExpiresAt.After(time.Now()) {
+		return ErrTestcasesLocked
+	}
+	question.TestcaseLock = &TestcaseLock{Holder: holder, ExpiresAt: expiresAt}
+	m.questions[ID] = question
+	return nil
+}
+
+func (m *memoryQuestions) UnlockTestcases(ctx context.Context, ID primitive.ObjectID, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	question, ok := m.questions[ID]
+	if ok && question.TestcaseLock != nil && question.TestcaseLock.Holder == holder {
+		question.TestcaseLock = nil
+		m.questions[ID] = question
+	}
+	return nil
+}
+
+func (m *memoryQuestions) SetTestcases(ctx context.Context, ID primitive.ObjectID, holder string, testcases []Testcase) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	question, ok := m.questions[ID]
+	if !ok || question.TestcaseLock == nil || question.TestcaseLock.Holder != holder {
+		return ErrTestcasesLocked
+	}
+	question.NumTestcases = len(testcases)
+	question.Testcases = append([]Testcase{}, testcases...)
+	m.questions[ID] = question
+	return nil
+}
+
+func (m *memoryQuestions) SoftDelete(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	question, ok := m.questions[ID]
+	if !ok || question.DeletedAt != nil {
+		return ErrNoSuchQuestion
+	}
+	now := time.Now().UTC()
+	question.DeletedAt = &now
+	m.questions[ID] = question
+	return nil
+}
+
+func (m *memoryQuestions) Restore(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	question, ok := m.questions[ID]
+	switch {
+	case !ok:
+		return ErrNoSuchQuestion
+	case question.DeletedAt == nil:
+		return ErrNotDeleted
+	}
+	question.DeletedAt = nil
+	m.questions[ID] = question
+	return nil
+}
+
+func (m *memoryQuestions) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.questions[ID]; !ok {
+		return ErrNoSuchQuestion
+	}
+	delete(m.questions, ID)
+	m.tombstones[ID] = QuestionTombstone{ID: ID, DeletedAt: time.Now().UTC()}
+	return nil
+}
+
+func (m *memoryQuestions) Tombstones(ctx context.Context) ([]QuestionTombstone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tombstones := []QuestionTombstone{}
+	for _, tombstone := range m.tombstones {
+		tombstones = append(tombstones, tombstone)
+	}
+	sort.Slice(tombstones, func(i, j int) bool { return lessID(tombstones[i].ID, tombstones[j].ID) })
+	return tombstones, nil
+}
+
+func (m *memoryQuestions) ForgetTombstone(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tombstones, ID)
+	return nil
+}
+
+// MemorySubmissionRepo : Submissions kept in memory, for tests. Add puts
+// submissions in place of the judge.
+type MemorySubmissionRepo struct {
+	mu          sync.RWMutex
+	submissions map[primitive.ObjectID]Submission
+}
+
+// NewMemorySubmissionRepo : Empty submission store living in memory
+func NewMemorySubmissionRepo() *MemorySubmissionRepo {
+	return &MemorySubmissionRepo{submissions: make(map[primitive.ObjectID]Submission)}
+}
+
+// Add : Stores the submission as it is
+func (m *MemorySubmissionRepo) Add(submission Submission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored Submission
+	if err := clone(submission, &stored); err != nil {
+		return err
+	}
+	m.submissions[submission.ID] = stored
+	return nil
+}
+
+// Get : Copy of the submission with the ID
+func (m *MemorySubmissionRepo) Get(ctx context.Context, ID primitive.ObjectID) (Submission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stored, ok := m.submissions[ID]
+	if !ok {
+		return Submission{}, ErrNoSuchSubmission
+	}
+	var submission Submission
+	err := clone(stored, &submission)
+	return submission, err
+}
+
+// Insert : Stores a copy of the submission
+func (m *MemorySubmissionRepo) Insert(ctx context.Context, submission Submission) error {
+	return m.Add(submission)
+}
+
+// Delete : Forgets the submission
+func (m *MemorySubmissionRepo) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.submissions, ID)
+	return nil
+}
+
+// Find : Submissions matching the filter, oldest first
+func (m *MemorySubmissionRepo) Find(ctx context.Context, filter SubmissionFilter) ([]Submission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	submissions := []Submission{}
+	for _, stored := range m.submissions {
+		if !filter.matches(stored) {
+			continue
+		}
+		var submission Submission
+		if err := clone(stored, &submission); err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, submission)
+	}
+	sort.Slice(submissions, func(i, j int) bool { return lessID(submissions[i].ID, submissions[j].ID) })
+	return submissions, nil
+}
+
+// Requeue : Moves the verdict into the history and marks it queued again,
+// once per rejudge
+func (m *MemorySubmissionRepo) Requeue(ctx context.Context, ID primitive.ObjectID, judgement Judgement) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	submission, ok := m.submissions[ID]
+	if !ok {
+		return nil
+	}
+	for _, earlier := range submission.History {
+		if earlier.RejudgeID == judgement.RejudgeID {
+			return nil
+		}
+	}
+	var stored Judgement
+	if err := clone(judgement, &stored); err != nil {
+		return err
+	}
+	submission.History = append(append([]Judgement{}, submission.History...), stored)
+	submission.Verdict = VerdictQueued
+	submission.Testcases = map[int]string{}
+	submission.Results = nil
+	submission.ClaimID = nil
+	m.submissions[ID] = submission
+	return nil
+}
+
+// Claim : Hands the judgement of the submission to the claim
+func (m *MemorySubmissionRepo) Claim(ctx context.Context, ID primitive.ObjectID, claimID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if submission, ok := m.submissions[ID]; ok {
+		submission.ClaimID = &claimID
+		m.submissions[ID] = submission
+	}
+	return nil
+}
+
+// SetVerdict : Records the verdict, testcases and results, as long as the
+// claim still holds the submission
+func (m *MemorySubmissionRepo) SetVerdict(ctx context.Context, judged Submission, claimID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	submission, ok := m.submissions[judged.ID]
+	if !ok || submission.ClaimID == nil || *submission.ClaimID != claimID {
+		return errClaimLost
+	}
+	var stored Submission
+	if err := clone(judged, &stored); err != nil {
+		return err
+	}
+	submission.Verdict = stored.Verdict
+	submission.Testcases = stored.Testcases
+	submission.Results = stored.Results
+	m.submissions[judged.ID] = submission
+	return nil
+}
+
+// UsesLanguage : Whether any submission is made in the language
+func (m *MemorySubmissionRepo) UsesLanguage(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, submission := range m.submissions {
+		if submission.LanguageID == ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UsesQuestion : Whether any submission answers the question
+func (m *MemorySubmissionRepo) UsesQuestion(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, submission := range m.submissions {
+		if submission.QuestionID == ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UsesContest : Whether any submission is made in the contest
+func (m *MemorySubmissionRepo) UsesContest(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, submission := range m.submissions {
+		if submission.ContestID != nil && *submission.ContestID == ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// memoryContests : Contests kept in memory, for tests
+type memoryContests struct {
+	mu       sync.RWMutex
+	contests map[primitive.ObjectID]Contest
+}
+
+// NewMemoryContestRepo : Empty contest store living in memory
+func NewMemoryContestRepo() ContestRepo {
+	return &memoryContests{contests: make(map[primitive.ObjectID]Contest)}
+}
+
+func (m *memoryContests) List(ctx context.Context) ([]Contest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	contests := []Contest{}
+	for _, stored := range m.contests {
+		var contest Contest
+		if err := clone(stored, &contest); err != nil {
+			return nil, err
+		}
+		contests = append(contests, contest)
+	}
+	sort.Slice(contests, func(i, j int) bool { return lessID(contests[j].ID, contests[i].ID) })
+	return contests, nil
+}
+
+func (m *memoryContests) Get(ctx context.Context, ID primitive.ObjectID) (Contest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var contest Contest
+	stored, ok := m.contests[ID]
+	if !ok {
+		return contest, ErrNoSuchContest
+	}
+	err := clone(stored, &contest)
+	return contest, err
+}
+
+func (m *memoryContests) Insert(ctx context.Context, contest Contest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored Contest
+	if err := clone(contest, &stored); err != nil {
+		return err
+	}
+	m.contests[contest.ID] = stored
+	return nil
+}
+
+func (m *memoryContests) Update(ctx context.Context, ID primitive.ObjectID, patch ContestPatch) (Contest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var contest Contest
+	stored, ok := m.contests[ID]
+	if !ok {
+		return contest, ErrNoSuchContest
+	}
+	if err := clone(stored, &contest); err != nil {
+		return contest, err
+	}
+	patch.apply(&contest)
+	if err := clone(contest, &stored); err != nil {
+		return contest, err
+	}
+	m.contests[ID] = stored
+	return contest, nil
+}
+
+func (m *memoryContests) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.contests[ID]; !ok {
+		return ErrNoSuchContest
+	}
+	delete(m.contests, ID)
+	return nil
+}
+
+func (m *memoryContests) UsesLanguage(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, contest := range m.contests {
+		if containsID(contest.Languages, ID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryContests) UsesQuestion(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, contest := range m.contests {
+		if containsID(contest.Questions, ID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// memoryJobs : Judge queue kept in memory, for tests
+type memoryJobs struct {
+	mu   sync.Mutex
+	jobs map[primitive.ObjectID]JudgeJob
+}
+
+// NewMemoryJobRepo : Empty judge queue living in memory
+func NewMemoryJobRepo() JobRepo {
+	return &memoryJobs{jobs: make(map[primitive.ObjectID]JudgeJob)}
+}
+
+func (m *memoryJobs) Enqueue(ctx context.Context, jobs []JudgeJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range jobs {
+		var stored JudgeJob
+		if err := clone(job, &stored); err != nil {
+			return err
+		}
+		m.jobs[job.ID] = stored
+	}
+	return nil
+}
+
+func (m *memoryJobs) Queued(ctx context.Context, IDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queued := make(map[primitive.ObjectID]bool)
+	for _, job := range m.jobs {
+		if containsID(IDs, job.SubmissionID) {
+			queued[job.SubmissionID] = true
+		}
+	}
+	return queued, nil
+}
+
+func (m *memoryJobs) Claim(ctx context.Context, workerID string, languages []primitive.ObjectID) (JudgeJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var next *JudgeJob
+	for ID := range m.jobs {
+		job := m.jobs[ID]
+		if job.ClaimedBy != "" || !containsID(languages, job.LanguageID) {
+			continue
+		}
+		if next == nil || job.Priority > next.Priority ||
+			(job.Priority == next.Priority && job.EnqueuedAt.Before(next.EnqueuedAt)) {
+			next = &job
+		}
+	}
+	if next == nil {
+		return JudgeJob{}, errQueueEmpty
+	}
+	now := time.Now()
+	claimID := primitive.NewObjectID()
+	next.ClaimedBy = workerID
+	next.ClaimedAt = &now
+	next.ClaimID = &claimID
+	m.jobs[next.ID] = *next
+	var job JudgeJob
+	err := clone(*next, &job)
+	return job, err
+}
+
+func (m *memoryJobs) Finish(ctx context.Context, ID primitive.ObjectID, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[ID]; ok && job.ClaimedBy == workerID {
+		delete(m.jobs, ID)
+	}
+	return nil
+}
+
+func (m *memoryJobs) Release(ctx context.Context, ID primitive.ObjectID, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[ID]; ok && job.ClaimedBy == workerID {
+		job.ClaimedBy = ""
+		job.ClaimedAt = nil
+		job.ClaimID = nil
+		m.jobs[ID] = job
+	}
+	return nil
+}
+
+func (m *memoryJobs) ReleaseExcept(ctx context.Context, live []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alive := make(map[string]bool, len(live))
+	for _, ID := range live {
+		alive[ID] = true
+	}
+	var released int64
+	for ID, job := range m.jobs {
+		if job.ClaimedBy == "" || alive[job.ClaimedBy] {
+			continue
+		}
+		job.ClaimedBy = ""
+		job.ClaimedAt = nil
+		job.ClaimID = nil
+		m.jobs[ID] = job
+		released++
+	}
+	return released, nil
+}
+
+// memoryRejudges : Rejudges kept in memory, for tests
+type memoryRejudges struct {
+	mu       sync.RWMutex
+	rejudges map[primitive.ObjectID]Rejudge
+}
+
+// NewMemoryRejudgeRepo : Empty rejudge store living in memory
+func NewMemoryRejudgeRepo() RejudgeRepo {
+	return &memoryRejudges{rejudges: make(map[primitive.ObjectID]Rejudge)}
+}
+
+func (m *memoryRejudges) Insert(ctx context.Context, rejudge Rejudge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored Rejudge
+	if err := clone(rejudge, &stored); err != nil {
+		return err
+	}
+	m.rejudges[rejudge.ID] = stored
+	return nil
+}
+
+func (m *memoryRejudges) Get(ctx context.Context, ID primitive.ObjectID) (Rejudge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stored, ok := m.rejudges[ID]
+	if !ok {
+		return Rejudge{}, ErrNoSuchRejudge
+	}
+	var rejudge Rejudge
+	err := clone(stored, &rejudge)
+	return rejudge, err
+}
+
+func (m *memoryRejudges) SetQueued(ctx context.Context, ID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rejudge, ok := m.rejudges[ID]; ok {
+		rejudge.Queued = true
+		m.rejudges[ID] = rejudge
+	}
+	return nil
+}
+
+func (m *memoryRejudges) Pending(ctx context.Context) ([]Rejudge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rejudges := []Rejudge{}
+	for _, stored := range m.rejudges {
+		if stored.Queued {
+			continue
+		}
+		var rejudge Rejudge
+		if err := clone(stored, &rejudge); err != nil {
+			return nil, err
+		}
+		rejudges = append(rejudges, rejudge)
+	}
+	sort.Slice(rejudges, func(i, j int) bool { return lessID(rejudges[i].ID, rejudges[j].ID) })
+	return rejudges, nil
+}
+
+// memoryWorkers : Advertisements kept in memory, for tests
+type memoryWorkers struct {
+	mu      sync.RWMutex
+	workers map[string]Worker
+}
+
+// NewMemoryWorkerRepo : Empty advertisement store living in memory
+func NewMemoryWorkerRepo() WorkerRepo {
+	return &memoryWorkers{workers: make(map[string]Worker)}
+}
+
+func (m *memoryWorkers) Heartbeat(ctx context.Context, worker Worker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored Worker
+	if err := clone(worker, &stored); err != nil {
+		return err
+	}
+	m.workers[worker.ID] = stored
+	return nil
+}
+
+func (m *memoryWorkers) Live(ctx context.Context, now time.Time) ([]Worker, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	workers := []Worker{}
+	for _, stored := range m.workers {
+		if !stored.ExpiresAt.After(now) {
+			continue
+		}
+		var worker Worker
+		if err := clone(stored, &worker); err != nil {
+			return nil, err
+		}
+		workers = append(workers, worker)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
+func (m *memoryWorkers) Retire(ctx context.Context, ID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.workers, ID)
+	return nil
+}
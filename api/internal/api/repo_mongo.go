@@ -0,0 +1,724 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// MongoOptions : Time single operations of the repositories get, and where
+// listings are read from
+type MongoOptions struct {
+	ReadTimeout  time.Duration      // Zero for none
+	WriteTimeout time.Duration      // Zero for none
+	ListReadPref *readpref.ReadPref // Nil reads listings from the primary
+}
+
+// read : Context of a single read
+func (o MongoOptions) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.ReadTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.ReadTimeout)
+}
+
+// write : Context of a single write
+func (o MongoOptions) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.WriteTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.WriteTimeout)
+}
+
+// listing : Collection of db listings read from. Listings may lag behind
+// the last writes, reads by ID and reads made to write stay on the
+// primary.
+func (o MongoOptions) listing(db *mongo.Database, name string) *mongo.Collection {
+	if o.ListReadPref == nil {
+		return db.Collection(name)
+	}
+	return db.Collection(name, options.Collection().SetReadPreference(o.ListReadPref))
+}
+
+// mongoLanguages : Languages kept in the languages collection
+type mongoLanguages struct {
+	MongoOptions
+	collection *mongo.Collection
+	listing    *mongo.Collection
+}
+
+// NewMongoLanguageRepo : Languages kept in the languages collection of db
+func NewMongoLanguageRepo(db *mongo.Database, opts MongoOptions) LanguageRepo {
+	return mongoLanguages{MongoOptions: opts, collection: db.Collection("languages"), listing: opts.listing(db, "languages")}
+}
+
+func (m mongoLanguages) List(ctx context.Context, v visibility) ([]Language, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.listing.Find(ctx, v.filter(), options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	languages := []Language{}
+	err = cursor.All(ctx, &languages)
+	return languages, err
+}
+
+func (m mongoLanguages) Get(ctx context.Context, ID primitive.ObjectID) (Language, error) {
+	return m.findOne(ctx, notDeleted(ID))
+}
+
+func (m mongoLanguages) GetIncludingDeleted(ctx context.Context, ID primitive.ObjectID) (Language, error) {
+	return m.findOne(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+}
+
+// findOne : Language matching the filter, ErrNoSuchLanguage when there is none
+func (m mongoLanguages) findOne(ctx context.Context, filter bson.M) (Language, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	var language Language
+	err := m.collection.FindOne(ctx, filter).Decode(&language)
+	if err != nil && errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchLanguage
+	}
+	return language, err
+}
+
+func (m mongoLanguages) Find(ctx context.Context, IDs []primitive.ObjectID) ([]Language, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": IDs}, "deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	languages := []Language{}
+	err = cursor.All(ctx, &languages)
+	return languages, err
+}
+
+func (m mongoLanguages) Insert(ctx context.Context, language Language) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, language)
+	return err
+}
+
+func (m mongoLanguages) Update(ctx context.Context, ID primitive.ObjectID, patch LanguagePatch) (Language, error) {
+	update := patch.update()
+	if len(update) <= 0 {
+		return m.Get(ctx, ID)
+	}
+	return m.findAndUpdate(ctx, ID, update)
+}
+
+func (m mongoLanguages) SetSelfTest(ctx context.Context, ID primitive.ObjectID, test *SelfTest) (Language, error) {
+	update := bson.M{"$unset": bson.M{"self_test": ""}}
+	if test != nil {
+		update = bson.M{"$set": bson.M{"self_test": test}}
+	}
+	return m.findAndUpdate(ctx, ID, update)
+}
+
+// findAndUpdate : Applies the update to the language and returns it as it
+// is after
+func (m mongoLanguages) findAndUpdate(ctx context.Context, ID primitive.ObjectID, update bson.M) (Language, error) {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	var language Language
+	err := m.collection.FindOneAndUpdate(ctx, notDeleted(ID), update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&language)
+	if err != nil && errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchLanguage
+	}
+	return language, err
+}
+
+func (m mongoLanguages) SoftDelete(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	return mongoSoftDelete(ctx, m.collection, ID, ErrNoSuchLanguage)
+}
+
+func (m mongoLanguages) Restore(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	return mongoRestore(ctx, m.collection, ID, ErrNoSuchLanguage)
+}
+
+func (m mongoLanguages) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	return mongoDelete(ctx, m.collection, ID, ErrNoSuchLanguage)
+}
+
+// mongoQuestions : Questions kept in the questions collection, and the
+// tombstones of those deleted for good in question_tombstones
+type mongoQuestions struct {
+	MongoOptions
+	collection *mongo.Collection
+	listing    *mongo.Collection
+	tombstones *mongo.Collection
+}
+
+// NewMongoQuestionRepo : Questions kept in the questions collection of db
+func NewMongoQuestionRepo(db *mongo.Database, opts MongoOptions) QuestionRepo {
+	return mongoQuestions{
+		MongoOptions: opts,
+		collection:   db.Collection("questions"),
+		listing:      opts.listing(db, "questions"),
+		tombstones:   db.Collection("question_tombstones"),
+	}
+}
+
+func (m mongoQuestions) List(ctx context.Context, v visibility) ([]Question, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.listing.Find(ctx, v.filter(), options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return nil, err
+	}
+	questions := []Question{}
+	err = cursor.All(ctx, &questions)
+	return questions, err
+}
+
+func (m mongoQuestions) Get(ctx context.Context, ID primitive.ObjectID) (Question, error) {
+	return m.findOne(ctx, notDeleted(ID))
+}
+
+func (m mongoQuestions) GetIncludingDeleted(ctx context.Context, ID primitive.ObjectID) (Question, error) {
+	return m.findOne(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+}
+
+// findOne : Question matching the filter, ErrNoSuchQuestion when there is none
+func (m mongoQuestions) findOne(ctx context.Context, filter bson.M) (Question, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	var question Question
+	err := m.collection.FindOne(ctx, filter).Decode(&question)
+	if err != nil && errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchQuestion
+	}
+	return question, err
+}
+
+func (m mongoQuestions) Exists(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	count, err := m.collection.CountDocuments(ctx, bson.M{"_id": bson.M{"$eq": ID}}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+func (m mongoQuestions) Insert(ctx context.Context, question Question) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, question)
+	return err
+}
+
+func (m mongoQuestions) Update(ctx context.Context, ID primitive.ObjectID, patch QuestionPatch) (Question, error) {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	var question Question
+	err := m.collection.FindOneAndUpdate(ctx, notDeleted(ID), patch.update(),
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&question)
+	if err != nil && errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchQuestion
+	}
+	return question, err
+}
+
+func (m mongoQuestions) LockTestcases(ctx context.Context, ID primitive.ObjectID, holder string, expiresAt time.Time) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	filter := bson.M{"_id": bson.M{"$eq": ID}, "$or": bson.A{
+		bson.M{"testcase_lock": bson.M{"$exists": false}},
+		bson.M{"testcase_lock.expires_at": bson.M{"$lte": time.Now().UTC()}},
+		bson.M{"testcase_lock.holder": bson.M{"$eq": holder}},
+	}}
+	lock := TestcaseLock{Holder: holder, ExpiresAt: expiresAt.UTC()}
+	result, err := m.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"testcase_lock": lock}})
+	if err != nil || result.MatchedCount > 0 {
+		return err
+	}
+	count, err := m.collection.CountDocuments(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNoSuchQuestion
+	}
+	return ErrTestcasesLocked
+}
+
+func (m mongoQuestions) UnlockTestcases(ctx context.Context, ID primitive.ObjectID, holder string) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": ID}, "testcase_lock.holder": bson.M{"$eq": holder}},
+		bson.M{"$unset": bson.M{"testcase_lock": ""}})
+	return err
+}
+
+// SetTestcases : Only written while holder has the lock, an edit whose
+// lock expired and was taken over cannot overwrite the next one
+func (m mongoQuestions) SetTestcases(ctx context.Context, ID primitive.ObjectID, holder string, testcases []Testcase) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	result, err := m.collection.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": ID}, "testcase_lock.holder": bson.M{"$eq": holder}},
+		bson.M{"$set": bson.M{"num_testcases": len(testcases), "testcases": testcases}})
+	if err == nil && result.MatchedCount == 0 {
+		err = ErrTestcasesLocked
+	}
+	return err
+}
+
+func (m mongoQuestions) SoftDelete(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	return mongoSoftDelete(ctx, m.collection, ID, ErrNoSuchQuestion)
+}
+
+func (m mongoQuestions) Restore(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	return mongoRestore(ctx, m.collection, ID, ErrNoSuchQuestion)
+}
+
+// Delete : The tombstone is written after the question is gone, so a
+// failure in between leaves testcases behind but never purges those of a
+// question that still exists
+func (m mongoQuestions) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	if err := mongoDelete(ctx, m.collection, ID, ErrNoSuchQuestion); err != nil {
+		return err
+	}
+	tombstone := QuestionTombstone{ID: ID, DeletedAt: time.Now().UTC()}
+	_, err := m.tombstones.ReplaceOne(ctx, bson.M{"_id": bson.M{"$eq": ID}}, tombstone, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m mongoQuestions) Tombstones(ctx context.Context) ([]QuestionTombstone, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.tombstones.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	tombstones := []QuestionTombstone{}
+	err = cursor.All(ctx, &tombstones)
+	return tombstones, err
+}
+
+func (m mongoQuestions) ForgetTombstone(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.tombstones.DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+	return err
+}
+
+// mongoSubmissions : Submissions kept in the submissions collection
+type mongoSubmissions struct {
+	MongoOptions
+	collection *mongo.Collection
+}
+
+// NewMongoSubmissionRepo : Submissions kept in the submissions collection
+// of db
+func NewMongoSubmissionRepo(db *mongo.Database, opts MongoOptions) SubmissionRepo {
+	return mongoSubmissions{MongoOptions: opts, collection: db.Collection("submissions")}
+}
+
+func (m mongoSubmissions) Get(ctx context.Context, ID primitive.ObjectID) (Submission, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	var submission Submission
+	err := m.collection.FindOne(ctx, bson.M{"_id": bson.M{"$eq": ID}}).Decode(&submission)
+	if err != nil && errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchSubmission
+	}
+	return submission, err
+}
+
+func (m mongoSubmissions) Insert(ctx context.Context, submission Submission) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, submission)
+	return err
+}
+
+func (m mongoSubmissions) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+	return err
+}
+
+func (m mongoSubmissions) Find(ctx context.Context, filter SubmissionFilter) ([]Submission, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, filter.mongo())
+	if err != nil {
+		return nil, err
+	}
+	submissions := []Submission{}
+	err = cursor.All(ctx, &submissions)
+	return submissions, err
+}
+
+func (m mongoSubmissions) Requeue(ctx context.Context, ID primitive.ObjectID, judgement Judgement) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	filter := bson.M{"_id": bson.M{"$eq": ID}, "history.rejudge_id": bson.M{"$ne": judgement.RejudgeID}}
+	_, err := m.collection.UpdateOne(ctx, filter, bson.M{
+		"$push":  bson.M{"history": judgement},
+		"$set":   bson.M{"verdict": VerdictQueued, "testcases": bson.M{}},
+		"$unset": bson.M{"results": "", "claim_id": ""},
+	})
+	return err
+}
+
+func (m mongoSubmissions) Claim(ctx context.Context, ID primitive.ObjectID, claimID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": ID}}, bson.M{"$set": bson.M{"claim_id": claimID}})
+	return err
+}
+
+func (m mongoSubmissions) SetVerdict(ctx context.Context, submission Submission, claimID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	result, err := m.collection.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": submission.ID}, "claim_id": bson.M{"$eq": claimID}}, bson.M{
+		"$set": bson.M{"verdict": submission.Verdict, "testcases": submission.Testcases, "results": submission.Results},
+	})
+	if err == nil && result.MatchedCount <= 0 {
+		err = errClaimLost
+	}
+	return err
+}
+
+func (m mongoSubmissions) UsesLanguage(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	return m.references(ctx, "lang_id", ID)
+}
+
+func (m mongoSubmissions) UsesQuestion(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	return m.references(ctx, "ques_id", ID)
+}
+
+func (m mongoSubmissions) UsesContest(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	return m.references(ctx, "contest_id", ID)
+}
+
+// references : Whether a submission holds ID in the given field
+func (m mongoSubmissions) references(ctx context.Context, field string, ID primitive.ObjectID) (bool, error) {
+	count, err := m.collection.CountDocuments(ctx, bson.M{field: bson.M{"$eq": ID}}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// mongoContests : Contests kept in the contests collection
+type mongoContests struct {
+	MongoOptions
+	collection *mongo.Collection
+	listing    *mongo.Collection
+}
+
+// NewMongoContestRepo : Contests kept in the contests collection of db
+func NewMongoContestRepo(db *mongo.Database, opts MongoOptions) ContestRepo {
+	return mongoContests{MongoOptions: opts, collection: db.Collection("contests"), listing: opts.listing(db, "contests")}
+}
+
+func (m mongoContests) List(ctx context.Context) ([]Contest, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.listing.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return nil, err
+	}
+	contests := []Contest{}
+	err = cursor.All(ctx, &contests)
+	return contests, err
+}
+
+func (m mongoContests) Get(ctx context.Context, ID primitive.ObjectID) (Contest, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	var contest Contest
+	err := m.collection.FindOne(ctx, bson.M{"_id": bson.M{"$eq": ID}}).Decode(&contest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchContest
+	}
+	return contest, err
+}
+
+func (m mongoContests) Insert(ctx context.Context, contest Contest) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, contest)
+	return err
+}
+
+func (m mongoContests) Update(ctx context.Context, ID primitive.ObjectID, patch ContestPatch) (Contest, error) {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	var contest Contest
+	err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": bson.M{"$eq": ID}}, patch.update(),
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&contest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchContest
+	}
+	return contest, err
+}
+
+func (m mongoContests) Delete(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	return mongoDelete(ctx, m.collection, ID, ErrNoSuchContest)
+}
+
+func (m mongoContests) UsesLanguage(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	return m.references(ctx, "languages", ID)
+}
+
+func (m mongoContests) UsesQuestion(ctx context.Context, ID primitive.ObjectID) (bool, error) {
+	return m.references(ctx, "questions", ID)
+}
+
+// references : Whether a contest lists ID in the given array field
+func (m mongoContests) references(ctx context.Context, field string, ID primitive.ObjectID) (bool, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	count, err := m.collection.CountDocuments(ctx, bson.M{field: bson.M{"$eq": ID}}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// mongoJobs : Judge queue kept in the judge_queue collection
+type mongoJobs struct {
+	MongoOptions
+	collection *mongo.Collection
+}
+
+// NewMongoJobRepo : Judge queue kept in the judge_queue collection of db
+func NewMongoJobRepo(db *mongo.Database, opts MongoOptions) JobRepo {
+	return mongoJobs{MongoOptions: opts, collection: db.Collection("judge_queue")}
+}
+
+func (m mongoJobs) Enqueue(ctx context.Context, jobs []JudgeJob) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	documents := make([]interface{}, len(jobs))
+	for i := range jobs {
+		documents[i] = jobs[i]
+	}
+	_, err := m.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+func (m mongoJobs) Queued(ctx context.Context, IDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, bson.M{"sub_id": bson.M{"$in": IDs}}, options.Find().SetProjection(bson.M{"sub_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var jobs []JudgeJob
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	queued := make(map[primitive.ObjectID]bool, len(jobs))
+	for _, job := range jobs {
+		queued[job.SubmissionID] = true
+	}
+	return queued, nil
+}
+
+func (m mongoJobs) Claim(ctx context.Context, workerID string, languages []primitive.ObjectID) (JudgeJob, error) {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	var job JudgeJob
+	err := m.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"claimed_by": bson.M{"$exists": false},
+			"lang_id":    bson.M{"$in": languages},
+		},
+		bson.M{"$set": bson.M{"claimed_by": workerID, "claimed_at": time.Now(), "claim_id": primitive.NewObjectID()}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "enqueued_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = errQueueEmpty
+	}
+	return job, err
+}
+
+func (m mongoJobs) Finish(ctx context.Context, ID primitive.ObjectID, workerID string) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": ID}, "claimed_by": bson.M{"$eq": workerID}})
+	return err
+}
+
+func (m mongoJobs) Release(ctx context.Context, ID primitive.ObjectID, workerID string) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.UpdateOne(ctx,
+		bson.M{"_id": bson.M{"$eq": ID}, "claimed_by": bson.M{"$eq": workerID}},
+		bson.M{"$unset": bson.M{"claimed_by": "", "claimed_at": "", "claim_id": ""}},
+	)
+	return err
+}
+
+func (m mongoJobs) ReleaseExcept(ctx context.Context, live []string) (int64, error) {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	result, err := m.collection.UpdateMany(ctx,
+		bson.M{"claimed_by": bson.M{"$exists": true, "$nin": live}},
+		bson.M{"$unset": bson.M{"claimed_by": "", "claimed_at": "", "claim_id": ""}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// mongoRejudges : Rejudges kept in the rejudges collection
+type mongoRejudges struct {
+	MongoOptions
+	collection *mongo.Collection
+}
+
+// NewMongoRejudgeRepo : Rejudges kept in the rejudges collection of db
+func NewMongoRejudgeRepo(db *mongo.Database, opts MongoOptions) RejudgeRepo {
+	return mongoRejudges{MongoOptions: opts, collection: db.Collection("rejudges")}
+}
+
+func (m mongoRejudges) Insert(ctx context.Context, rejudge Rejudge) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, rejudge)
+	return err
+}
+
+func (m mongoRejudges) Get(ctx context.Context, ID primitive.ObjectID) (Rejudge, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	var rejudge Rejudge
+	err := m.collection.FindOne(ctx, bson.M{"_id": bson.M{"$eq": ID}}).Decode(&rejudge)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = ErrNoSuchRejudge
+	}
+	return rejudge, err
+}
+
+func (m mongoRejudges) SetQueued(ctx context.Context, ID primitive.ObjectID) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": ID}}, bson.M{"$set": bson.M{"queued": true}})
+	return err
+}
+
+func (m mongoRejudges) Pending(ctx context.Context) ([]Rejudge, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, bson.M{"queued": bson.M{"$ne": true}}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	rejudges := []Rejudge{}
+	err = cursor.All(ctx, &rejudges)
+	return rejudges, err
+}
+
+// mongoWorkers : Advertisements kept in the workers collection, expired
+// ones are dropped by its TTL index
+type mongoWorkers struct {
+	MongoOptions
+	collection *mongo.Collection
+}
+
+// NewMongoWorkerRepo : Advertisements kept in the workers collection of db
+func NewMongoWorkerRepo(db *mongo.Database, opts MongoOptions) WorkerRepo {
+	return mongoWorkers{MongoOptions: opts, collection: db.Collection("workers")}
+}
+
+func (m mongoWorkers) Heartbeat(ctx context.Context, worker Worker) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"_id": bson.M{"$eq": worker.ID}}, worker, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m mongoWorkers) Live(ctx context.Context, now time.Time) ([]Worker, error) {
+	ctx, cancel := m.read(ctx)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, bson.M{"expires_at": bson.M{"$gt": now}}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	workers := []Worker{}
+	err = cursor.All(ctx, &workers)
+	return workers, err
+}
+
+func (m mongoWorkers) Retire(ctx context.Context, ID string) error {
+	ctx, cancel := m.write(ctx)
+	defer cancel()
+	_, err := m.collection.DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+	return err
+}
+
+// mongoSoftDelete : Marks the document deleted, it stays in place for the
+// submissions referencing it and can be restored
+func mongoSoftDelete(ctx context.Context, collection *mongo.Collection, ID primitive.ObjectID, notFound error) error {
+	updateResult, err := collection.UpdateOne(ctx, notDeleted(ID), bson.M{"$set": bson.M{"deleted_at": time.Now().UTC()}})
+	if err != nil {
+		return err
+	}
+	if updateResult.MatchedCount <= 0 {
+		return notFound
+	}
+	return nil
+}
+
+// mongoRestore : Brings back a soft deleted document
+func mongoRestore(ctx context.Context, collection *mongo.Collection, ID primitive.ObjectID, notFound error) error {
+	updateResult, err := collection.UpdateOne(ctx,
+		bson.M{"_id": bson.M{"$eq": ID}, "deleted_at": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"deleted_at": ""}})
+	if err != nil || updateResult.MatchedCount > 0 {
+		return err
+	}
+
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": bson.M{"$eq": ID}}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrNotDeleted
+	}
+	return notFound
+}
+
+// mongoDelete : Removes the document for good
+func mongoDelete(ctx context.Context, collection *mongo.Collection, ID primitive.ObjectID, notFound error) error {
+	deleteResult, err := collection.DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": ID}})
+	if err != nil {
+		return err
+	}
+	if deleteResult.DeletedCount <= 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"judge-two/internal/judge"
+	"judge-two/internal/tracing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Errors returned by language self-tests
+var (
+	ErrNoProbe          = errors.New("language has no probe program to self-test with")
+	ErrSelfTestFailed   = errors.New("language failed its self-test and cannot be enabled")
+	errProbeInputFailed = errors.New("probe did not print back its input")
+)
+
+// Probe runs are bounded whatever the time multiplier of the language
+const (
+	probeInput     = "judge self-test\n1 2 3\n"
+	probeTimeLimit = 5 * time.Second
+	selfTestLimit  = 60 * time.Second
+)
+
+// Validate : A probe needs its source, the version command is optional
+func (p LanguageProbe) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Source, validation.Required),
+		validation.Field(&p.Version, validation.Each(validation.Required)),
+	)
+}
+
+// runnerLanguage : Commands of the language as the runner takes them
+func (l Language) runnerLanguage() judge.Language {
+	return judge.Language{
+		Name:      l.Name,
+		Time:      l.Time,
+		Filename:  l.Filename,
+		Compile:   l.Compile,
+		Execute:   l.Execute,
+		Artifacts: l.Artifacts,
+		Limits:    l.Limits,
+	}
+}
+
+// selfTest : Compiles the probe of the language in the sandbox and runs it
+// on a fixed input, which it has to print back. Probes are programs sent
+// by admins, only workers run them and never the API.
+func (api *API) selfTest(ctx context.Context, language Language) *SelfTest {
+	ctx, span := tracing.Tracer().Start(ctx, "language self-test")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, selfTestLimit)
+	defer cancel()
+
+	result := &SelfTest{CheckedAt: time.Now().UTC()}
+	if len(language.Probe.Version) > 0 {
+		version, err := api.sandbox().Version(ctx, language.Probe.Version)
+		if err != nil && version == "" {
+			version = err.Error()
+		}
+		result.Version = version
+	}
+
+	dir, err := ioutil.TempDir("", "probe-")
+	if err != nil {
+		result.Verdict = judge.VerdictRuntimeError
+		result.Output = err.Error()
+		return result
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "source")
+	testcase := judge.Testcase{Input: filepath.Join(dir, "input"), Output: filepath.Join(dir, "input")}
+	err = ioutil.WriteFile(source, []byte(language.Probe.Source), 0644)
+	if err == nil {
+		err = ioutil.WriteFile(testcase.Input, []byte(probeInput), 0644)
+	}
+
+	var program *judge.Program
+	if err == nil {
+		program, err = api.sandbox().Compile(ctx, language.runnerLanguage(), source)
+	}
+	var compileErr *judge.CompileError
+	switch {
+	case errors.As(err, &compileErr):
+		result.Verdict = judge.VerdictCompileError
+		result.Output = compileErr.Output
+		return result
+	case err != nil:
+		result.Verdict = judge.VerdictCompileError
+		result.Output = err.Error()
+		return result
+	}
+	defer program.Close()
+
+	run, err := program.Run(ctx, testcase, probeTimeLimit)
+	switch {
+	case err != nil:
+		result.Verdict = judge.VerdictRuntimeError
+		result.Output = err.Error()
+	case run.Verdict == judge.VerdictWrongAnswer:
+		result.Verdict = run.Verdict
+		result.Output = errProbeInputFailed.Error()
+	default:
+		result.Verdict = run.Verdict
+		result.Output = run.Detail
+	}
+	result.Passed = result.Verdict == judge.VerdictAccepted
+
+	api.log(ctx).Info("Language self-test",
+		zap.String("language", language.Name), zap.String("verdict", result.Verdict), zap.String("version", result.Version))
+	return result
+}
+
+// retestLanguage : Clears the last self-test of a stored language, so the
+// workers run its probe again at their next heartbeat
+func (api *API) retestLanguage(ctx context.Context, ID primitive.ObjectID) (Language, error) {
+	language, err := api.Languages.Get(ctx, ID)
+	if err != nil {
+		return language, err
+	}
+	if language.Probe == nil {
+		return language, ErrNoProbe
+	}
+
+	return api.Languages.SetSelfTest(ctx, ID, nil)
+}
+
+func (api *API) selfTestLanguageV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	language, err := api.retestLanguage(r.Context(), ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, language)
+}
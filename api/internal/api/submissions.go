@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// maxSourceSize : Largest solution taken, in bytes
+const maxSourceSize = 64 << 10
+
+// ErrNoSuchSubmission : Returned for unknown submission IDs
+var ErrNoSuchSubmission = errors.New("no such submission with this ID")
+
+// SubmissionInput : Body of POST /v2/submissions
+type SubmissionInput struct {
+	QuestionID string `json:"ques_id"`
+	LanguageID string `json:"lang_id"`
+	ContestID  string `json:"contest_id,omitempty"` // Contest the submission is made in, if any
+	Source     string `json:"source"`
+}
+
+// Validate : Both IDs and a source no larger than maxSourceSize
+func (s SubmissionInput) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.QuestionID, validation.Required),
+		validation.Field(&s.LanguageID, validation.Required),
+		validation.Field(&s.Source, validation.Required, validation.Length(1, maxSourceSize)),
+	)
+}
+
+// submit : Stores the submission once its question, and its contest when
+// it is made in one, take its language and puts it in the judge queue. A submission that could not be queued is
+// removed again, so none waits for a verdict that never comes.
+func (api *API) submit(ctx context.Context, input SubmissionInput) (Submission, error) {
+	if err := input.Validate(); err != nil {
+		return Submission{}, err
+	}
+	questionID, err := parseID(input.QuestionID)
+	if err != nil {
+		return Submission{}, validation.Errors{"ques_id": err}
+	}
+	languageID, err := parseID(input.LanguageID)
+	if err != nil {
+		return Submission{}, validation.Errors{"lang_id": err}
+	}
+	if api.shuttingDown() {
+		return Submission{}, ErrShuttingDown
+	}
+
+	question, err := api.Questions.Get(ctx, questionID)
+	if err == nil && question.Archived {
+		err = ErrNoSuchQuestion
+	}
+	if err != nil {
+		return Submission{}, err
+	}
+	contest, err := api.questionContest(ctx, question, input.ContestID)
+	if err != nil {
+		return Submission{}, err
+	}
+	if _, err = api.submissionLanguage(ctx, question, contest, languageID); err != nil {
+		return Submission{}, err
+	}
+
+	submission := Submission{
+		ID:         primitive.NewObjectID(),
+		LanguageID: languageID,
+		QuestionID: questionID,
+		Verdict:    VerdictQueued,
+		Testcases:  map[int]string{},
+		Source:     input.Source,
+	}
+	if contest != nil {
+		submission.ContestID = &contest.ID
+	}
+	if err = api.Submissions.Insert(ctx, submission); err != nil {
+		return submission, err
+	}
+	if err = api.enqueueSubmissions(ctx, []Submission{submission}, PrioritySubmission, nil); err != nil {
+		if deleteErr := api.Submissions.Delete(context.Background(), submission.ID); deleteErr != nil {
+			api.log(ctx).Error("Removing a submission that could not be queued failed",
+				zap.String("submission", submission.ID.Hex()), zap.Error(deleteErr))
+		}
+		return submission, err
+	}
+	return submission, nil
+}
+
+func (api *API) createSubmissionV2(w http.ResponseWriter, r *http.Request) {
+	var reqBody SubmissionInput
+	if err := decodeJSON(r, &reqBody); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	submission, err := api.submit(r.Context(), reqBody)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/submissions/"+submission.ID.Hex())
+	writeJSON(w, http.StatusCreated, submission)
+}
+
+func (api *API) getSubmissionV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	submission, err := api.Submissions.Get(r.Context(), ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, submission)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSubmissionRoutes(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1", "2")
+	input := SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: python.ID.Hex(), Source: "print(input())"}
+
+	rec := s.json(http.MethodPost, "/v2/submissions", input, false)
+	var submission Submission
+	expectJSON(t, rec, http.StatusCreated, &submission)
+	path := "/v2/submissions/" + submission.ID.Hex()
+	if location := rec.Header().Get("Location"); location != path {
+		t.Fatalf("created at %q, expected %q", location, path)
+	}
+	if submission.Verdict != VerdictQueued || strings.Contains(rec.Body.String(), input.Source) {
+		t.Fatalf("created %s", rec.Body.String())
+	}
+	queued, err := s.Jobs.Queued(context.Background(), []primitive.ObjectID{submission.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !queued[submission.ID] {
+		t.Fatal("submission not queued")
+	}
+
+	// Workers claim the job in the language of the submission only
+	if _, err = s.Jobs.Claim(context.Background(), "other", []primitive.ObjectID{primitive.NewObjectID()}); err != errQueueEmpty {
+		t.Fatalf("claimed in another language with %v", err)
+	}
+	job, err := s.Jobs.Claim(context.Background(), "worker", []primitive.ObjectID{python.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.SubmissionID != submission.ID || job.Priority != PrioritySubmission {
+		t.Fatalf("claimed %+v", job)
+	}
+
+	stored, err := s.Submissions.Get(context.Background(), submission.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Source != input.Source {
+		t.Fatalf("stored source %q", stored.Source)
+	}
+	expectJSON(t, s.get(path, false), http.StatusOK, &submission)
+	expectError(t, s.get("/v2/submissions/"+primitive.NewObjectID().Hex(), false), http.StatusNotFound, CodeNotFound)
+	expectError(t, s.get("/v2/submissions/nope", false), http.StatusNotFound, CodeNotFound)
+}
+
+func TestSubmissionRoutesRefuse(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	ruby := s.language("Ruby")
+	question := s.question("Echo", "1")
+	valid := SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: python.ID.Hex(), Source: "print(input())"}
+
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: python.ID.Hex()}, false),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", SubmissionInput{QuestionID: "nope", LanguageID: python.ID.Hex(), Source: "x"}, false),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", SubmissionInput{QuestionID: primitive.NewObjectID().Hex(), LanguageID: python.ID.Hex(), Source: "x"}, false),
+		http.StatusNotFound, CodeNotFound)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: python.ID.Hex(),
+		Source: strings.Repeat("x", maxSourceSize+1)}, false), http.StatusUnprocessableEntity, CodeValidationFailed)
+
+	// Languages the question does not take are refused with the reason
+	languages := []primitive.ObjectID{python.ID}
+	expectStatus(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Languages: &languages}, true), http.StatusOK)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: ruby.ID.Hex(), Source: "x"}, false),
+		http.StatusBadRequest, CodeInvalidRequest)
+	disabled := true
+	expectStatus(t, s.json(http.MethodPatch, "/v2/languages/"+python.ID.Hex(), LanguagePatch{Disabled: &disabled}, true), http.StatusOK)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", valid, false), http.StatusBadRequest, CodeInvalidRequest)
+	disabled = false
+	expectStatus(t, s.json(http.MethodPatch, "/v2/languages/"+python.ID.Hex(), LanguagePatch{Disabled: &disabled}, true), http.StatusOK)
+
+	archived := true
+	expectStatus(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Archived: &archived}, true), http.StatusOK)
+	expectError(t, s.json(http.MethodPost, "/v2/submissions", SubmissionInput{QuestionID: question.ID.Hex(), LanguageID: python.ID.Hex(), Source: "x"}, false),
+		http.StatusNotFound, CodeNotFound)
+	archived = false
+	expectStatus(t, s.json(http.MethodPatch, "/v2/questions/"+question.ID.Hex(), QuestionPatch{Archived: &archived}, true), http.StatusOK)
+
+	// Nothing is stored while shutting down
+	atomic.StoreInt32(&s.draining, 1)
+	rec := s.json(http.MethodPost, "/v2/submissions", valid, false)
+	expectError(t, rec, http.StatusServiceUnavailable, CodeUnavailable)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("no Retry-After")
+	}
+	atomic.StoreInt32(&s.draining, 0)
+	submissions, err := s.Submissions.Find(context.Background(), SubmissionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submissions) != 0 {
+		t.Fatalf("refused submissions stored: %v", submissions)
+	}
+
+	expectStatus(t, s.json(http.MethodPost, "/v2/submissions", valid, false), http.StatusCreated)
+}
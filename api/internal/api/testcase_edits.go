@@ -0,0 +1,513 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Errors returned while editing single testcases
+var (
+	ErrNoSuchQuestion   = errors.New("no such question with this ID")
+	ErrNoSuchTestcase   = errors.New("no such testcase in this question")
+	ErrMissingTestcase  = errors.New("input or output file missing")
+	ErrInvalidReorder   = errors.New("order should list every testcase index exactly once")
+	ErrLastTestcaseLeft = errors.New("a question needs at least one testcase")
+	ErrTestcasesLocked  = errors.New("testcases of this question are being edited, try again")
+)
+
+// TestcasesResponse : Testcases of the question after an edit
+type TestcasesResponse struct {
+	Success   bool       `json:"success"`
+	Testcases []Testcase `json:"testcases"`
+}
+
+// testcaseList : Testcases of the question, filling in the ones of
+// questions uploaded before testcases were recorded individually
+func (q Question) testcaseList() []Testcase {
+	if len(q.Testcases) == q.NumTestcases {
+		return q.Testcases
+	}
+	testcases := make([]Testcase, q.NumTestcases)
+	for i := range testcases {
+		testcases[i] = Testcase{Index: i + 1, Number: i + 1}
+	}
+	return testcases
+}
+
+func (api *API) inputPath(ID primitive.ObjectID, index int) string {
+	return fmt.Sprintf("%sinput/input%d.txt", api.testcasesPath(ID), index)
+}
+
+func (api *API) outputPath(ID primitive.ObjectID, index int) string {
+	return fmt.Sprintf("%soutput/output%d.txt", api.testcasesPath(ID), index)
+}
+
+// findQuestion : Question with the hex ID given in the "id" form field
+func (api *API) findQuestion(r *http.Request) (Question, error) {
+	ID, err := parseID(r.FormValue("id"))
+	if err != nil {
+		return Question{}, err
+	}
+	return api.Questions.Get(r.Context(), ID)
+}
+
+// testcaseIndex : Index of a testcase given as text, checked against the
+// testcases of the question
+func testcaseIndex(value string, testcases []Testcase) (int, error) {
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 1 || index > len(testcases) {
+		return 0, ErrNoSuchTestcase
+	}
+	return index, nil
+}
+
+// Testcase locks are leases kept on the question in the database, so two
+// edits of the same question never interleave whichever replica of the API
+// they reach. The lock of a crashed edit is taken over once it expires.
+const (
+	testcaseLockTTL   = 2 * time.Minute
+	testcaseLockWait  = 10 * time.Second // Longest wait for an edit in progress
+	testcaseLockRetry = 100 * time.Millisecond
+)
+
+// lockTestcases : Waits for the lock on the testcases of the question and
+// returns its holder, which records the testcases, and its release
+func (api *API) lockTestcases(ctx context.Context, ID primitive.ObjectID) (string, func(), error) {
+	holder := primitive.NewObjectID().Hex()
+	deadline := time.Now().Add(testcaseLockWait)
+	for {
+		err := api.Questions.LockTestcases(ctx, ID, holder, time.Now().Add(testcaseLockTTL))
+		if err == nil {
+			break
+		}
+		if err != ErrTestcasesLocked || time.Now().After(deadline) {
+			return "", nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		case <-time.After(testcaseLockRetry):
+		}
+	}
+
+	return holder, func() {
+		// Released even when the request was cancelled meanwhile
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := api.Questions.UnlockTestcases(ctx, ID, holder); err != nil {
+			api.Log.Warn("Testcase lock not released, it expires on its own", zap.String("question", ID.Hex()), zap.Error(err))
+		}
+	}, nil
+}
+
+// testcaseStage : Next state of the testcase folder of a question, built
+// in a staging folder of its own next to it and swapped in whole
+type testcaseStage struct {
+	folder  string // Current folder, ending with a slash
+	staging string // Folder the next state is built in, ending with a slash
+}
+
+// stageTestcases : Empty staging folder for the testcases of the question
+func (api *API) stageTestcases(ID primitive.ObjectID) (*testcaseStage, error) {
+	if err := os.MkdirAll(api.Storage, os.ModePerm); err != nil {
+		return nil, err
+	}
+	// Named "<id>.staging-<random>", so the purge of the question finds it
+	staging, err := ioutil.TempDir(api.Storage, ID.Hex()+".staging-")
+	if err != nil {
+		return nil, err
+	}
+	stage := &testcaseStage{folder: api.testcasesPath(ID), staging: staging + "/"}
+	err = os.Chmod(staging, 0755)
+	if err == nil {
+		err = os.MkdirAll(stage.staging+"input/", os.ModePerm)
+	}
+	if err == nil {
+		err = os.MkdirAll(stage.staging+"output/", os.ModePerm)
+	}
+	if err != nil {
+		stage.discard()
+		return nil, err
+	}
+	return stage, nil
+}
+
+func (s *testcaseStage) inputPath(index int) string {
+	return fmt.Sprintf("%sinput/input%d.txt", s.staging, index)
+}
+
+func (s *testcaseStage) outputPath(index int) string {
+	return fmt.Sprintf("%soutput/output%d.txt", s.staging, index)
+}
+
+// keep : Puts the current files of the testcase at index from at index to
+// in the staging folder. They are linked rather than copied, files are
+// only ever replaced and never written in place.
+func (s *testcaseStage) keep(from int, to int) error {
+	if err := s.keepFile(fmt.Sprintf("%sinput/input%d.txt", s.folder, from), s.inputPath(to)); err != nil {
+		return err
+	}
+	return s.keepFile(fmt.Sprintf("%soutput/output%d.txt", s.folder, from), s.outputPath(to))
+}
+
+func (s *testcaseStage) keepFile(source string, target string) error {
+	if err := os.Link(source, target); err == nil {
+		return nil
+	}
+	// Copied instead where the storage cannot link
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// save : Writes the form file named field to target in the staging
+// folder, returning false when the form has no such file
+func (s *testcaseStage) save(r *http.Request, field string, target string, limits TestcaseLimits) (bool, error) {
+	file, _, err := r.FormFile(field)
+	if err == http.ErrMissingFile {
+		return false, nil
+	}
+	if err != nil {
+		return false, badRequest(err)
+	}
+	defer file.Close()
+
+	_, err = copyLimited(target, file, limits.MaxFileSize)
+	return err == nil, err
+}
+
+// discard : Drops the staging folder, leaving the testcases as they were
+func (s *testcaseStage) discard() {
+	os.RemoveAll(s.staging)
+}
+
+// commit : Swaps the staging folder in, then runs record, which stores
+// the staged testcases in the database. When record fails the previous
+// folder is swapped back, so the files always match what the database
+// holds.
+func (s *testcaseStage) commit(record func() error) error {
+	folder := strings.TrimSuffix(s.folder, "/")
+	staging := strings.TrimSuffix(s.staging, "/")
+	old := staging + ".old"
+
+	existed := true
+	if err := os.Rename(folder, old); err != nil {
+		if !os.IsNotExist(err) {
+			s.discard()
+			return err
+		}
+		existed = false
+	}
+	restore := func() {
+		if existed {
+			os.Rename(old, folder)
+		}
+	}
+	if err := os.Rename(staging, folder); err != nil {
+		restore()
+		s.discard()
+		return err
+	}
+
+	if err := record(); err != nil {
+		// The staged testcases go back to the staging folder to be dropped
+		os.Rename(folder, staging)
+		restore()
+		s.discard()
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// editTestcases : Stages the testcases edit builds from the question,
+// swaps them in and records them, holding the lock of the question so
+// edit sees the testcases the previous edit left
+func (api *API) editTestcases(ctx context.Context, ID primitive.ObjectID, edit func(Question, *testcaseStage) ([]Testcase, error)) ([]Testcase, error) {
+	holder, unlock, err := api.lockTestcases(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	question, err := api.Questions.Get(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	stage, err := api.stageTestcases(ID)
+	if err != nil {
+		return nil, err
+	}
+	testcases, err := edit(question, stage)
+	if err != nil {
+		stage.discard()
+		return nil, err
+	}
+	err = stage.commit(func() error {
+		return api.Questions.SetTestcases(ctx, ID, holder, testcases)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return testcases, nil
+}
+
+// addTestcase : Appends the testcase made of the "input" and "output"
+// form files, named after the "name" and "description" form fields
+func (api *API) addTestcase(r *http.Request, ID primitive.ObjectID) ([]Testcase, error) {
+	return api.editTestcases(r.Context(), ID, func(question Question, stage *testcaseStage) ([]Testcase, error) {
+		testcases := question.testcaseList()
+
+		// The new testcase goes last, numbered after every existing one
+		testcase := Testcase{
+			Index:       len(testcases) + 1,
+			Number:      1,
+			Name:        r.FormValue("name"),
+			Description: r.FormValue("description"),
+		}
+		for _, existing := range testcases {
+			if existing.Number >= testcase.Number {
+				testcase.Number = existing.Number + 1
+			}
+			if err := stage.keep(existing.Index, existing.Index); err != nil {
+				return nil, err
+			}
+		}
+
+		savedInput, err := stage.save(r, "input", stage.inputPath(testcase.Index), api.Limits)
+		if err == nil && !savedInput {
+			err = ErrMissingTestcase
+		}
+		if err != nil {
+			return nil, err
+		}
+		savedOutput, err := stage.save(r, "output", stage.outputPath(testcase.Index), api.Limits)
+		if err == nil && !savedOutput {
+			err = ErrMissingTestcase
+		}
+		if err != nil {
+			return nil, err
+		}
+		return append(testcases, testcase), nil
+	})
+}
+
+// replaceTestcase : Replaces the files sent as "input" or "output" and the
+// "name" or "description" sent of the testcase at the given index,
+// keeping whatever was left out
+func (api *API) replaceTestcase(r *http.Request, ID primitive.ObjectID, indexText string) ([]Testcase, error) {
+	return api.editTestcases(r.Context(), ID, func(question Question, stage *testcaseStage) ([]Testcase, error) {
+		testcases := question.testcaseList()
+		index, err := testcaseIndex(indexText, testcases)
+		if err != nil {
+			return nil, err
+		}
+
+		savedInput, err := stage.save(r, "input", stage.inputPath(index), api.Limits)
+		if err != nil {
+			return nil, err
+		}
+		savedOutput, err := stage.save(r, "output", stage.outputPath(index), api.Limits)
+		if err != nil {
+			return nil, err
+		}
+		name, hasName := r.Form["name"]
+		description, hasDescription := r.Form["description"]
+		if !savedInput && !savedOutput && !hasName && !hasDescription {
+			return nil, ErrMissingTestcase
+		}
+
+		for _, testcase := range testcases {
+			if testcase.Index != index {
+				err = stage.keep(testcase.Index, testcase.Index)
+			}
+			if err == nil && testcase.Index == index && !savedInput {
+				err = stage.keepFile(api.inputPath(ID, index), stage.inputPath(index))
+			}
+			if err == nil && testcase.Index == index && !savedOutput {
+				err = stage.keepFile(api.outputPath(ID, index), stage.outputPath(index))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if hasName {
+			testcases[index-1].Name = name[0]
+		}
+		if hasDescription {
+			testcases[index-1].Description = description[0]
+		}
+		return testcases, nil
+	})
+}
+
+// deleteTestcase : Removes the testcase at the given index, later
+// testcases move up by one to close the gap
+func (api *API) deleteTestcase(ctx context.Context, ID primitive.ObjectID, indexText string) ([]Testcase, error) {
+	return api.editTestcases(ctx, ID, func(question Question, stage *testcaseStage) ([]Testcase, error) {
+		testcases := question.testcaseList()
+		index, err := testcaseIndex(indexText, testcases)
+		if err != nil {
+			return nil, err
+		}
+		if len(testcases) == 1 {
+			return nil, ErrLastTestcaseLeft
+		}
+
+		testcases = append(testcases[:index-1], testcases[index:]...)
+		for i := range testcases {
+			if err = stage.keep(testcases[i].Index, i+1); err != nil {
+				return nil, err
+			}
+			testcases[i].Index = i + 1
+		}
+		return testcases, nil
+	})
+}
+
+// reorderTestcases : Puts the testcases in the order given by their
+// current indices, such as [3, 1, 2]
+func (api *API) reorderTestcases(ctx context.Context, ID primitive.ObjectID, order []int) ([]Testcase, error) {
+	return api.editTestcases(ctx, ID, func(question Question, stage *testcaseStage) ([]Testcase, error) {
+		testcases := question.testcaseList()
+		if len(order) != len(testcases) {
+			return nil, ErrInvalidReorder
+		}
+
+		seen := make(map[int]bool)
+		reordered := make([]Testcase, len(testcases))
+		for i, from := range order {
+			if seen[from] || from < 1 || from > len(testcases) {
+				return nil, ErrInvalidReorder
+			}
+			seen[from] = true
+			if err := stage.keep(from, i+1); err != nil {
+				return nil, err
+			}
+			reordered[i] = testcases[from-1]
+			reordered[i].Index = i + 1
+		}
+		return reordered, nil
+	})
+}
+
+func (api *API) addTestcaseHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2*api.Limits.MaxFileSize+maxFormOverhead)
+
+	ID, err := parseID(r.FormValue("id"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	testcases, err := api.addTestcase(r, ID)
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	api.rejudgeIfAsked(r, ID)
+
+	json.NewEncoder(w).Encode(TestcasesResponse{
+		Success:   true,
+		Testcases: testcases,
+	})
+}
+
+func (api *API) replaceTestcaseHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2*api.Limits.MaxFileSize+maxFormOverhead)
+
+	ID, err := parseID(r.FormValue("id"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	testcases, err := api.replaceTestcase(r, ID, r.FormValue("index"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	api.rejudgeIfAsked(r, ID)
+
+	json.NewEncoder(w).Encode(TestcasesResponse{
+		Success:   true,
+		Testcases: testcases,
+	})
+}
+
+func (api *API) deleteTestcaseHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := parseID(r.FormValue("id"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	testcases, err := api.deleteTestcase(r.Context(), ID, r.FormValue("index"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	api.rejudgeIfAsked(r, ID)
+
+	json.NewEncoder(w).Encode(TestcasesResponse{
+		Success:   true,
+		Testcases: testcases,
+	})
+}
+
+func (api *API) reorderTestcasesHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := parseID(r.FormValue("id"))
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	// Current indices in their new order, such as "3,1,2"
+	var order []int
+	for _, item := range strings.Split(r.FormValue("order"), ",") {
+		from, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			api.writeV1Error(w, r, ErrInvalidReorder)
+			return
+		}
+		order = append(order, from)
+	}
+
+	testcases, err := api.reorderTestcases(r.Context(), ID, order)
+	if err != nil {
+		api.writeV1Error(w, r, err)
+		return
+	}
+
+	api.rejudgeIfAsked(r, ID)
+
+	json.NewEncoder(w).Encode(TestcasesResponse{
+		Success:   true,
+		Testcases: testcases,
+	})
+}
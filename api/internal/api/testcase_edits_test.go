@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storageEntries : Names in the storage folder of the test server
+func (s *testServer) storageEntries() []string {
+	entries, err := ioutil.ReadDir(s.Storage)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestTestcaseCommitRollsBack(t *testing.T) {
+	s := newTestServer(t)
+	question := s.question("Echo", "1")
+
+	stage, err := s.stageTestcases(question.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(stage.inputPath(1), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("database down")
+	var seen string
+	err = stage.commit(func() error {
+		// Recorded once the new files are in place
+		content, _ := ioutil.ReadFile(s.inputPath(question.ID, 1))
+		seen = string(content)
+		return failed
+	})
+	if err != failed || seen != "new" {
+		t.Fatalf("commit gave %v with %q in place while recording", err, seen)
+	}
+
+	content, err := ioutil.ReadFile(s.inputPath(question.ID, 1))
+	if err != nil || string(content) != "1" {
+		t.Fatalf("input after the rollback %q, %v", content, err)
+	}
+	if entries := s.storageEntries(); len(entries) != 1 || entries[0] != question.ID.Hex() {
+		t.Fatalf("storage left with %v", entries)
+	}
+}
+
+func TestTestcaseStagesNeverClash(t *testing.T) {
+	s := newTestServer(t)
+	question := s.question("Echo", "1")
+
+	// A crashed edit leaves its staging folder behind, the next ones stage
+	// in folders of their own
+	first, err := s.stageTestcases(question.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.stageTestcases(question.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.staging == second.staging {
+		t.Fatalf("both edits staged in %s", first.staging)
+	}
+	if _, err = os.Stat(first.staging); err != nil {
+		t.Fatalf("first staging folder gone: %v", err)
+	}
+	second.discard()
+
+	// And the purge of the question removes whatever is left
+	if err = s.Questions.Delete(context.Background(), question.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.purgeStorage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if entries := s.storageEntries(); len(entries) != 0 {
+		t.Fatalf("storage left with %v", entries)
+	}
+}
+
+func TestTestcaseLock(t *testing.T) {
+	s := newTestServer(t)
+	question := s.question("Echo", "1")
+	ctx := context.Background()
+
+	if err := s.Questions.LockTestcases(ctx, question.ID, "first", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Questions.LockTestcases(ctx, question.ID, "second", time.Now().Add(time.Minute)); err != ErrTestcasesLocked {
+		t.Fatalf("second lock gave %v", err)
+	}
+	if err := s.Questions.SetTestcases(ctx, question.ID, "second", nil); err != ErrTestcasesLocked {
+		t.Fatalf("recorded without the lock with %v", err)
+	}
+
+	// An expired lock is taken over, and its holder can no longer record
+	if err := s.Questions.LockTestcases(ctx, question.ID, "first", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Questions.LockTestcases(ctx, question.ID, "second", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("expired lock not taken over: %v", err)
+	}
+	if err := s.Questions.SetTestcases(ctx, question.ID, "first", nil); err != ErrTestcasesLocked {
+		t.Fatalf("recorded with a lost lock with %v", err)
+	}
+	if err := s.Questions.UnlockTestcases(ctx, question.ID, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Questions.LockTestcases(ctx, question.ID, "third", time.Now().Add(time.Minute)); err != ErrTestcasesLocked {
+		t.Fatalf("released by a former holder, third lock gave %v", err)
+	}
+	if err := s.Questions.UnlockTestcases(ctx, question.ID, "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edits wait for the lock and release it
+	if _, err := s.editTestcases(ctx, question.ID, func(question Question, stage *testcaseStage) ([]Testcase, error) {
+		return question.testcaseList(), stage.keep(1, 1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.Questions.Get(ctx, question.ID)
+	if err != nil || stored.TestcaseLock != nil {
+		t.Fatalf("lock left as %+v, %v", stored.TestcaseLock, err)
+	}
+	if err = s.Questions.LockTestcases(ctx, primitive.NewObjectID(), "first", time.Now()); err != ErrNoSuchQuestion {
+		t.Fatalf("locked a missing question with %v", err)
+	}
+}
@@ -0,0 +1,375 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"judge-two/internal/judge"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors reported while validating the testcases of an archive
+var (
+	ErrUnexpectedEntry   = errors.New("entry is not a testcase file")
+	ErrDuplicateTestcase = errors.New("entry duplicates another testcase")
+	ErrNoTestcases       = errors.New("archive has no paired testcases")
+	ErrInvalidArchive    = errors.New("archive failed validation, see report")
+	ErrUnpairedInput     = errors.New("input has no matching output and is skipped")
+	ErrUnpairedOutput    = errors.New("output has no matching input and is skipped")
+	ErrInvalidManifest   = errors.New("manifest is not valid JSON")
+	ErrManifestUnknown   = errors.New("manifest lists a testcase missing from the archive")
+	ErrManifestDuplicate = errors.New("manifest lists a testcase more than once")
+	ErrUnlistedTestcase  = errors.New("testcase is not listed in the manifest and is placed last")
+)
+
+// testcaseLayout : Naming scheme of the testcase files inside an archive
+type testcaseLayout = judge.Layout
+
+// testcaseLayouts : Layouts recognised in archives, shared with judgectl
+var testcaseLayouts = judge.Layouts
+
+// testcaseArchive : Validated archive of testcases, paired by testcase number
+type testcaseArchive struct {
+	*archiveReader
+	limits    TestcaseLimits
+	layout    testcaseLayout
+	root      string // Wrapper folder the testcases sit in, if any
+	inputs    map[int]*archiveEntry
+	outputs   map[int]*archiveEntry
+	manifest  *archiveEntry
+	metadata  testcaseManifest // Content of the manifest, if any
+	testcases []Testcase       // Paired testcases in their final order
+	report    ArchiveReport
+}
+
+// testcaseManifest : Optional manifest.json next to the testcases
+type testcaseManifest = judge.Manifest
+
+// manifestTestcase : Single testcase listed in a manifest
+type manifestTestcase = judge.ManifestTestcase
+
+// manifestFile : Name of the manifest inside an archive
+const manifestFile = judge.ManifestFile
+
+// testcasesPath : Folder holding the testcases of a question
+func (api *API) testcasesPath(ID primitive.ObjectID) string {
+	return fmt.Sprintf("%s%s/", api.Storage, ID.Hex())
+}
+
+// ArchiveIssue : Problem found with one entry of a testcase archive,
+// Entry is empty when the problem concerns the archive as a whole
+type ArchiveIssue struct {
+	Entry  string `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// ArchiveReport : Outcome of validating a testcase archive
+type ArchiveReport struct {
+	Valid     bool           `json:"valid"`
+	Layout    string         `json:"layout"`
+	Root      string         `json:"root,omitempty"`
+	Testcases int            `json:"testcases"`
+	Mapping   []Testcase     `json:"mapping"`
+	Errors    []ArchiveIssue `json:"errors"`
+	Warnings  []ArchiveIssue `json:"warnings"`
+}
+
+func (r *ArchiveReport) addError(entry string, err error) {
+	r.Errors = append(r.Errors, ArchiveIssue{Entry: entry, Reason: err.Error()})
+}
+
+func (r *ArchiveReport) addWarning(entry string, err error) {
+	r.Warnings = append(r.Warnings, ArchiveIssue{Entry: entry, Reason: err.Error()})
+}
+
+// openTestcaseArchive : Opens the archive at filePath and validates every
+// entry. An archive is returned whenever the file itself could be read,
+// its report tells whether the testcases can be used.
+func openTestcaseArchive(filePath string, format archiveFormat, limits TestcaseLimits) (*testcaseArchive, error) {
+	reader, err := openArchive(filePath, format, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &testcaseArchive{
+		archiveReader: reader,
+		limits:        limits,
+		inputs:        make(map[int]*archiveEntry),
+		outputs:       make(map[int]*archiveEntry),
+	}
+	archive.validate()
+
+	return archive, nil
+}
+
+// detectLayout : Finds the single wrapper folder shared by every entry,
+// if any, and the layout of the testcases inside it
+func (a *testcaseArchive) detectLayout() {
+	for {
+		top := ""
+		wrapped := true
+		for _, entry := range a.entries {
+			rel := strings.TrimPrefix(entry.Name, a.root)
+			slash := strings.Index(rel, "/")
+			if rel == "" {
+				continue
+			}
+			if slash == -1 || (top != "" && rel[:slash] != top) {
+				wrapped = false
+				break
+			}
+			top = rel[:slash]
+		}
+		if !wrapped || top == "" || top == "input" || top == "output" {
+			break
+		}
+		a.root += top + "/"
+	}
+
+	a.layout = testcaseLayouts[0]
+	for _, layout := range testcaseLayouts {
+		for _, entry := range a.entries {
+			rel := strings.TrimPrefix(entry.Name, a.root)
+			if layout.Input.MatchString(rel) || layout.Output.MatchString(rel) {
+				a.layout = layout
+				return
+			}
+		}
+	}
+}
+
+// validate : Fills the report with every problem found instead of
+// stopping at the first one, so setters can fix an archive in one go
+func (a *testcaseArchive) validate() {
+	report := ArchiveReport{
+		Errors:   []ArchiveIssue{},
+		Warnings: []ArchiveIssue{},
+	}
+
+	if len(a.entries) > a.limits.MaxEntries {
+		report.addError("", ErrTooManyEntries)
+	}
+	for _, problem := range a.problems {
+		report.addError("", problem)
+	}
+
+	// Names are checked before anything is derived from them
+	entries := a.entries[:0:0]
+	for _, entry := range a.entries {
+		if _, err := sanitizeEntryName(entry.Name); err != nil {
+			report.addError(entry.Name, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	a.entries = entries
+	a.detectLayout()
+
+	var total uint64
+	var outputOrder []int
+	for _, entry := range a.entries {
+		name := entry.Name
+		rel := strings.TrimPrefix(name, a.root)
+		if entry.Dir {
+			continue
+		}
+		if !entry.Regular {
+			report.addError(name, ErrNotRegularFile)
+			continue
+		}
+		if err := checkEntrySize(entry, a.limits); err != nil {
+			report.addError(name, err)
+			continue
+		}
+		total += entry.Size
+
+		if rel == manifestFile {
+			a.manifest = entry
+		} else if match := a.layout.Input.FindStringSubmatch(rel); match != nil {
+			fileNumber, err := strconv.Atoi(match[1])
+			if err != nil {
+				report.addError(name, ErrUnexpectedEntry)
+				continue
+			}
+			if _, ok := a.inputs[fileNumber]; ok {
+				report.addError(name, ErrDuplicateTestcase)
+				continue
+			}
+			a.inputs[fileNumber] = entry
+		} else if match := a.layout.Output.FindStringSubmatch(rel); match != nil {
+			fileNumber, err := strconv.Atoi(match[1])
+			if err != nil {
+				report.addError(name, ErrUnexpectedEntry)
+				continue
+			}
+			if _, ok := a.outputs[fileNumber]; ok {
+				report.addError(name, ErrDuplicateTestcase)
+				continue
+			}
+			a.outputs[fileNumber] = entry
+			outputOrder = append(outputOrder, fileNumber)
+		} else {
+			report.addError(name, ErrUnexpectedEntry)
+		}
+	}
+	if total > uint64(a.limits.MaxTotalSize) {
+		report.addError("", ErrTotalTooLarge)
+	}
+
+	var paired []int
+	for fileNumber, entry := range a.inputs {
+		if _, ok := a.outputs[fileNumber]; ok {
+			paired = append(paired, fileNumber)
+		} else {
+			report.addWarning(entry.Name, ErrUnpairedInput)
+		}
+	}
+	for _, fileNumber := range outputOrder {
+		if _, ok := a.inputs[fileNumber]; !ok {
+			report.addWarning(a.outputs[fileNumber].Name, ErrUnpairedOutput)
+		}
+	}
+	sort.Ints(paired)
+	sort.Slice(report.Warnings, func(i, j int) bool {
+		return naturalLess(report.Warnings[i].Entry, report.Warnings[j].Entry)
+	})
+
+	a.testcases = a.orderTestcases(paired, &report)
+	if len(report.Errors) == 0 && len(a.testcases) == 0 {
+		report.addError("", ErrNoTestcases)
+	}
+
+	report.Valid = len(report.Errors) == 0
+	report.Layout = a.layout.Name
+	report.Root = a.root
+	report.Testcases = len(a.testcases)
+	report.Mapping = a.testcases
+	a.report = report
+}
+
+// naturalLess : Orders names with their digit runs compared as numbers,
+// so input2 comes before input10. Names only apart by leading zeros
+// fall back to plain order.
+func naturalLess(a string, b string) bool {
+	x, y := a, b
+	for len(x) > 0 && len(y) > 0 {
+		dx, dy := leadingDigits(x), leadingDigits(y)
+		if len(dx) > 0 && len(dy) > 0 {
+			nx, ny := strings.TrimLeft(dx, "0"), strings.TrimLeft(dy, "0")
+			if len(nx) != len(ny) {
+				return len(nx) < len(ny)
+			}
+			if nx != ny {
+				return nx < ny
+			}
+			x, y = x[len(dx):], y[len(dy):]
+			continue
+		}
+		if x[0] != y[0] {
+			return x[0] < y[0]
+		}
+		x, y = x[1:], y[1:]
+	}
+	if len(x) != len(y) {
+		return len(x) < len(y)
+	}
+	return a < b
+}
+
+// leadingDigits : Run of digits s starts with
+func leadingDigits(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
+
+// orderTestcases : Numbers the paired testcases from 1, following the
+// manifest when there is one and the original numbers otherwise
+func (a *testcaseArchive) orderTestcases(paired []int, report *ArchiveReport) []Testcase {
+	testcases := []Testcase{}
+	if a.manifest == nil {
+		for i, fileNumber := range paired {
+			testcases = append(testcases, Testcase{Index: i + 1, Number: fileNumber})
+		}
+		return testcases
+	}
+
+	var manifest testcaseManifest
+	src, err := a.manifest.Open()
+	if err != nil {
+		report.addError(a.manifest.Name, err)
+		return testcases
+	}
+	defer src.Close()
+	if err = json.NewDecoder(io.LimitReader(src, a.limits.MaxFileSize)).Decode(&manifest); err != nil {
+		report.addError(a.manifest.Name, ErrInvalidManifest)
+		return testcases
+	}
+	a.metadata = manifest
+
+	listed := make(map[int]bool)
+	for _, entry := range manifest.Testcases {
+		if listed[entry.Number] {
+			report.addError(a.manifest.Name, fmt.Errorf("%w: %d", ErrManifestDuplicate, entry.Number))
+			continue
+		}
+		listed[entry.Number] = true
+		_, oki := a.inputs[entry.Number]
+		_, oko := a.outputs[entry.Number]
+		if !oki || !oko {
+			report.addError(a.manifest.Name, fmt.Errorf("%w: %d", ErrManifestUnknown, entry.Number))
+			continue
+		}
+		testcases = append(testcases, Testcase{
+			Index:       len(testcases) + 1,
+			Number:      entry.Number,
+			Name:        entry.Name,
+			Description: entry.Description,
+		})
+	}
+	for _, fileNumber := range paired {
+		if !listed[fileNumber] {
+			report.addWarning(a.inputs[fileNumber].Name, ErrUnlistedTestcase)
+			testcases = append(testcases, Testcase{Index: len(testcases) + 1, Number: fileNumber})
+		}
+	}
+
+	return testcases
+}
+
+// extract : Writes the paired testcases into folderPath as
+// input/inputN.txt and output/outputN.txt, N being the display index
+func (a *testcaseArchive) extract(folderPath string) ([]Testcase, error) {
+	if err := os.MkdirAll(folderPath+"input/", os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(folderPath+"output/", os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	// Header sizes can lie, so the copied bytes are counted again
+	remaining := a.limits.MaxTotalSize
+	for _, testcase := range a.testcases {
+		n, err := copyEntry(fmt.Sprintf("%sinput/input%d.txt", folderPath, testcase.Index), a.inputs[testcase.Number], a.limits.MaxFileSize, remaining)
+		if err != nil {
+			return nil, err
+		}
+		remaining -= n
+		n, err = copyEntry(fmt.Sprintf("%soutput/output%d.txt", folderPath, testcase.Index), a.outputs[testcase.Number], a.limits.MaxFileSize, remaining)
+		if err != nil {
+			return nil, err
+		}
+		remaining -= n
+	}
+
+	return a.testcases, nil
+}
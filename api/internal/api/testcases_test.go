@@ -0,0 +1,137 @@
+package api
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"input/input10.txt", "input/input2.txt", "output/output1.txt", "input/input02.txt", "input/input1.txt", "input/input.txt"}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+	expected := []string{"input/input.txt", "input/input1.txt", "input/input02.txt", "input/input2.txt", "input/input10.txt", "output/output1.txt"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("sorted to %v, expected %v", names, expected)
+		}
+	}
+}
+
+func TestArchiveLayouts(t *testing.T) {
+	standard := func(input bool, n int) string {
+		if input {
+			return "input/input" + strconv.Itoa(n) + ".txt"
+		}
+		return "output/output" + strconv.Itoa(n) + ".txt"
+	}
+	flat := func(input bool, n int) string {
+		if input {
+			return "0" + strconv.Itoa(n) + ".in"
+		}
+		if n%2 == 0 {
+			return "0" + strconv.Itoa(n) + ".ans"
+		}
+		return "0" + strconv.Itoa(n) + ".out"
+	}
+
+	cases := []struct {
+		Name    string
+		Layout  string
+		Root    string
+		Folders []string // Wrapper folders listed as entries of their own
+		Path    func(input bool, n int) string
+	}{
+		{Name: "standard", Layout: "standard", Path: standard},
+		{Name: "flat", Layout: "flat", Path: flat},
+		{Name: "wrapped standard", Layout: "standard", Root: "echo/", Folders: []string{"echo/"}, Path: standard},
+		{Name: "wrapped flat", Layout: "flat", Root: "echo/tests/", Folders: []string{"echo/", "echo/tests/"}, Path: flat},
+		{Name: "wrapped without folder entries", Layout: "flat", Root: "echo/", Path: flat},
+	}
+
+	for _, format := range []archiveFormat{formatZip, formatTarGz, formatTarXz} {
+		for _, c := range cases {
+			var files []archiveFile
+			for _, folder := range c.Folders {
+				files = append(files, archiveFile{Name: folder, Type: tar.TypeDir, Mode: os.ModeDir | 0755})
+			}
+			for _, n := range []int{10, 2, 1} {
+				number := strconv.Itoa(n)
+				files = append(files,
+					archiveFile{Name: c.Root + c.Path(true, n), Body: "in " + number},
+					archiveFile{Name: c.Root + c.Path(false, n), Body: "out " + number},
+				)
+			}
+
+			archive, err := openBytes(t, archiveOf(t, format, files...), format, testLimits())
+			if err != nil {
+				t.Fatalf("%s %s: %v", format, c.Name, err)
+			}
+			report := archive.report
+			if !report.Valid || report.Layout != c.Layout || report.Root != c.Root || report.Testcases != 3 {
+				archive.Close()
+				t.Fatalf("%s %s reported %+v", format, c.Name, report)
+			}
+			expectExtracted(t, archive, []int{1, 2, 10})
+			archive.Close()
+		}
+	}
+}
+
+func TestArchiveLayoutsWarnUnpaired(t *testing.T) {
+	files := []archiveFile{{Name: "1.in", Body: "in 1"}, {Name: "1.out", Body: "out 1"}, {Name: "2.in", Body: "in 2"}, {Name: "3.ans", Body: "out 3"}}
+	for _, format := range []archiveFormat{formatZip, formatTarGz, formatTarXz} {
+		archive, err := openBytes(t, archiveOf(t, format, files...), format, testLimits())
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := archive.report
+		if !report.Valid || report.Layout != "flat" || report.Testcases != 1 || len(report.Warnings) != 2 {
+			archive.Close()
+			t.Fatalf("%s reported %+v", format, report)
+		}
+		expectExtracted(t, archive, []int{1})
+		archive.Close()
+	}
+}
+
+// expectExtracted : Extracts the archive and fails the test unless the
+// testcases numbered as given come out as input/inputN.txt and
+// output/outputN.txt, N following their natural order
+func expectExtracted(t *testing.T, archive *testcaseArchive, numbers []int) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "layout-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testcases, err := archive.extract(dir + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(testcases) != len(numbers) {
+		t.Fatalf("extracted %+v, expected %v", testcases, numbers)
+	}
+	for i, testcase := range testcases {
+		if testcase.Index != i+1 || testcase.Number != numbers[i] {
+			t.Fatalf("extracted %+v, expected %v", testcases, numbers)
+		}
+		number := strconv.Itoa(testcase.Number)
+		for path, expected := range map[string]string{
+			filepath.Join(dir, "input", "input"+strconv.Itoa(testcase.Index)+".txt"):   "in " + number,
+			filepath.Join(dir, "output", "output"+strconv.Itoa(testcase.Index)+".txt"): "out " + number,
+		} {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != expected {
+				t.Fatalf("%s holds %q, expected %q", path, content, expected)
+			}
+		}
+	}
+}
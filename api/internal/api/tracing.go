@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"judge-two/internal/tracing"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// Names the API and the workers report their spans under
+const (
+	serviceName       = "judge-api"
+	workerServiceName = "judge-worker"
+)
+
+func (api *API) mountTracing(service string) {
+	stop, err := tracing.Setup(api.Config.Tracing, service)
+	if err != nil {
+		api.Log.Error("Tracing setup failed")
+		panic(err)
+	}
+	api.stopTracing = stop
+}
+
+// mongoSpans : Spans of the MongoDB commands in flight, by request ID
+var mongoSpans sync.Map
+
+// startMongoSpan : Span for a command, child of the span of the request
+// that sent it
+func startMongoSpan(ctx context.Context, e *event.CommandStartedEvent) {
+	_, span := tracing.Tracer().Start(ctx, "mongodb."+e.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMongodb,
+			semconv.DBNameKey.String(e.DatabaseName),
+			semconv.DBOperationKey.String(e.CommandName),
+		),
+	)
+	mongoSpans.Store(e.RequestID, span)
+}
+
+// endMongoSpan : Ends the span of a command once it replied
+func endMongoSpan(ctx context.Context, requestID int64, failure string) {
+	value, ok := mongoSpans.Load(requestID)
+	if !ok {
+		return
+	}
+	mongoSpans.Delete(requestID)
+
+	span := value.(trace.Span)
+	if len(failure) > 0 {
+		span.SetStatus(codes.Error, failure)
+	}
+	span.End()
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LanguagesResponse : Every language
+type LanguagesResponse struct {
+	Languages []Language `json:"languages"`
+}
+
+// RejudgeCreatedResponse : Rejudge started by POST /v2/rejudges
+type RejudgeCreatedResponse struct {
+	ID          string `json:"id"`
+	Submissions int    `json:"submissions"`
+}
+
+// mountV2 : Resource oriented routes with proper status codes and
+// ErrorResponse bodies. The v1 routes share the same operations.
+func (api *API) mountV2() {
+	v2 := api.Router.PathPrefix("/v2").Subrouter()
+	v2.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: &APIError{Code: CodeNotFound, Message: "no such route"}})
+	})
+	v2.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: &APIError{Code: CodeInvalidRequest, Message: "method not allowed"}})
+	})
+
+	// Languages
+	v2.HandleFunc("/languages", api.requireAdminV2(api.listDeletedLanguagesV2)).Methods("GET").Queries("deleted", "true")
+	v2.HandleFunc("/languages", api.listLanguagesV2).Methods("GET")
+	v2.HandleFunc("/languages", api.requireAdminV2(api.createLanguageV2)).Methods("POST")
+	v2.HandleFunc("/languages/{id}", api.getLanguageV2).Methods("GET")
+	v2.HandleFunc("/languages/{id}", api.requireAdminV2(api.updateLanguageV2)).Methods("PATCH")
+	v2.HandleFunc("/languages/{id}", api.requireAdminV2(api.purgeLanguageV2)).Methods("DELETE").Queries("hard", "true")
+	v2.HandleFunc("/languages/{id}", api.requireAdminV2(api.deleteLanguageV2)).Methods("DELETE")
+	v2.HandleFunc("/languages/{id}/restore", api.requireAdminV2(api.restoreLanguageV2)).Methods("POST")
+	v2.HandleFunc("/languages/{id}/self-test", api.requireAdminV2(api.selfTestLanguageV2)).Methods("POST")
+
+	// Questions
+	v2.HandleFunc("/questions", api.requireAdminV2(api.listDeletedQuestionsV2)).Methods("GET").Queries("deleted", "true")
+	v2.HandleFunc("/questions", api.listQuestionsV2).Methods("GET")
+	v2.HandleFunc("/questions", api.createQuestionV2).Methods("POST")
+	v2.HandleFunc("/questions/{id}", api.getQuestionV2).Methods("GET")
+	v2.HandleFunc("/questions/{id}", api.requireAdminV2(api.updateQuestionV2)).Methods("PATCH")
+	v2.HandleFunc("/questions/{id}", api.requireAdminV2(api.purgeQuestionV2)).Methods("DELETE").Queries("hard", "true")
+	v2.HandleFunc("/questions/{id}", api.requireAdminV2(api.deleteQuestionV2)).Methods("DELETE")
+	v2.HandleFunc("/questions/{id}/restore", api.requireAdminV2(api.restoreQuestionV2)).Methods("POST")
+	v2.HandleFunc("/questions/{id}/languages", api.listQuestionLanguagesV2).Methods("GET")
+	v2.HandleFunc("/questions/{id}/languages/{language}", api.getQuestionLanguageV2).Methods("GET")
+	v2.HandleFunc("/questions/{id}/export", api.requireAdminV2(api.exportQuestionV2)).Methods("GET")
+
+	// Testcases
+	v2.HandleFunc("/questions/{id}/testcases", api.requireAdminV2(api.replaceTestcasesV2)).Methods("PUT")
+	v2.HandleFunc("/questions/{id}/testcases", api.requireAdminV2(api.addTestcaseV2)).Methods("POST")
+	v2.HandleFunc("/questions/{id}/testcases/order", api.requireAdminV2(api.reorderTestcasesV2)).Methods("PUT")
+	v2.HandleFunc("/questions/{id}/testcases/{index:[0-9]+}", api.requireAdminV2(api.updateTestcaseV2)).Methods("PATCH")
+	v2.HandleFunc("/questions/{id}/testcases/{index:[0-9]+}", api.requireAdminV2(api.deleteTestcaseV2)).Methods("DELETE")
+	v2.HandleFunc("/questions/{id}/testcases/{index:[0-9]+}/{file:input|output}", api.requireAdminV2(api.downloadTestcaseV2)).Methods("GET")
+
+	// Contests
+	v2.HandleFunc("/contests", api.listContestsV2).Methods("GET")
+	v2.HandleFunc("/contests", api.requireAdminV2(api.createContestV2)).Methods("POST")
+	v2.HandleFunc("/contests/{id}", api.getContestV2).Methods("GET")
+	v2.HandleFunc("/contests/{id}", api.requireAdminV2(api.updateContestV2)).Methods("PATCH")
+	v2.HandleFunc("/contests/{id}", api.requireAdminV2(api.deleteContestV2)).Methods("DELETE")
+
+	// Submissions
+	v2.HandleFunc("/submissions", api.createSubmissionV2).Methods("POST")
+	v2.HandleFunc("/submissions/{id}", api.getSubmissionV2).Methods("GET")
+
+	// Rejudges
+	v2.HandleFunc("/rejudges", api.requireAdminV2(api.createRejudgeV2)).Methods("POST")
+	v2.HandleFunc("/rejudges/{id}", api.requireAdminV2(api.getRejudgeV2)).Methods("GET")
+
+	// Workers
+	v2.HandleFunc("/workers", api.requireAdminV2(api.listWorkersV2)).Methods("GET")
+}
+
+// pathID : ID given in the {id} part of the route
+func pathID(r *http.Request) (primitive.ObjectID, error) {
+	return parseID(mux.Vars(r)["id"])
+}
+
+// decodeJSON : Reads the JSON body of the request into target, refusing
+// fields target does not have
+func decodeJSON(r *http.Request, target interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return badRequest(err)
+	}
+	return nil
+}
+
+// isDryRun : Whether the "dryRun" query or form field is set
+func isDryRun(r *http.Request) bool {
+	value, _ := strconv.ParseBool(r.FormValue("dryRun"))
+	return value
+}
+
+func (api *API) listLanguagesV2(w http.ResponseWriter, r *http.Request) {
+	languages, err := api.Languages.List(r.Context(), api.requestVisibility(r))
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, LanguagesResponse{Languages: languages})
+}
+
+func (api *API) createLanguageV2(w http.ResponseWriter, r *http.Request) {
+	var reqBody LanguageInput
+	if err := decodeJSON(r, &reqBody); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	language, err := api.createLanguage(r.Context(), reqBody)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v2/languages/"+language.ID.Hex())
+	writeJSON(w, http.StatusCreated, language)
+}
+
+func (api *API) getLanguageV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	language, err := api.Languages.Get(r.Context(), ID)
+	if err == nil && language.Archived && api.requestVisibility(r) == visibleToContestants {
+		err = ErrNoSuchLanguage
+	}
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, language)
+}
+
+func (api *API) updateLanguageV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	var patch LanguagePatch
+	if err = decodeJSON(r, &patch); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	language, err := api.updateLanguage(r.Context(), ID, patch)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, language)
+}
+
+func (api *API) deleteLanguageV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.Languages.SoftDelete(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) createRejudgeV2(w http.ResponseWriter, r *http.Request) {
+	var reqBody RejudgeRequest
+	if err := decodeJSON(r, &reqBody); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	if err := reqBody.validate(); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	rejudge, err := api.rejudge(r.Context(), reqBody)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	// Verdicts come in as workers get to the queued submissions
+	w.Header().Set("Location", "/v2/rejudges/"+rejudge.ID.Hex())
+	writeJSON(w, http.StatusAccepted, RejudgeCreatedResponse{
+		ID:          rejudge.ID.Hex(),
+		Submissions: len(rejudge.Submissions),
+	})
+}
+
+func (api *API) getRejudgeV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	response, err := api.rejudgeStatus(r.Context(), ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// QuestionsResponse : Every question
+type QuestionsResponse struct {
+	Questions []Question `json:"questions"`
+}
+
+// QuestionCreatedResponse : Question made from an archive, left out on
+// dry runs, along with the validation report of the archive
+type QuestionCreatedResponse struct {
+	Question *Question      `json:"question,omitempty"`
+	Report   *ArchiveReport `json:"report"`
+}
+
+// TestcaseListResponse : Testcases of a question after an edit
+type TestcaseListResponse struct {
+	Testcases []Testcase     `json:"testcases"`
+	Report    *ArchiveReport `json:"report,omitempty"`
+}
+
+// ReorderRequest : Current testcase indices in their new order
+type ReorderRequest struct {
+	Order []int `json:"order"`
+}
+
+// pathQuestion : Question given in the {id} part of the route
+func (api *API) pathQuestion(r *http.Request) (Question, error) {
+	ID, err := pathID(r)
+	if err != nil {
+		return Question{}, err
+	}
+	return api.Questions.Get(r.Context(), ID)
+}
+
+func (api *API) listQuestionsV2(w http.ResponseWriter, r *http.Request) {
+	questions, err := api.Questions.List(r.Context(), api.requestVisibility(r))
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, QuestionsResponse{Questions: questions})
+}
+
+func (api *API) createQuestionV2(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, api.Limits.MaxArchiveSize+maxFormOverhead)
+
+	dryRun := isDryRun(r)
+	question, report, err := api.createQuestion(r, dryRun)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if dryRun {
+		writeJSON(w, http.StatusOK, QuestionCreatedResponse{Report: report})
+		return
+	}
+	w.Header().Set("Location", "/v2/questions/"+question.ID.Hex())
+	writeJSON(w, http.StatusCreated, QuestionCreatedResponse{
+		Question: &question,
+		Report:   report,
+	})
+}
+
+func (api *API) getQuestionV2(w http.ResponseWriter, r *http.Request) {
+	question, err := api.visibleQuestion(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	question.Testcases = question.testcaseList()
+	writeJSON(w, http.StatusOK, question)
+}
+
+func (api *API) updateQuestionV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	var patch QuestionPatch
+	if err = decodeJSON(r, &patch); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	question, err := api.updateQuestion(r.Context(), ID, patch)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, question)
+}
+
+func (api *API) deleteQuestionV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.Questions.SoftDelete(r.Context(), ID); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) exportQuestionV2(w http.ResponseWriter, r *http.Request) {
+	question, err := api.pathQuestion(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	api.sendQuestionExport(w, r, question)
+}
+
+func (api *API) replaceTestcasesV2(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, api.Limits.MaxArchiveSize+maxFormOverhead)
+
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	dryRun := isDryRun(r)
+	report, testcases, err := api.replaceQuestionTestcases(r, ID, dryRun)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	if !dryRun {
+		api.rejudgeIfAsked(r, ID)
+	}
+
+	writeJSON(w, http.StatusOK, TestcaseListResponse{
+		Testcases: testcases,
+		Report:    report,
+	})
+}
+
+func (api *API) addTestcaseV2(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2*api.Limits.MaxFileSize+maxFormOverhead)
+
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	testcases, err := api.addTestcase(r, ID)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	api.rejudgeIfAsked(r, ID)
+
+	writeJSON(w, http.StatusCreated, TestcaseListResponse{Testcases: testcases})
+}
+
+func (api *API) updateTestcaseV2(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2*api.Limits.MaxFileSize+maxFormOverhead)
+
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	testcases, err := api.replaceTestcase(r, ID, mux.Vars(r)["index"])
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	api.rejudgeIfAsked(r, ID)
+
+	writeJSON(w, http.StatusOK, TestcaseListResponse{Testcases: testcases})
+}
+
+func (api *API) deleteTestcaseV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	testcases, err := api.deleteTestcase(r.Context(), ID, mux.Vars(r)["index"])
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	api.rejudgeIfAsked(r, ID)
+
+	writeJSON(w, http.StatusOK, TestcaseListResponse{Testcases: testcases})
+}
+
+func (api *API) reorderTestcasesV2(w http.ResponseWriter, r *http.Request) {
+	ID, err := pathID(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	var reqBody ReorderRequest
+	if err = decodeJSON(r, &reqBody); err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	testcases, err := api.reorderTestcases(r.Context(), ID, reqBody.Order)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	api.rejudgeIfAsked(r, ID)
+
+	writeJSON(w, http.StatusOK, TestcaseListResponse{Testcases: testcases})
+}
+
+func (api *API) downloadTestcaseV2(w http.ResponseWriter, r *http.Request) {
+	question, err := api.pathQuestion(r)
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	index, err := testcaseIndex(mux.Vars(r)["index"], question.testcaseList())
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+
+	if err = api.sendTestcaseFile(w, r, question, index, mux.Vars(r)["file"]); err != nil {
+		api.writeError(w, r, err)
+	}
+}
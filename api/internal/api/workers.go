@@ -0,0 +1,365 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"judge-two/internal/judge"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// errNotAdvertised : Worker whose advertisement is not recorded
+var errNotAdvertised = errors.New("languages of the worker are not advertised")
+
+// missedHeartbeats : Heartbeats a worker may miss before it is considered
+// gone and its claimed jobs are put back in the queue
+const missedHeartbeats = 3
+
+// LanguageCoverage : Live workers able to run a language
+type LanguageCoverage struct {
+	ID       primitive.ObjectID `json:"id"`
+	Name     string             `json:"name"`
+	Disabled bool               `json:"disabled"`
+	Probe    bool               `json:"probe"` // Languages without a probe cannot be verified, so no worker runs them
+	Workers  []string           `json:"workers"`
+	Failing  []string           `json:"failing"` // Live workers the probe failed on
+}
+
+// WorkersResponse : Live workers and the languages they cover
+type WorkersResponse struct {
+	Workers   []Worker           `json:"workers"`
+	Languages []LanguageCoverage `json:"languages"`
+	Uncovered []LanguageCoverage `json:"uncovered"` // Languages no live worker can run
+}
+
+// capabilities : Fingerprint of each language a worker self-tested, so
+// languages edited since get tested again
+type capabilities map[primitive.ObjectID][sha256.Size]byte
+
+// fingerprint : Digest of everything a self-test depends on
+func (l Language) fingerprint() [sha256.Size]byte {
+	definition, _ := json.Marshal(struct {
+		Language judge.Language
+		Probe    *LanguageProbe
+	}{l.runnerLanguage(), l.Probe})
+	return sha256.Sum256(definition)
+}
+
+// discoverLanguages : Self-tests on this worker every language with a
+// probe that is new, changed or sent back to testing since the last call,
+// and returns the IDs of the languages that passed and of those that
+// failed. Passes are recorded on the language, failures only while no
+// worker recorded anything, so a worker lacking a compiler does not hide
+// that others have it. A failure only keeps the language off this worker.
+func (api *API) discoverLanguages(ctx context.Context, tested capabilities, passed map[primitive.ObjectID]bool) ([]primitive.ObjectID, []primitive.ObjectID, error) {
+	languages, err := api.Languages.List(ctx, visibleToAdmins)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	IDs := []primitive.ObjectID{}
+	failed := []primitive.ObjectID{}
+	seen := make(map[primitive.ObjectID]bool)
+	for _, language := range languages {
+		seen[language.ID] = true
+		if language.Probe == nil {
+			delete(tested, language.ID)
+			delete(passed, language.ID)
+			continue
+		}
+		fingerprint := language.fingerprint()
+		if tested[language.ID] != fingerprint || language.SelfTest == nil {
+			test := api.selfTest(ctx, language)
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			if test.Passed || language.SelfTest == nil {
+				if _, err = api.Languages.SetSelfTest(ctx, language.ID, test); err != nil {
+					return nil, nil, err
+				}
+			}
+			tested[language.ID] = fingerprint
+			passed[language.ID] = test.Passed
+		}
+		if passed[language.ID] {
+			IDs = append(IDs, language.ID)
+		} else {
+			failed = append(failed, language.ID)
+		}
+	}
+	for ID := range tested {
+		if !seen[ID] {
+			delete(tested, ID)
+			delete(passed, ID)
+		}
+	}
+	return IDs, failed, nil
+}
+
+// heartbeat : Records the advertisement of the worker, valid until it
+// misses a few heartbeats
+func (api *API) heartbeat(ctx context.Context, worker *Worker, interval time.Duration) error {
+	worker.HeartbeatAt = time.Now().UTC()
+	worker.ExpiresAt = worker.HeartbeatAt.Add(missedHeartbeats * interval)
+	return api.Workers.Heartbeat(ctx, *worker)
+}
+
+// liveWorkers : Workers whose last heartbeat has not expired yet
+func (api *API) liveWorkers(ctx context.Context) ([]Worker, error) {
+	return api.Workers.Live(ctx, time.Now().UTC())
+}
+
+// workerCoverage : Live workers and, for every language, which of them
+// can run it
+func (api *API) workerCoverage(ctx context.Context) (WorkersResponse, error) {
+	response := WorkersResponse{Languages: []LanguageCoverage{}, Uncovered: []LanguageCoverage{}}
+	workers, err := api.liveWorkers(ctx)
+	if err != nil {
+		return response, err
+	}
+	response.Workers = workers
+	languages, err := api.Languages.List(ctx, visibleToAdmins)
+	if err != nil {
+		return response, err
+	}
+
+	capable := make(map[primitive.ObjectID][]string)
+	failing := make(map[primitive.ObjectID][]string)
+	for _, worker := range workers {
+		for _, ID := range worker.Languages {
+			capable[ID] = append(capable[ID], worker.ID)
+		}
+		for _, ID := range worker.Failed {
+			failing[ID] = append(failing[ID], worker.ID)
+		}
+	}
+	for _, language := range languages {
+		coverage := LanguageCoverage{
+			ID:       language.ID,
+			Name:     language.Name,
+			Disabled: language.Disabled,
+			Probe:    language.Probe != nil,
+			Workers:  capable[language.ID],
+			Failing:  failing[language.ID],
+		}
+		if coverage.Failing == nil {
+			coverage.Failing = []string{}
+		}
+		if coverage.Workers == nil {
+			coverage.Workers = []string{}
+			response.Uncovered = append(response.Uncovered, coverage)
+		}
+		response.Languages = append(response.Languages, coverage)
+	}
+	return response, nil
+}
+
+// ensureWorkerIndexes : Lets MongoDB drop expired advertisements and keeps
+// the queue lookups of workers on an index
+func (api *API) ensureWorkerIndexes(ctx context.Context) error {
+	_, err := api.Db.Collection("workers").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = api.Db.Collection("judge_queue").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "lang_id", Value: 1}, {Key: "priority", Value: -1}, {Key: "enqueued_at", Value: 1}},
+	})
+	return err
+}
+
+// advertisement : Last advertisement of this worker, renewed by
+// runAdvertisements and read by every slot before claiming a job
+type advertisement struct {
+	mu           sync.RWMutex
+	worker       Worker
+	advertisedAt time.Time // Last heartbeat recorded, zero before the first one
+}
+
+// current : Copy of the advertisement as it stands
+func (a *advertisement) current() Worker {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	worker := a.worker
+	worker.Languages = append([]primitive.ObjectID{}, a.worker.Languages...)
+	worker.Failed = append([]primitive.ObjectID{}, a.worker.Failed...)
+	return worker
+}
+
+// checkAdvertised : Readiness check failing until the first heartbeat of
+// the worker is recorded, and again once it missed a few
+func (a *advertisement) checkAdvertised(interval time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		a.mu.RLock()
+		advertisedAt := a.advertisedAt
+		a.mu.RUnlock()
+		if advertisedAt.IsZero() {
+			return errNotAdvertised
+		}
+		if time.Since(advertisedAt) > missedHeartbeats*interval {
+			return fmt.Errorf("%w since %s", errNotAdvertised, advertisedAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+}
+
+// newAdvertisement : Advertisement of this worker before its first
+// heartbeat, with no language
+func (api *API) newAdvertisement() *advertisement {
+	host, _ := os.Hostname()
+	worker := Worker{
+		ID:        api.Config.Judge.Name,
+		Host:      host,
+		Slots:     api.Config.Judge.Workers,
+		Languages: []primitive.ObjectID{},
+		Failed:    []primitive.ObjectID{},
+		StartedAt: time.Now().UTC(),
+	}
+	if len(worker.ID) <= 0 {
+		worker.ID = host
+	}
+	return &advertisement{worker: worker}
+}
+
+// runAdvertisements : Sends a heartbeat every interval until ctx ends, and
+// self-tests the languages added or edited since alongside, so slow
+// self-tests never hold back a heartbeat. The worker retires once its jobs
+// are done.
+func (api *API) runAdvertisements(ctx context.Context, ad *advertisement, tested capabilities, passed map[primitive.ObjectID]bool) {
+	var discovery sync.WaitGroup
+	discovery.Add(1)
+	go func() {
+		defer discovery.Done()
+		api.everyHeartbeat(ctx, func() {
+			api.discover(ctx, ad, tested, passed)
+		})
+	}()
+	api.everyHeartbeat(ctx, func() {
+		api.advertise(ctx, ad)
+	})
+	discovery.Wait()
+}
+
+// everyHeartbeat : Calls f every heartbeat interval until ctx ends, ticks
+// missed while f runs are dropped
+func (api *API) everyHeartbeat(ctx context.Context, f func()) {
+	ticker := time.NewTicker(api.heartbeatInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		f()
+	}
+}
+
+// heartbeatInterval : Time between two advertisements
+func (api *API) heartbeatInterval() time.Duration {
+	return time.Duration(api.Config.Judge.Heartbeat) * time.Second
+}
+
+// discover : Self-tests the languages added or edited since the last call
+// and changes the languages of the advertisement, which the next heartbeat
+// records. On failure the languages advertised so far stand.
+func (api *API) discover(ctx context.Context, ad *advertisement, tested capabilities, passed map[primitive.ObjectID]bool) {
+	log := api.Log.With(zap.String("worker", ad.current().ID))
+	languages, failed, err := api.discoverLanguages(ctx, tested, passed)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		log.Error("Language discovery failed", zap.Error(err))
+		return
+	}
+
+	ad.mu.Lock()
+	changed := len(languages) != len(ad.worker.Languages)
+	ad.worker.Languages = languages
+	ad.worker.Failed = failed
+	ad.mu.Unlock()
+	if changed {
+		log.Info("Languages advertised", zap.Int("languages", len(languages)), zap.Int("failed", len(failed)))
+	}
+}
+
+// advertise : Records the advertisement and puts back the jobs of workers
+// that stopped sending heartbeats. Failures are logged and the last
+// advertisement stands until the next heartbeat.
+func (api *API) advertise(ctx context.Context, ad *advertisement) {
+	worker := ad.current()
+	log := api.Log.With(zap.String("worker", worker.ID))
+
+	err := api.heartbeat(ctx, &worker, api.heartbeatInterval())
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		log.Error("Heartbeat failed", zap.Error(err))
+	} else {
+		ad.mu.Lock()
+		ad.worker.HeartbeatAt = worker.HeartbeatAt
+		ad.worker.ExpiresAt = worker.ExpiresAt
+		ad.advertisedAt = worker.HeartbeatAt
+		ad.mu.Unlock()
+	}
+	if err = api.releaseOrphanedJobs(ctx); err != nil {
+		log.Error("Releasing jobs of expired workers failed", zap.Error(err))
+	}
+}
+
+// retireWorker : Withdraws the advertisement of the worker and puts its
+// claimed jobs back in the queue for other workers
+func (api *API) retireWorker(worker Worker) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := api.Workers.Retire(ctx, worker.ID)
+	if err == nil {
+		err = api.releaseOrphanedJobs(ctx)
+	}
+	if err != nil {
+		api.Log.Error("Worker retirement failed", zap.String("worker", worker.ID), zap.Error(err))
+	}
+}
+
+// releaseOrphanedJobs : Puts back in the queue the jobs claimed by workers
+// that are no longer live
+func (api *API) releaseOrphanedJobs(ctx context.Context) error {
+	workers, err := api.liveWorkers(ctx)
+	if err != nil {
+		return err
+	}
+	live := make([]string, len(workers))
+	for i, worker := range workers {
+		live[i] = worker.ID
+	}
+	released, err := api.Jobs.ReleaseExcept(ctx, live)
+	if released > 0 {
+		api.Log.Warn("Released jobs of expired workers", zap.Int64("jobs", released))
+	}
+	return err
+}
+
+func (api *API) listWorkersV2(w http.ResponseWriter, r *http.Request) {
+	response, err := api.workerCoverage(r.Context())
+	if err != nil {
+		api.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWorkerRoutes(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	ruby := s.language("Ruby")
+
+	live := Worker{ID: "live", Slots: 2, Languages: []primitive.ObjectID{python.ID}}
+	if err := s.heartbeat(context.Background(), &live, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	other := Worker{ID: "other", Languages: []primitive.ObjectID{}, Failed: []primitive.ObjectID{python.ID}}
+	if err := s.heartbeat(context.Background(), &other, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	gone := Worker{ID: "gone", Languages: []primitive.ObjectID{ruby.ID}, ExpiresAt: time.Now().Add(-time.Second)}
+	if err := s.Workers.Heartbeat(context.Background(), gone); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, s.get("/v2/workers", false), http.StatusUnauthorized, CodeUnauthorized)
+	var response WorkersResponse
+	expectJSON(t, s.get("/v2/workers", true), http.StatusOK, &response)
+	if len(response.Workers) != 2 || response.Workers[0].ID != live.ID || response.Workers[1].ID != other.ID {
+		t.Fatalf("live workers %+v", response.Workers)
+	}
+	if len(response.Languages) != 2 || len(response.Uncovered) != 1 || response.Uncovered[0].ID != ruby.ID {
+		t.Fatalf("coverage %+v, uncovered %+v", response.Languages, response.Uncovered)
+	}
+	for _, coverage := range response.Languages {
+		if coverage.ID == python.ID && (len(coverage.Workers) != 1 || coverage.Workers[0] != live.ID) {
+			t.Fatalf("python covered by %v", coverage.Workers)
+		}
+		if coverage.ID == python.ID && (len(coverage.Failing) != 1 || coverage.Failing[0] != other.ID) {
+			t.Fatalf("python failing on %v", coverage.Failing)
+		}
+	}
+}
+
+func TestRetiredWorkerReleasesJobs(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1")
+	submission := Submission{ID: primitive.NewObjectID(), QuestionID: question.ID, LanguageID: python.ID, Verdict: VerdictQueued}
+	if err := s.enqueueSubmissions(context.Background(), []Submission{submission}, PrioritySubmission, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	worker := Worker{ID: "worker", Languages: []primitive.ObjectID{python.ID}}
+	if err := s.heartbeat(context.Background(), &worker, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Jobs.Claim(context.Background(), worker.ID, worker.Languages); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Jobs.Claim(context.Background(), "other", worker.Languages); err != errQueueEmpty {
+		t.Fatalf("claimed job claimed again with %v", err)
+	}
+
+	s.retireWorker(worker)
+	var response WorkersResponse
+	expectJSON(t, s.get("/v2/workers", true), http.StatusOK, &response)
+	if len(response.Workers) != 0 {
+		t.Fatalf("retired worker still listed: %+v", response.Workers)
+	}
+	job, err := s.Jobs.Claim(context.Background(), "other", worker.Languages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.SubmissionID != submission.ID {
+		t.Fatalf("claimed %+v", job)
+	}
+}
+
+func TestSelfTestFailureStaysOnWorker(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh on this host")
+	}
+	s := newTestServer(t)
+	shell := s.probedLanguage("Shell", "sh")
+	missing := s.probedLanguage("Missing", "judge-test-missing-interpreter")
+
+	// Another worker without sh failed first, this one passes
+	failure := &SelfTest{Verdict: VerdictRuntimeError}
+	if _, err := s.Languages.SetSelfTest(context.Background(), shell.ID, failure); err != nil {
+		t.Fatal(err)
+	}
+	languages, failed, err := s.discoverLanguages(context.Background(), make(capabilities), make(map[primitive.ObjectID]bool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(languages) != 1 || languages[0] != shell.ID || len(failed) != 1 || failed[0] != missing.ID {
+		t.Fatalf("passed %v, failed %v", languages, failed)
+	}
+
+	for _, ID := range []primitive.ObjectID{shell.ID, missing.ID} {
+		language, err := s.Languages.Get(context.Background(), ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if language.Disabled {
+			t.Fatalf("%s disabled by the self-test of one worker", language.Name)
+		}
+		if language.SelfTest == nil || language.SelfTest.Passed != (ID == shell.ID) {
+			t.Fatalf("%s self-test %+v", language.Name, language.SelfTest)
+		}
+	}
+}
+
+func TestExpiredClaimLosesVerdict(t *testing.T) {
+	s := newTestServer(t)
+	python := s.language("Python")
+	question := s.question("Echo", "1")
+	submission := Submission{ID: primitive.NewObjectID(), QuestionID: question.ID, LanguageID: python.ID, Verdict: VerdictQueued}
+	if err := s.Submissions.Insert(context.Background(), submission); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.enqueueSubmissions(context.Background(), []Submission{submission}, PrioritySubmission, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first worker expires mid-judgement and the job goes to another
+	claim := func(workerID string) primitive.ObjectID {
+		job, err := s.Jobs.Claim(context.Background(), workerID, []primitive.ObjectID{python.ID})
+		if err == nil {
+			err = s.Submissions.Claim(context.Background(), submission.ID, *job.ClaimID)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *job.ClaimID
+	}
+	expired := claim("expired")
+	if _, err := s.Jobs.ReleaseExcept(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	current := claim("current")
+
+	judged := submission
+	judged.Verdict = VerdictAccepted
+	if err := s.Submissions.SetVerdict(context.Background(), judged, current); err != nil {
+		t.Fatal(err)
+	}
+	judged.Verdict = VerdictTimeLimit
+	if err := s.Submissions.SetVerdict(context.Background(), judged, expired); err != errClaimLost {
+		t.Fatalf("expired claim recorded its verdict with %v", err)
+	}
+	stored, err := s.Submissions.Get(context.Background(), submission.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Verdict != VerdictAccepted {
+		t.Fatalf("verdict %s, expected the one of the current claim", stored.Verdict)
+	}
+
+	// Rejudges drop the claim, so no earlier judgement lands afterwards
+	if err = s.Submissions.Requeue(context.Background(), submission.ID, Judgement{RejudgeID: primitive.NewObjectID()}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Submissions.SetVerdict(context.Background(), judged, current); err != errClaimLost {
+		t.Fatalf("claim kept through a rejudge, %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// Config : Settings of the judge, read in order from the defaults, an
+// optional YAML file, JUDGE_* environment variables and command line flags
+type Config struct {
+	File            string        `yaml:"-"`
+	Listen          string        `yaml:"listen"`
+	ShutdownDelay   int           `yaml:"shutdown_delay"` // Seconds readiness fails before the server stops taking requests
+	ShutdownTimeout int           `yaml:"shutdown_timeout"`
+	AdminToken      string        `yaml:"admin_token"`
+	TLS             TLSConfig     `yaml:"tls"`
+	Mongo           MongoConfig   `yaml:"mongo"`
+	Storage         StorageConfig `yaml:"storage"`
+	Judge           JudgeConfig   `yaml:"judge"`
+	Limits          LimitsConfig  `yaml:"limits"`
+	Log             LogConfig     `yaml:"log"`
+	Tracing         TracingConfig `yaml:"tracing"`
+}
+
+// TracingConfig : Where OpenTelemetry spans are exported and how many
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// LogConfig : Format and minimum level of the logs
+type LogConfig struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+// TLSConfig : Certificate and key served over HTTPS, plain HTTP when empty
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// MongoConfig : Database connection
+type MongoConfig struct {
+	URI            string `yaml:"uri"`
+	Database       string `yaml:"database"`
+	Migrate        bool   `yaml:"migrate"`         // Apply pending schema migrations at startup instead of refusing to start
+	ConnectTimeout int    `yaml:"connect_timeout"` // Seconds to keep retrying the first connection
+	ReadTimeout    int    `yaml:"read_timeout"`    // Seconds a single read may take
+	WriteTimeout   int    `yaml:"write_timeout"`   // Seconds a single write may take, waiting for a majority included
+	ListReadPref   string `yaml:"list_read_preference"`
+}
+
+// StorageConfig : Where testcases are kept
+type StorageConfig struct {
+	Backend       string `yaml:"backend"`
+	Path          string `yaml:"path"`
+	PurgeInterval int    `yaml:"purge_interval"` // Seconds between two purges of deleted testcases, 0 for none
+}
+
+// JudgeConfig : Settings of the judge workers, started with judge worker
+type JudgeConfig struct {
+	Workers   int      `yaml:"workers"`   // Submissions judged at once
+	Name      string   `yaml:"name"`      // Name the worker advertises under, the host name when empty
+	Heartbeat int      `yaml:"heartbeat"` // Seconds between two advertisements
+	Sandbox   []string `yaml:"sandbox"`   // Command every compile and run is wrapped in, required by workers
+}
+
+// LimitsConfig : Default limits for questions and uploaded testcase archives
+type LimitsConfig struct {
+	TimeLimit      int   `yaml:"time_limit"`
+	MaxArchiveSize int64 `yaml:"max_archive_size"`
+	MaxFileSize    int64 `yaml:"max_file_size"`
+	MaxTotalSize   int64 `yaml:"max_total_size"`
+	MaxEntries     int   `yaml:"max_entries"`
+	MaxRatio       int64 `yaml:"max_ratio"`
+}
+
+// Storage backends
+const (
+	StorageLocal = "local"
+)
+
+// Log formats, JSON for production and readable lines for development
+const (
+	LogJSON    = "json"
+	LogConsole = "console"
+)
+
+// ReadPreferences : Read preferences MongoDB knows, primary only reads from
+// the primary while the others spread reads over the secondaries
+var ReadPreferences = []interface{}{"primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest"}
+
+// Tracing exporters, none keeps tracing off and stdout is for local use
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// Default : Settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Listen:          "0.0.0.0:8080",
+		ShutdownDelay:   10,
+		ShutdownTimeout: 15,
+		Mongo: MongoConfig{
+			URI:      "mongodb://localhost:27017",
+			Database: "judge",
+			Migrate:  true,
+
+			ConnectTimeout: 60,
+			ReadTimeout:    10,
+			WriteTimeout:   15,
+			ListReadPref:   "secondaryPreferred",
+		},
+		Storage: StorageConfig{
+			Backend:       StorageLocal,
+			Path:          "testcases/",
+			PurgeInterval: 3600,
+		},
+		Judge: JudgeConfig{
+			Workers:   1,
+			Heartbeat: 10,
+		},
+		Limits: LimitsConfig{
+			TimeLimit:      1,
+			MaxArchiveSize: 64 << 20,
+			MaxFileSize:    64 << 20,
+			MaxTotalSize:   256 << 20,
+			MaxEntries:     2000,
+			MaxRatio:       500,
+		},
+		Log: LogConfig{
+			Format: LogJSON,
+			Level:  "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingNone,
+			Endpoint:    "localhost:55680",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load : Builds the configuration from args, usually os.Args[1:]
+func Load(args []string) (Config, error) {
+	return LoadCommand("judge", args, nil)
+}
+
+// LoadCommand : Load for a subcommand of the judge, define adds the flags
+// of the subcommand next to the configuration ones
+func LoadCommand(name string, args []string, define func(fs *flag.FlagSet)) (Config, error) {
+	// A first pass over the flags only looks for -config
+	cfg := Default()
+	cfg.File = os.Getenv("JUDGE_CONFIG")
+	if err := flagSet(name, &cfg, define).Parse(args); err != nil {
+		return cfg, err
+	}
+	file := cfg.File
+
+	cfg = Default()
+	cfg.File = file
+	if len(file) > 0 {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return cfg, err
+		}
+		if err = yaml.UnmarshalStrict(content, &cfg); err != nil {
+			return cfg, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := flagSet(name, &cfg, define).Parse(args); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func flagSet(name string, cfg *Config, define func(fs *flag.FlagSet)) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if define != nil {
+		define(fs)
+	}
+	fs.StringVar(&cfg.File, "config", cfg.File, "YAML configuration file")
+	fs.StringVar(&cfg.Listen, "listen", cfg.Listen, "Address the API listens on")
+	fs.IntVar(&cfg.ShutdownDelay, "shutdown-delay", cfg.ShutdownDelay, "Seconds readiness fails on shutdown before the server stops taking requests, at least one readiness period")
+	fs.IntVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "Seconds requests in flight get to finish on shutdown")
+	fs.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "Bearer token for the admin routes")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert", cfg.TLS.CertFile, "TLS certificate file")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key", cfg.TLS.KeyFile, "TLS key file")
+	fs.StringVar(&cfg.Mongo.URI, "mongo-uri", cfg.Mongo.URI, "MongoDB connection URI")
+	fs.StringVar(&cfg.Mongo.Database, "mongo-db", cfg.Mongo.Database, "MongoDB database name")
+	fs.IntVar(&cfg.Mongo.ConnectTimeout, "mongo-connect-timeout", cfg.Mongo.ConnectTimeout, "Seconds to keep retrying the first MongoDB connection")
+	fs.IntVar(&cfg.Mongo.ReadTimeout, "mongo-read-timeout", cfg.Mongo.ReadTimeout, "Seconds a single MongoDB read may take")
+	fs.IntVar(&cfg.Mongo.WriteTimeout, "mongo-write-timeout", cfg.Mongo.WriteTimeout, "Seconds a single MongoDB write may take")
+	fs.StringVar(&cfg.Mongo.ListReadPref, "mongo-list-read-preference", cfg.Mongo.ListReadPref, "Read preference of listings, such as secondaryPreferred")
+	fs.BoolVar(&cfg.Mongo.Migrate, "migrate", cfg.Mongo.Migrate, "Apply pending schema migrations at startup, -migrate=false leaves them to judge migrate")
+	fs.StringVar(&cfg.Storage.Backend, "storage-backend", cfg.Storage.Backend, "Testcase storage backend")
+	fs.StringVar(&cfg.Storage.Path, "storage-path", cfg.Storage.Path, "Folder of the local testcase storage")
+	fs.IntVar(&cfg.Storage.PurgeInterval, "purge-interval", cfg.Storage.PurgeInterval, "Seconds between two purges of the testcases of deleted questions, 0 for none")
+	fs.IntVar(&cfg.Judge.Workers, "workers", cfg.Judge.Workers, "Number of submissions a worker judges at once")
+	fs.StringVar(&cfg.Judge.Name, "worker-name", cfg.Judge.Name, "Name the worker advertises under, the host name when empty")
+	fs.IntVar(&cfg.Judge.Heartbeat, "heartbeat", cfg.Judge.Heartbeat, "Seconds between two advertisements of the worker")
+	fs.Var((*fields)(&cfg.Judge.Sandbox), "sandbox", "Command every compile and run of a worker is wrapped in, arguments separated by spaces")
+	fs.IntVar(&cfg.Limits.TimeLimit, "time-limit", cfg.Limits.TimeLimit, "Default time limit of a question in seconds")
+	fs.Int64Var(&cfg.Limits.MaxArchiveSize, "max-archive-size", cfg.Limits.MaxArchiveSize, "Maximum size of a testcase archive in bytes")
+	fs.Int64Var(&cfg.Limits.MaxFileSize, "max-file-size", cfg.Limits.MaxFileSize, "Maximum size of a single testcase file in bytes")
+	fs.Int64Var(&cfg.Limits.MaxTotalSize, "max-total-size", cfg.Limits.MaxTotalSize, "Maximum uncompressed size of a testcase archive in bytes")
+	fs.IntVar(&cfg.Limits.MaxEntries, "max-entries", cfg.Limits.MaxEntries, "Maximum number of entries in a testcase archive")
+	fs.Int64Var(&cfg.Limits.MaxRatio, "max-ratio", cfg.Limits.MaxRatio, "Maximum compression ratio of a testcase archive")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "Log format, json or console")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "Minimum log level, debug, info, warn or error")
+	fs.StringVar(&cfg.Tracing.Exporter, "tracing-exporter", cfg.Tracing.Exporter, "Trace exporter, none, stdout or otlp")
+	fs.StringVar(&cfg.Tracing.Endpoint, "tracing-endpoint", cfg.Tracing.Endpoint, "Address of the OTLP collector")
+	fs.BoolVar(&cfg.Tracing.Insecure, "tracing-insecure", cfg.Tracing.Insecure, "Connect to the OTLP collector without TLS")
+	fs.Float64Var(&cfg.Tracing.SampleRatio, "tracing-sample-ratio", cfg.Tracing.SampleRatio, "Fraction of new traces that are sampled")
+	return fs
+}
+
+func applyEnv(cfg *Config) error {
+	stringVars := map[string]*string{
+		"JUDGE_LISTEN":                     &cfg.Listen,
+		"JUDGE_ADMIN_TOKEN":                &cfg.AdminToken,
+		"JUDGE_TLS_CERT":                   &cfg.TLS.CertFile,
+		"JUDGE_TLS_KEY":                    &cfg.TLS.KeyFile,
+		"JUDGE_MONGO_URI":                  &cfg.Mongo.URI,
+		"JUDGE_MONGO_DB":                   &cfg.Mongo.Database,
+		"JUDGE_MONGO_LIST_READ_PREFERENCE": &cfg.Mongo.ListReadPref,
+		"JUDGE_STORAGE_BACKEND":            &cfg.Storage.Backend,
+		"JUDGE_STORAGE_PATH":               &cfg.Storage.Path,
+		"JUDGE_WORKER_NAME":                &cfg.Judge.Name,
+		"JUDGE_LOG_FORMAT":                 &cfg.Log.Format,
+		"JUDGE_LOG_LEVEL":                  &cfg.Log.Level,
+		"JUDGE_TRACING_EXPORTER":           &cfg.Tracing.Exporter,
+		"JUDGE_TRACING_ENDPOINT":           &cfg.Tracing.Endpoint,
+	}
+	for name, target := range stringVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	intVars := map[string]*int{
+		"JUDGE_SHUTDOWN_DELAY":        &cfg.ShutdownDelay,
+		"JUDGE_SHUTDOWN_TIMEOUT":      &cfg.ShutdownTimeout,
+		"JUDGE_WORKERS":               &cfg.Judge.Workers,
+		"JUDGE_MONGO_CONNECT_TIMEOUT": &cfg.Mongo.ConnectTimeout,
+		"JUDGE_MONGO_READ_TIMEOUT":    &cfg.Mongo.ReadTimeout,
+		"JUDGE_MONGO_WRITE_TIMEOUT":   &cfg.Mongo.WriteTimeout,
+		"JUDGE_HEARTBEAT":             &cfg.Judge.Heartbeat,
+		"JUDGE_PURGE_INTERVAL":        &cfg.Storage.PurgeInterval,
+		"JUDGE_TIME_LIMIT":            &cfg.Limits.TimeLimit,
+		"JUDGE_MAX_ENTRIES":           &cfg.Limits.MaxEntries,
+	}
+	for name, target := range intVars {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	int64Vars := map[string]*int64{
+		"JUDGE_MAX_ARCHIVE_SIZE": &cfg.Limits.MaxArchiveSize,
+		"JUDGE_MAX_FILE_SIZE":    &cfg.Limits.MaxFileSize,
+		"JUDGE_MAX_TOTAL_SIZE":   &cfg.Limits.MaxTotalSize,
+		"JUDGE_MAX_RATIO":        &cfg.Limits.MaxRatio,
+	}
+	for name, target := range int64Vars {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	if value, ok := os.LookupEnv("JUDGE_SANDBOX"); ok {
+		cfg.Judge.Sandbox = strings.Fields(value)
+	}
+
+	boolVars := map[string]*bool{
+		"JUDGE_TRACING_INSECURE": &cfg.Tracing.Insecure,
+		"JUDGE_MONGO_MIGRATE":    &cfg.Mongo.Migrate,
+	}
+	for name, target := range boolVars {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	float64Vars := map[string]*float64{
+		"JUDGE_TRACING_SAMPLE_RATIO": &cfg.Tracing.SampleRatio,
+	}
+	for name, target := range float64Vars {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	return nil
+}
+
+// fields : Flag holding a list given as arguments separated by spaces
+type fields []string
+
+func (f *fields) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, " ")
+}
+
+func (f *fields) Set(value string) error {
+	*f = strings.Fields(value)
+	return nil
+}
+
+var mongoURI = regexp.MustCompile(`^mongodb(\+srv)?://`)
+
+// Validate : Checks the configuration before anything is started
+func (c Config) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Listen, validation.Required),
+		validation.Field(&c.ShutdownDelay, validation.Min(0)),
+		validation.Field(&c.ShutdownTimeout, validation.Required, validation.Min(1)),
+		validation.Field(&c.TLS),
+		validation.Field(&c.Mongo),
+		validation.Field(&c.Storage),
+		validation.Field(&c.Judge),
+		validation.Field(&c.Limits),
+		validation.Field(&c.Log),
+		validation.Field(&c.Tracing),
+	)
+}
+
+// Validate : Checks the tracing settings
+func (c TracingConfig) Validate() error {
+	err := validation.ValidateStruct(&c,
+		validation.Field(&c.Exporter, validation.Required, validation.In(TracingNone, TracingStdout, TracingOTLP)),
+		validation.Field(&c.SampleRatio, validation.Min(0.0), validation.Max(1.0)),
+	)
+	if err != nil {
+		return err
+	}
+	if c.Exporter == TracingOTLP && len(c.Endpoint) <= 0 {
+		return errors.New("endpoint is required by the otlp exporter")
+	}
+	return nil
+}
+
+// Validate : Checks the log settings
+func (c LogConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Format, validation.Required, validation.In(LogJSON, LogConsole)),
+		validation.Field(&c.Level, validation.Required, validation.In("debug", "info", "warn", "error")),
+	)
+}
+
+// Validate : Certificate and key go together
+func (c TLSConfig) Validate() error {
+	if (len(c.CertFile) > 0) != (len(c.KeyFile) > 0) {
+		return errors.New("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// Validate : Checks the database settings
+func (c MongoConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.URI, validation.Required, validation.Match(mongoURI)),
+		validation.Field(&c.Database, validation.Required),
+		validation.Field(&c.ConnectTimeout, validation.Min(0)),
+		validation.Field(&c.ReadTimeout, validation.Required, validation.Min(1)),
+		validation.Field(&c.WriteTimeout, validation.Required, validation.Min(1)),
+		validation.Field(&c.ListReadPref, validation.Required, validation.In(ReadPreferences...)),
+	)
+}
+
+// Validate : Checks the storage settings
+func (c StorageConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Backend, validation.Required, validation.In(StorageLocal)),
+		validation.Field(&c.Path, validation.Required),
+		validation.Field(&c.PurgeInterval, validation.Min(0)),
+	)
+}
+
+// Validate : Checks the judge settings
+func (c JudgeConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Workers, validation.Min(0)),
+		validation.Field(&c.Heartbeat, validation.Required, validation.Min(1)),
+	)
+}
+
+// Validate : Checks the limits, all of which must be positive
+func (c LimitsConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.TimeLimit, validation.Required, validation.Min(1)),
+		validation.Field(&c.MaxArchiveSize, validation.Required, validation.Min(int64(1))),
+		validation.Field(&c.MaxFileSize, validation.Required, validation.Min(int64(1))),
+		validation.Field(&c.MaxTotalSize, validation.Required, validation.Min(int64(1))),
+		validation.Field(&c.MaxEntries, validation.Required, validation.Min(1)),
+		validation.Field(&c.MaxRatio, validation.Required, validation.Min(int64(1))),
+	)
+}
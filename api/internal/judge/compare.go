@@ -0,0 +1,63 @@
+package judge
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Compare : Whether actual matches expected line by line, ignoring
+// trailing whitespace on each line and trailing empty lines
+func Compare(expected io.Reader, actual io.Reader) (bool, error) {
+	want := bufio.NewReader(expected)
+	got := bufio.NewReader(actual)
+	for {
+		wantLine, wantMore, err := readLine(want)
+		if err != nil {
+			return false, err
+		}
+		gotLine, gotMore, err := readLine(got)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case !wantMore && !gotMore:
+			return true, nil
+		case !wantMore:
+			return onlyBlank(got, gotLine)
+		case !gotMore:
+			return onlyBlank(want, wantLine)
+		case wantLine != gotLine:
+			return false, nil
+		}
+	}
+}
+
+// readLine : Next line without its trailing whitespace, false once the
+// reader is exhausted
+func readLine(r *bufio.Reader) (string, bool, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return "", false, nil
+		}
+		err = nil
+	}
+	return strings.TrimRight(line, " \t\r\n"), true, err
+}
+
+// onlyBlank : Whether line and the rest of r hold nothing but whitespace
+func onlyBlank(r *bufio.Reader, line string) (bool, error) {
+	for {
+		if line != "" {
+			return false, nil
+		}
+		var more bool
+		var err error
+		line, more, err = readLine(r)
+		if err != nil || !more {
+			return err == nil, err
+		}
+	}
+}
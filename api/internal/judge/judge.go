@@ -0,0 +1,40 @@
+// Package judge : Compiles and runs solutions against testcases and
+// compares their output, without any database or server
+package judge
+
+import "regexp"
+
+// Verdicts of a submission and of its single testcases
+const (
+	VerdictQueued       = "QU"
+	VerdictAccepted     = "AC"
+	VerdictWrongAnswer  = "WA"
+	VerdictTimeLimit    = "TLE"
+	VerdictRuntimeError = "RE"
+	VerdictCompileError = "CE"
+	VerdictOutputLimit  = "OLE"
+)
+
+// Layout : Naming scheme of testcase files, the first group of both
+// patterns being the testcase number
+type Layout struct {
+	Name   string
+	Input  *regexp.Regexp
+	Output *regexp.Regexp
+}
+
+// Layouts : Recognised layouts, the first one matching any file wins
+var Layouts = []Layout{
+	{
+		// input/input1.txt and output/output1.txt
+		Name:   "standard",
+		Input:  regexp.MustCompile(`^input/input([0-9]+)\.([a-zA-Z]+)$`),
+		Output: regexp.MustCompile(`^output/output([0-9]+)\.([a-zA-Z]+)$`),
+	},
+	{
+		// 01.in with 01.out or 01.ans
+		Name:   "flat",
+		Input:  regexp.MustCompile(`^([0-9]+)\.in$`),
+		Output: regexp.MustCompile(`^([0-9]+)\.(out|ans)$`),
+	},
+}
@@ -0,0 +1,165 @@
+package judge
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Language : How solutions written in a language are built and run.
+// Commands are argument lists run without a shell, their arguments and
+// environment may hold these placeholders:
+//
+//	{src}      the source file
+//	{workdir}  the working folder of the step
+//	{binary}   the first artifact that is not a pattern, or else the
+//	           source file name without its extension
+//	{memlimit} the memory limit of the step in megabytes
+type Language struct {
+	Name      string   `json:"name" yaml:"name" bson:"name"`
+	Time      int      `json:"time" yaml:"time" bson:"time"`             // Multiplier of the time limit of questions
+	Filename  string   `json:"filename" yaml:"filename" bson:"filename"` // Name the source file is saved under
+	Compile   *Command `json:"compile" yaml:"compile" bson:"compile"`    // Nil for interpreted languages
+	Execute   Command  `json:"execute" yaml:"execute" bson:"execute"`
+	Artifacts []string `json:"artifacts" yaml:"artifacts" bson:"artifacts"` // Files, or patterns, the run step gets from the compile step
+	Limits    Limits   `json:"limits" yaml:"limits" bson:"limits"`
+}
+
+// Command : Program to run and its environment, on top of PATH
+type Command struct {
+	Argv []string          `json:"argv" yaml:"argv" bson:"argv"`
+	Env  map[string]string `json:"env,omitempty" yaml:"env,omitempty" bson:"env,omitempty"`
+}
+
+// Limits : Bounds of the compile step and memory and output of the run
+// step, whose time is the time limit of the question times the language
+// multiplier. Zero picks the default.
+type Limits struct {
+	CompileTime   int `json:"compile_time" yaml:"compile_time" bson:"compile_time"`       // Seconds
+	CompileMemory int `json:"compile_memory" yaml:"compile_memory" bson:"compile_memory"` // Megabytes
+	Memory        int `json:"memory" yaml:"memory" bson:"memory"`                         // Megabytes
+	Output        int `json:"output" yaml:"output" bson:"output"`                         // Megabytes
+}
+
+// Validate : Limits cannot be negative
+func (l Limits) Validate() error {
+	if l.CompileTime < 0 || l.CompileMemory < 0 || l.Memory < 0 || l.Output < 0 {
+		return ErrNegativeLimit
+	}
+	return nil
+}
+
+// Default limits
+const (
+	DefaultCompileTime   = 30
+	DefaultCompileMemory = 1024
+	DefaultMemory        = 256
+	DefaultOutput        = 64
+)
+
+// Placeholders allowed in commands
+const (
+	PlaceholderSrc      = "{src}"
+	PlaceholderWorkdir  = "{workdir}"
+	PlaceholderBinary   = "{binary}"
+	PlaceholderMemlimit = "{memlimit}"
+)
+
+// Errors returned while checking a language
+var (
+	ErrEmptyCommand       = errors.New("command has no program to run")
+	ErrUnknownPlaceholder = errors.New("unknown placeholder")
+	ErrUnsafeArtifact     = errors.New("artifact should be a relative path inside the working folder")
+	ErrNegativeLimit      = errors.New("limits cannot be negative")
+)
+
+var placeholder = regexp.MustCompile(`\{[a-z]+\}`)
+
+// Validate : Checks the command is not empty and only uses known
+// placeholders
+func (c Command) Validate() error {
+	if len(c.Argv) <= 0 || strings.TrimSpace(c.Argv[0]) == "" {
+		return ErrEmptyCommand
+	}
+	values := append([]string{}, c.Argv...)
+	for _, value := range c.Env {
+		values = append(values, value)
+	}
+	for _, value := range values {
+		for _, found := range placeholder.FindAllString(value, -1) {
+			switch found {
+			case PlaceholderSrc, PlaceholderWorkdir, PlaceholderBinary, PlaceholderMemlimit:
+			default:
+				return fmt.Errorf("%w %s", ErrUnknownPlaceholder, found)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateArtifact : Artifacts are relative paths, or patterns, that stay
+// inside the working folder
+func ValidateArtifact(artifact string) error {
+	if artifact == "" || path.IsAbs(artifact) || strings.Contains(artifact, "\\") {
+		return ErrUnsafeArtifact
+	}
+	for _, part := range strings.Split(path.Clean(artifact), "/") {
+		if part == ".." {
+			return ErrUnsafeArtifact
+		}
+	}
+	if _, err := path.Match(artifact, ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsafeArtifact, err)
+	}
+	return nil
+}
+
+// TimeLimit : Time a solution gets per testcase of a question limited to
+// the given seconds
+func (l Language) TimeLimit(seconds int) time.Duration {
+	multiplier := l.Time
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	return time.Duration(seconds*multiplier) * time.Second
+}
+
+// CompileTimeLimit : Time the compile step gets
+func (l Language) CompileTimeLimit() time.Duration {
+	if l.Limits.CompileTime > 0 {
+		return time.Duration(l.Limits.CompileTime) * time.Second
+	}
+	return DefaultCompileTime * time.Second
+}
+
+// binaryName : What {binary} stands for inside a working folder
+func (l Language) binaryName() string {
+	for _, artifact := range l.Artifacts {
+		if !strings.ContainsAny(artifact, "*?[") {
+			return filepath.FromSlash(artifact)
+		}
+	}
+	return strings.TrimSuffix(l.Filename, filepath.Ext(l.Filename))
+}
+
+// expander : Replaces the placeholders of a step running in workdir
+func (l Language) expander(workdir string, memory int) *strings.Replacer {
+	return strings.NewReplacer(
+		PlaceholderSrc, filepath.Join(workdir, l.Filename),
+		PlaceholderWorkdir, workdir,
+		PlaceholderBinary, filepath.Join(workdir, l.binaryName()),
+		PlaceholderMemlimit, strconv.Itoa(memory),
+	)
+}
+
+func orDefault(value int, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,191 @@
+package judge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Errors returned while loading a problem folder
+var (
+	ErrNoTestcases       = errors.New("folder has no paired testcases")
+	ErrUnpairedTestcase  = errors.New("testcase misses its input or its output")
+	ErrDuplicateTestcase = errors.New("file duplicates another testcase")
+)
+
+// ManifestFile : Name of the manifest next to the testcases
+const ManifestFile = "manifest.json"
+
+// Manifest : Optional manifest.json next to the testcases, fixing their
+// order and giving them names. Exported questions also carry their own
+// name and time limit in it.
+type Manifest struct {
+	Name      string             `json:"name,omitempty"`
+	Time      int                `json:"time,omitempty"`
+	Testcases []ManifestTestcase `json:"testcases"`
+}
+
+// ManifestTestcase : Single testcase listed in a manifest
+type ManifestTestcase struct {
+	Number      int    `json:"number"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Problem : Testcases of a question found in a local folder
+type Problem struct {
+	Name      string
+	Time      int // Time limit in seconds, 0 when the manifest has none
+	Layout    string
+	Testcases []Testcase
+}
+
+// Testcase : Paths of the files of a single testcase
+type Testcase struct {
+	Index       int
+	Number      int
+	Name        string
+	Description string
+	Input       string
+	Output      string
+}
+
+// LoadProblem : Pairs the testcases found in dir, laid out the way
+// archives are, in the order of the manifest when there is one
+func LoadProblem(dir string) (Problem, error) {
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return Problem{}, err
+	}
+
+	layout := Layouts[0]
+	for _, candidate := range Layouts {
+		if matchesAny(candidate, names) {
+			layout = candidate
+			break
+		}
+	}
+
+	inputs := make(map[int]string)
+	outputs := make(map[int]string)
+	for _, name := range names {
+		files := inputs
+		match := layout.Input.FindStringSubmatch(name)
+		if match == nil {
+			files = outputs
+			match = layout.Output.FindStringSubmatch(name)
+		}
+		if match == nil {
+			continue
+		}
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		if _, ok := files[number]; ok {
+			return Problem{}, fmt.Errorf("%w: %s", ErrDuplicateTestcase, name)
+		}
+		files[number] = filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	var numbers []int
+	for number, input := range inputs {
+		if _, ok := outputs[number]; !ok {
+			return Problem{}, fmt.Errorf("%w: %s", ErrUnpairedTestcase, input)
+		}
+		numbers = append(numbers, number)
+	}
+	for number, output := range outputs {
+		if _, ok := inputs[number]; !ok {
+			return Problem{}, fmt.Errorf("%w: %s", ErrUnpairedTestcase, output)
+		}
+	}
+	if len(numbers) <= 0 {
+		return Problem{}, ErrNoTestcases
+	}
+	sort.Ints(numbers)
+
+	problem := Problem{Layout: layout.Name}
+	manifest, err := readManifest(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return Problem{}, err
+	}
+	problem.Name = manifest.Name
+	problem.Time = manifest.Time
+
+	// Listed testcases first, in the order of the manifest, then the others
+	listed := make(map[int]bool)
+	add := func(number int, name string, description string) {
+		listed[number] = true
+		problem.Testcases = append(problem.Testcases, Testcase{
+			Index:       len(problem.Testcases) + 1,
+			Number:      number,
+			Name:        name,
+			Description: description,
+			Input:       inputs[number],
+			Output:      outputs[number],
+		})
+	}
+	for _, testcase := range manifest.Testcases {
+		if _, ok := inputs[testcase.Number]; ok && !listed[testcase.Number] {
+			add(testcase.Number, testcase.Name, testcase.Description)
+		}
+	}
+	for _, number := range numbers {
+		if !listed[number] {
+			add(number, "", "")
+		}
+	}
+
+	return problem, nil
+}
+
+func matchesAny(layout Layout, names []string) bool {
+	for _, name := range names {
+		if layout.Input.MatchString(name) || layout.Output.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readManifest : Content of the manifest at path, empty when there is none
+func readManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &manifest)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("%s: %w", path, err)
+	}
+	return manifest, nil
+}
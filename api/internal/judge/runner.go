@@ -0,0 +1,329 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"judge-two/internal/tracing"
+
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+)
+
+// CompileError : Compiler rejected the solution
+type CompileError struct {
+	Output string
+}
+
+func (e *CompileError) Error() string {
+	return "compilation failed"
+}
+
+// Sandbox : Command every compile, run and version step is run inside,
+// such as an nsjail or bwrap invocation ending with the separator before
+// the program. Its arguments may hold the placeholders of the step. An
+// empty sandbox runs steps directly on this host, which only local tools
+// running trusted code should do.
+type Sandbox []string
+
+// Program : Compiled solution, ready to be run on testcases
+type Program struct {
+	sandbox  Sandbox
+	language Language
+	dir      string // Holds the working folders and the output of runs
+	work     string // Working folder of the run step
+}
+
+// Result : Outcome of running a program on one testcase
+type Result struct {
+	Verdict string
+	Time    time.Duration
+	Detail  string // Why a run failed, such as its exit status
+}
+
+// command : Command with its placeholders replaced, run in dir inside the
+// sandbox without a shell so no argument is ever interpreted and a timeout
+// kills the program itself. Only PATH is inherited from this process.
+func (s Sandbox) command(ctx context.Context, spec Command, dir string, expand *strings.Replacer) (*exec.Cmd, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	argv := make([]string, 0, len(s)+len(spec.Argv))
+	for _, arg := range append(append([]string{}, s...), spec.Argv...) {
+		argv = append(argv, expand.Replace(arg))
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir}
+	for name, value := range spec.Env {
+		cmd.Env = append(cmd.Env, name+"="+expand.Replace(value))
+	}
+	return cmd, nil
+}
+
+// Compile : Compile in an empty sandbox, for local tools only
+func Compile(ctx context.Context, language Language, source string) (*Program, error) {
+	return Sandbox(nil).Compile(ctx, language, source)
+}
+
+// Compile : Copies the source into a fresh folder, compiles it there and
+// carries the artifacts over to the folder runs happen in. Without
+// artifacts the whole compile folder is carried over. The caller closes
+// the program once done with it.
+func (s Sandbox) Compile(ctx context.Context, language Language, source string) (*Program, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "compile", trace.WithAttributes(label.String("judge.language", language.Name)))
+	defer span.End()
+
+	dir, err := ioutil.TempDir("", "judge-")
+	if err != nil {
+		return nil, err
+	}
+	program := &Program{sandbox: s, language: language, dir: dir}
+	if err = program.build(ctx, source); err != nil {
+		program.Close()
+		var compileErr *CompileError
+		if !errors.As(err, &compileErr) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(label.Bool("judge.compiled", false))
+		return nil, err
+	}
+	span.SetAttributes(label.Bool("judge.compiled", true))
+	return program, nil
+}
+
+func (p *Program) build(ctx context.Context, source string) error {
+	compileDir := filepath.Join(p.dir, "compile")
+	if err := os.Mkdir(compileDir, 0755); err != nil {
+		return err
+	}
+	if err := copyFile(filepath.Join(compileDir, p.language.Filename), source); err != nil {
+		return err
+	}
+	p.work = compileDir
+	if p.language.Compile == nil {
+		return nil
+	}
+
+	compileCtx, cancel := context.WithTimeout(ctx, p.language.CompileTimeLimit())
+	defer cancel()
+	memory := orDefault(p.language.Limits.CompileMemory, DefaultCompileMemory)
+	cmd, err := p.sandbox.command(compileCtx, *p.language.Compile, compileDir, p.language.expander(compileDir, memory))
+	if err != nil {
+		return err
+	}
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case compileCtx.Err() == context.DeadlineExceeded:
+		return &CompileError{Output: string(output) + "compilation timed out"}
+	case errors.As(err, &exitErr):
+		return &CompileError{Output: string(output)}
+	case err != nil:
+		return err
+	}
+
+	if len(p.language.Artifacts) <= 0 {
+		return nil
+	}
+	runDir := filepath.Join(p.dir, "run")
+	if err := os.Mkdir(runDir, 0755); err != nil {
+		return err
+	}
+	for _, artifact := range p.language.Artifacts {
+		if err := ValidateArtifact(artifact); err != nil {
+			return err
+		}
+		matches, err := filepath.Glob(filepath.Join(compileDir, filepath.FromSlash(artifact)))
+		if err != nil {
+			return err
+		}
+		if len(matches) <= 0 {
+			return &CompileError{Output: string(output) + fmt.Sprintf("compiler did not produce %s", artifact)}
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(compileDir, match)
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(filepath.Join(runDir, rel)), 0755)
+			}
+			if err == nil {
+				err = copyFile(filepath.Join(runDir, rel), match)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	p.work = runDir
+	return nil
+}
+
+// Run : Runs the program on the testcase and compares its output
+func (p *Program) Run(ctx context.Context, testcase Testcase, limit time.Duration) (Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "run", trace.WithAttributes(
+		label.String("judge.language", p.language.Name),
+		label.String("judge.input", filepath.Base(testcase.Input)),
+	))
+	defer span.End()
+
+	result, err := p.run(ctx, testcase, limit)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(label.String("judge.verdict", result.Verdict), label.Int64("judge.time_ms", result.Time.Milliseconds()))
+	return result, err
+}
+
+func (p *Program) run(ctx context.Context, testcase Testcase, limit time.Duration) (Result, error) {
+	input, err := os.Open(testcase.Input)
+	if err != nil {
+		return Result{}, err
+	}
+	defer input.Close()
+	output, err := os.Create(filepath.Join(p.dir, "stdout"))
+	if err != nil {
+		return Result{}, err
+	}
+	defer output.Close()
+
+	runCtx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+	// Programs printing past the output limit are killed right away
+	// rather than left to fill the disk until the time limit
+	killCtx, kill := context.WithCancel(runCtx)
+	defer kill()
+	memory := orDefault(p.language.Limits.Memory, DefaultMemory)
+	cmd, err := p.sandbox.command(killCtx, p.language.Execute, p.work, p.language.expander(p.work, memory))
+	if err != nil {
+		return Result{}, err
+	}
+	outputLimit := orDefault(p.language.Limits.Output, DefaultOutput)
+	stdout := &limitedWriter{w: output, left: int64(outputLimit) << 20, exceeded: kill}
+	cmd.Stdin = input
+	cmd.Stdout = stdout
+
+	start := time.Now()
+	err = cmd.Run()
+	result := Result{Time: time.Since(start)}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return result, ctx.Err()
+	case stdout.over:
+		result.Verdict = VerdictOutputLimit
+		result.Detail = fmt.Sprintf("output is over %d MB", outputLimit)
+		return result, nil
+	case runCtx.Err() == context.DeadlineExceeded:
+		result.Verdict = VerdictTimeLimit
+		return result, nil
+	case errors.As(err, &exitErr):
+		result.Verdict = VerdictRuntimeError
+		result.Detail = exitErr.Error()
+		return result, nil
+	case err != nil:
+		return result, err
+	}
+
+	expected, err := os.Open(testcase.Output)
+	if err != nil {
+		return result, err
+	}
+	defer expected.Close()
+	if _, err = output.Seek(0, io.SeekStart); err != nil {
+		return result, err
+	}
+	same, err := Compare(expected, output)
+	if err != nil {
+		return result, err
+	}
+	result.Verdict = VerdictWrongAnswer
+	if same {
+		result.Verdict = VerdictAccepted
+	}
+	return result, nil
+}
+
+// errOutputLimit : Stops copying the output of a program past its limit
+var errOutputLimit = errors.New("output limit exceeded")
+
+// limitedWriter : Writer keeping at most left more bytes, calling exceeded
+// once a write goes past them
+type limitedWriter struct {
+	w        io.Writer
+	left     int64
+	over     bool
+	exceeded func()
+}
+
+func (l *limitedWriter) Write(b []byte) (int, error) {
+	if int64(len(b)) <= l.left {
+		n, err := l.w.Write(b)
+		l.left -= int64(n)
+		return n, err
+	}
+	n, err := l.w.Write(b[:l.left])
+	l.left -= int64(n)
+	if err == nil {
+		err = errOutputLimit
+	}
+	if !l.over {
+		l.over = true
+		l.exceeded()
+	}
+	return n, err
+}
+
+// Version : First line printed by a command such as g++ --version
+func (s Sandbox) Version(ctx context.Context, argv []string) (string, error) {
+	expand := strings.NewReplacer(PlaceholderWorkdir, os.TempDir(), PlaceholderMemlimit, strconv.Itoa(DefaultMemory))
+	cmd, err := s.command(ctx, Command{Argv: argv}, os.TempDir(), expand)
+	if err != nil {
+		return "", err
+	}
+	output, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, err
+		}
+	}
+	return "", err
+}
+
+// Close : Removes the folders of the program
+func (p *Program) Close() error {
+	return os.RemoveAll(p.dir)
+}
+
+func copyFile(target string, source string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,75 @@
+package judge
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// shellProgram : Program running script with sh on a testcase whose
+// expected output is "hello"
+func shellProgram(t *testing.T, script string, limits Limits) (*Program, Testcase) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh on this host")
+	}
+	dir, err := ioutil.TempDir("", "judge-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	testcase := Testcase{Input: filepath.Join(dir, "input"), Output: filepath.Join(dir, "output")}
+	source := filepath.Join(dir, "main.sh")
+	for path, content := range map[string]string{testcase.Input: "", testcase.Output: "hello\n", source: script} {
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	language := Language{
+		Name:     "Shell",
+		Time:     1,
+		Filename: "main.sh",
+		Execute:  Command{Argv: []string{"sh", "main.sh"}},
+		Limits:   limits,
+	}
+	program, err := Compile(context.Background(), language, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { program.Close() })
+	return program, testcase
+}
+
+func TestRunOutputLimit(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		script  string
+		verdict string
+	}{
+		{"accepted", "echo hello", VerdictAccepted},
+		{"endless", "while :; do echo hello; done", VerdictOutputLimit},
+		{"at once", "head -c 2097152 /dev/zero", VerdictOutputLimit},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			program, testcase := shellProgram(t, c.script, Limits{Output: 1})
+			result, err := program.Run(context.Background(), testcase, 20*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Verdict != c.verdict {
+				t.Fatalf("verdict %s (%s), expected %s", result.Verdict, result.Detail, c.verdict)
+			}
+			info, err := os.Stat(filepath.Join(program.dir, "stdout"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() > 1<<20 {
+				t.Fatalf("%d bytes of output kept, expected at most 1 MB", info.Size())
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Namespace : Prefix of every metric of the judge
+const Namespace = "judge"
+
+// HTTP API
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// MongoDB
+var (
+	MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "mongo_command_duration_seconds",
+		Help:      "Time taken by MongoDB commands, by command name and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "outcome"})
+)
+
+// Testcases
+var (
+	TestcaseImportDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "testcase_import_duration_seconds",
+		Help:      "Time taken to validate and extract uploaded testcase archives, by outcome.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"outcome"})
+)
+
+// Judging, recorded by the workers that run submissions
+var (
+	Submissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "submissions_total",
+		Help:      "Judged submissions, by verdict and language.",
+	}, []string{"verdict", "language"})
+
+	CompileDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "compile_duration_seconds",
+		Help:      "Time taken to compile submissions, by language.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"language"})
+
+	RunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Time taken by a submission on a single testcase, by language.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2, 4, 8, 16},
+	}, []string{"language"})
+
+	SandboxFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "sandbox_failures_total",
+		Help:      "Failures of the sandbox itself, as opposed to the submission, by stage.",
+	}, []string{"stage"})
+)
+
+// Judge queue, filled in from the queue collection whenever it is scraped
+var (
+	QueueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "queue", "depth"),
+		"Jobs waiting in the judge queue, by priority.",
+		[]string{"priority"}, nil,
+	)
+
+	QueueAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "queue", "oldest_job_age_seconds"),
+		"Age of the oldest job waiting in the judge queue.",
+		nil, nil,
+	)
+)
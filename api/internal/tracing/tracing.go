@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"judge-two/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/propagators"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// InstrumentationName : Name of the tracer used by the judge's own spans
+const InstrumentationName = "judge-two"
+
+// Tracer : Tracer for the judge's own spans
+func Tracer() trace.Tracer {
+	return global.Tracer(InstrumentationName)
+}
+
+// Setup : Installs the global tracer provider and W3C trace context
+// propagation. The returned function flushes pending spans and must be
+// called before the process exits.
+func Setup(cfg config.TracingConfig, service string) (func(context.Context) error, error) {
+	global.SetTextMapPropagator(otel.NewCompositeTextMapPropagator(propagators.TraceContext{}, propagators.Baggage{}))
+
+	var exporter exporttrace.SpanExporter
+	switch cfg.Exporter {
+	case config.TracingNone:
+		// The global provider stays the no-op one
+		return func(context.Context) error { return nil }, nil
+	case config.TracingStdout:
+		stdoutExporter, err := stdout.NewExporter(stdout.WithoutMetricExport())
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdoutExporter
+	case config.TracingOTLP:
+		options := []otlp.ExporterOption{otlp.WithAddress(cfg.Endpoint)}
+		if cfg.Insecure {
+			options = append(options, otlp.WithInsecure())
+		}
+		otlpExporter, err := otlp.NewExporter(options...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = otlpExporter
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+
+	processor := sdktrace.NewBatchSpanProcessor(exporter)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))}),
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String(service))),
+		sdktrace.WithSpanProcessor(processor),
+	)
+	global.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		// Unregistering the processor exports the spans it still holds
+		provider.UnregisterSpanProcessor(processor)
+		return exporter.Shutdown(ctx)
+	}, nil
+}
+
+// Carrier : Trace context stored in a document, such as a judge job, so
+// the span that enqueued it is the parent of the span that processes it
+type Carrier map[string]string
+
+// Get : Value of key, empty when missing
+func (c Carrier) Get(key string) string {
+	return c[key]
+}
+
+// Set : Stores value under key
+func (c Carrier) Set(key string, value string) {
+	c[key] = value
+}
+
+// Inject : Trace context of ctx, nil when ctx carries none
+func Inject(ctx context.Context) Carrier {
+	carrier := Carrier{}
+	global.TextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) <= 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract : ctx with the trace context from carrier as remote parent
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	if carrier == nil {
+		return ctx
+	}
+	return global.TextMapPropagator().Extract(ctx, carrier)
+}
@@ -0,0 +1,692 @@
+// Package client : Typed client of the v2 judge API described at
+// /openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client : Connection to a judge server
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option : Setting of a Client
+type Option func(*Client)
+
+// WithToken : Admin token sent as a bearer token, required by the language,
+// question and contest edits and deletes, the testcase edit, export,
+// download and rejudge calls
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient : HTTP client used instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New : Client of the server at baseURL, such as http://localhost:8080
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// do : Sends the request and decodes a successful JSON reply into result,
+// failures come back as *Error
+func (c *Client) do(req *http.Request, result interface{}) error {
+	res, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if result == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// send : Sends the request, the caller closes the body of a successful reply
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 400 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Error *Error `json:"error"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil || body.Error == nil {
+		body.Error = &Error{Code: CodeInternal, Message: res.Status}
+	}
+	body.Error.StatusCode = res.StatusCode
+	return nil, body.Error
+}
+
+// newRequest : Request to the path with query parameters and a body
+func (c *Client) newRequest(ctx context.Context, method string, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return http.NewRequestWithContext(ctx, method, target, body)
+}
+
+// jsonRequest : Request whose body is payload encoded as JSON
+func (c *Client) jsonRequest(ctx context.Context, method string, path string, query url.Values, payload interface{}) (*http.Request, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, method, path, query, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// formFile : File part of a multipart body
+type formFile struct {
+	field    string
+	filename string
+	content  io.Reader
+}
+
+// formRequest : Multipart request streaming the files, so archives are
+// never held in memory
+func (c *Client) formRequest(ctx context.Context, method string, path string, query url.Values, fields map[string]string, files []formFile) (*http.Request, error) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		err := writeForm(form, fields, files)
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := c.newRequest(ctx, method, path, query, reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req, nil
+}
+
+func writeForm(form *multipart.Writer, fields map[string]string, files []formFile) error {
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		part, err := form.CreateFormFile(file.field, file.filename)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(part, file.content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editQuery : Query of the calls editing testcases
+func editQuery(rejudge bool) url.Values {
+	query := url.Values{}
+	if rejudge {
+		query.Set("rejudge", "true")
+	}
+	return query
+}
+
+func languagePath(ID string) string {
+	return "/v2/languages/" + url.PathEscape(ID)
+}
+
+func questionPath(ID string) string {
+	return "/v2/questions/" + url.PathEscape(ID)
+}
+
+func contestPath(ID string) string {
+	return "/v2/contests/" + url.PathEscape(ID)
+}
+
+// contestQuery : Query checking languages within the contest, none when
+// contestID is empty
+func contestQuery(contestID string) url.Values {
+	if contestID == "" {
+		return nil
+	}
+	return url.Values{"contest_id": {contestID}}
+}
+
+func testcasePath(questionID string, index int) string {
+	return questionPath(questionID) + "/testcases/" + strconv.Itoa(index)
+}
+
+// ListLanguages : Every language, by name
+func (c *Client) ListLanguages(ctx context.Context) ([]Language, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/languages", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Languages []Language `json:"languages"`
+	}
+	err = c.do(req, &result)
+	return result.Languages, err
+}
+
+// GetLanguage : Language with the given ID
+func (c *Client) GetLanguage(ctx context.Context, ID string) (Language, error) {
+	var language Language
+	req, err := c.newRequest(ctx, http.MethodGet, languagePath(ID), nil, nil)
+	if err == nil {
+		err = c.do(req, &language)
+	}
+	return language, err
+}
+
+// CreateLanguage : Adds a language. Needs the admin token.
+func (c *Client) CreateLanguage(ctx context.Context, input LanguageInput) (Language, error) {
+	var language Language
+	req, err := c.jsonRequest(ctx, http.MethodPost, "/v2/languages", nil, input)
+	if err == nil {
+		err = c.do(req, &language)
+	}
+	return language, err
+}
+
+// UpdateLanguage : Changes the fields set in patch. Needs the admin token.
+func (c *Client) UpdateLanguage(ctx context.Context, ID string, patch LanguagePatch) (Language, error) {
+	var language Language
+	req, err := c.jsonRequest(ctx, http.MethodPatch, languagePath(ID), nil, patch)
+	if err == nil {
+		err = c.do(req, &language)
+	}
+	return language, err
+}
+
+// DeleteLanguage : Soft deletes a language, RestoreLanguage brings it back.
+// Needs the admin token.
+func (c *Client) DeleteLanguage(ctx context.Context, ID string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, languagePath(ID), nil, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// ListDeletedLanguages : Soft deleted languages, which can be restored.
+// Needs the admin token.
+func (c *Client) ListDeletedLanguages(ctx context.Context) ([]Language, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/languages", url.Values{"deleted": {"true"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Languages []Language `json:"languages"`
+	}
+	err = c.do(req, &result)
+	return result.Languages, err
+}
+
+// RestoreLanguage : Brings back a soft deleted language. Needs the admin
+// token.
+func (c *Client) RestoreLanguage(ctx context.Context, ID string) (Language, error) {
+	var language Language
+	req, err := c.newRequest(ctx, http.MethodPost, languagePath(ID)+"/restore", nil, nil)
+	if err == nil {
+		err = c.do(req, &language)
+	}
+	return language, err
+}
+
+// PurgeLanguage : Deletes a language for good, refused while submissions
+// or contests reference it. Needs the admin token.
+func (c *Client) PurgeLanguage(ctx context.Context, ID string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, languagePath(ID), url.Values{"hard": {"true"}}, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// SelfTestLanguage : Clears the last self-test of a language, so every
+// worker runs its probe again. Needs the admin token.
+func (c *Client) SelfTestLanguage(ctx context.Context, ID string) (Language, error) {
+	var language Language
+	req, err := c.newRequest(ctx, http.MethodPost, languagePath(ID)+"/self-test", nil, nil)
+	if err == nil {
+		err = c.do(req, &language)
+	}
+	return language, err
+}
+
+// ListQuestions : Every question, newest first
+func (c *Client) ListQuestions(ctx context.Context) ([]Question, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/questions", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Questions []Question `json:"questions"`
+	}
+	err = c.do(req, &result)
+	return result.Questions, err
+}
+
+// GetQuestion : Question with the given ID
+func (c *Client) GetQuestion(ctx context.Context, ID string) (Question, error) {
+	var question Question
+	req, err := c.newRequest(ctx, http.MethodGet, questionPath(ID), nil, nil)
+	if err == nil {
+		err = c.do(req, &question)
+	}
+	return question, err
+}
+
+// CreateQuestion : Creates a question from a testcase archive. The
+// question is nil on dry runs. When the archive is invalid the *Error
+// carries the report.
+func (c *Client) CreateQuestion(ctx context.Context, question NewQuestion) (*Question, *ArchiveReport, error) {
+	query := url.Values{}
+	if question.DryRun {
+		query.Set("dryRun", "true")
+	}
+	fields := map[string]string{}
+	if len(question.Name) > 0 {
+		fields["name"] = question.Name
+	}
+	if question.Time > 0 {
+		fields["time"] = strconv.Itoa(question.Time)
+	}
+
+	req, err := c.formRequest(ctx, http.MethodPost, "/v2/questions", query, fields, []formFile{
+		{field: "testcases", filename: question.Archive.Name, content: question.Archive.Content},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var result struct {
+		Question *Question      `json:"question"`
+		Report   *ArchiveReport `json:"report"`
+	}
+	err = c.do(req, &result)
+	return result.Question, result.Report, err
+}
+
+// UpdateQuestion : Changes the fields set in patch. Needs the admin token.
+func (c *Client) UpdateQuestion(ctx context.Context, ID string, patch QuestionPatch) (Question, error) {
+	var question Question
+	req, err := c.jsonRequest(ctx, http.MethodPatch, questionPath(ID), nil, patch)
+	if err == nil {
+		err = c.do(req, &question)
+	}
+	return question, err
+}
+
+// DeleteQuestion : Soft deletes a question, RestoreQuestion brings it back.
+// Needs the admin token.
+func (c *Client) DeleteQuestion(ctx context.Context, ID string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, questionPath(ID), nil, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// QuestionLanguages : Languages the question accepts and the time limit
+// of each, within the contest unless contestID is empty
+func (c *Client) QuestionLanguages(ctx context.Context, ID string, contestID string) ([]QuestionLanguage, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, questionPath(ID)+"/languages", contestQuery(contestID), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Languages []QuestionLanguage `json:"languages"`
+	}
+	err = c.do(req, &result)
+	return result.Languages, err
+}
+
+// CheckQuestionLanguage : Whether the question accepts submissions in the
+// language, within the contest unless contestID is empty. The error says
+// why not.
+func (c *Client) CheckQuestionLanguage(ctx context.Context, questionID string, languageID string, contestID string) (QuestionLanguage, error) {
+	var language QuestionLanguage
+	req, err := c.newRequest(ctx, http.MethodGet, questionPath(questionID)+"/languages/"+url.PathEscape(languageID), contestQuery(contestID), nil)
+	if err == nil {
+		err = c.do(req, &language)
+	}
+	return language, err
+}
+
+// ListDeletedQuestions : Soft deleted questions, which can be restored.
+// Needs the admin token.
+func (c *Client) ListDeletedQuestions(ctx context.Context) ([]Question, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/questions", url.Values{"deleted": {"true"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Questions []Question `json:"questions"`
+	}
+	err = c.do(req, &result)
+	return result.Questions, err
+}
+
+// RestoreQuestion : Brings back a soft deleted question. Needs the admin
+// token.
+func (c *Client) RestoreQuestion(ctx context.Context, ID string) (Question, error) {
+	var question Question
+	req, err := c.newRequest(ctx, http.MethodPost, questionPath(ID)+"/restore", nil, nil)
+	if err == nil {
+		err = c.do(req, &question)
+	}
+	return question, err
+}
+
+// PurgeQuestion : Deletes a question for good, refused while submissions
+// or contests reference it. Its testcases go with the next storage purge. Needs the
+// admin token.
+func (c *Client) PurgeQuestion(ctx context.Context, ID string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, questionPath(ID), url.Values{"hard": {"true"}}, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// ExportQuestion : Zip of the question that CreateQuestion takes back,
+// the caller closes it. Needs the admin token.
+func (c *Client) ExportQuestion(ctx context.Context, ID string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, questionPath(ID)+"/export", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// testcaseList : Decodes the testcases replied by an edit
+func (c *Client) testcaseList(req *http.Request) ([]Testcase, *ArchiveReport, error) {
+	var result struct {
+		Testcases []Testcase     `json:"testcases"`
+		Report    *ArchiveReport `json:"report"`
+	}
+	err := c.do(req, &result)
+	return result.Testcases, result.Report, err
+}
+
+// ReplaceTestcases : Replaces every testcase of the question with those of
+// the archive. The testcases are nil on dry runs. Needs the admin token.
+func (c *Client) ReplaceTestcases(ctx context.Context, ID string, archive Archive, dryRun bool, rejudge bool) ([]Testcase, *ArchiveReport, error) {
+	query := editQuery(rejudge)
+	if dryRun {
+		query.Set("dryRun", "true")
+	}
+	req, err := c.formRequest(ctx, http.MethodPut, questionPath(ID)+"/testcases", query, nil, []formFile{
+		{field: "testcases", filename: archive.Name, content: archive.Content},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.testcaseList(req)
+}
+
+// testcaseForm : Fields and files of a single testcase
+func testcaseForm(testcase TestcaseFiles) (map[string]string, []formFile) {
+	fields := map[string]string{}
+	if len(testcase.Name) > 0 {
+		fields["name"] = testcase.Name
+	}
+	if len(testcase.Description) > 0 {
+		fields["description"] = testcase.Description
+	}
+	var files []formFile
+	if testcase.Input != nil {
+		files = append(files, formFile{field: "input", filename: "input.txt", content: testcase.Input})
+	}
+	if testcase.Output != nil {
+		files = append(files, formFile{field: "output", filename: "output.txt", content: testcase.Output})
+	}
+	return fields, files
+}
+
+// AddTestcase : Appends a testcase, both files are required. Needs the
+// admin token.
+func (c *Client) AddTestcase(ctx context.Context, ID string, testcase TestcaseFiles, rejudge bool) ([]Testcase, error) {
+	fields, files := testcaseForm(testcase)
+	req, err := c.formRequest(ctx, http.MethodPost, questionPath(ID)+"/testcases", editQuery(rejudge), fields, files)
+	if err != nil {
+		return nil, err
+	}
+	testcases, _, err := c.testcaseList(req)
+	return testcases, err
+}
+
+// UpdateTestcase : Replaces the files and labels set in testcase. Needs
+// the admin token.
+func (c *Client) UpdateTestcase(ctx context.Context, ID string, index int, testcase TestcaseFiles, rejudge bool) ([]Testcase, error) {
+	fields, files := testcaseForm(testcase)
+	req, err := c.formRequest(ctx, http.MethodPatch, testcasePath(ID, index), editQuery(rejudge), fields, files)
+	if err != nil {
+		return nil, err
+	}
+	testcases, _, err := c.testcaseList(req)
+	return testcases, err
+}
+
+// DeleteTestcase : Removes a testcase, later ones move up. Needs the admin
+// token.
+func (c *Client) DeleteTestcase(ctx context.Context, ID string, index int, rejudge bool) ([]Testcase, error) {
+	req, err := c.newRequest(ctx, http.MethodDelete, testcasePath(ID, index), editQuery(rejudge), nil)
+	if err != nil {
+		return nil, err
+	}
+	testcases, _, err := c.testcaseList(req)
+	return testcases, err
+}
+
+// ReorderTestcases : Puts the testcases in the order of their current
+// indices, such as []int{3, 1, 2}. Needs the admin token.
+func (c *Client) ReorderTestcases(ctx context.Context, ID string, order []int, rejudge bool) ([]Testcase, error) {
+	req, err := c.jsonRequest(ctx, http.MethodPut, questionPath(ID)+"/testcases/order", editQuery(rejudge), map[string][]int{"order": order})
+	if err != nil {
+		return nil, err
+	}
+	testcases, _, err := c.testcaseList(req)
+	return testcases, err
+}
+
+// DownloadTestcase : The "input" or "output" file of a testcase, the
+// caller closes it. Needs the admin token.
+func (c *Client) DownloadTestcase(ctx context.Context, ID string, index int, file string) (io.ReadCloser, error) {
+	if file != "input" && file != "output" {
+		return nil, fmt.Errorf("file should be input or output, not %q", file)
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, testcasePath(ID, index)+"/"+file, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// ListContests : Every contest, newest first
+func (c *Client) ListContests(ctx context.Context) ([]Contest, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/contests", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Contests []Contest `json:"contests"`
+	}
+	err = c.do(req, &result)
+	return result.Contests, err
+}
+
+// GetContest : Contest with the given ID
+func (c *Client) GetContest(ctx context.Context, ID string) (Contest, error) {
+	var contest Contest
+	req, err := c.newRequest(ctx, http.MethodGet, contestPath(ID), nil, nil)
+	if err == nil {
+		err = c.do(req, &contest)
+	}
+	return contest, err
+}
+
+// CreateContest : Adds a contest. Needs the admin token.
+func (c *Client) CreateContest(ctx context.Context, input ContestInput) (Contest, error) {
+	var contest Contest
+	req, err := c.jsonRequest(ctx, http.MethodPost, "/v2/contests", nil, input)
+	if err == nil {
+		err = c.do(req, &contest)
+	}
+	return contest, err
+}
+
+// UpdateContest : Changes the fields set in patch. Needs the admin token.
+func (c *Client) UpdateContest(ctx context.Context, ID string, patch ContestPatch) (Contest, error) {
+	var contest Contest
+	req, err := c.jsonRequest(ctx, http.MethodPatch, contestPath(ID), nil, patch)
+	if err == nil {
+		err = c.do(req, &contest)
+	}
+	return contest, err
+}
+
+// DeleteContest : Removes a contest for good, refused once submissions
+// were made in it. Needs the admin token.
+func (c *Client) DeleteContest(ctx context.Context, ID string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, contestPath(ID), nil, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// CreateSubmission : Submits a solution, refused when the question or the
+// contest it is made in does not take its language
+func (c *Client) CreateSubmission(ctx context.Context, input SubmissionInput) (Submission, error) {
+	var submission Submission
+	req, err := c.jsonRequest(ctx, http.MethodPost, "/v2/submissions", nil, input)
+	if err == nil {
+		err = c.do(req, &submission)
+	}
+	return submission, err
+}
+
+// GetSubmission : Submission and its verdict
+func (c *Client) GetSubmission(ctx context.Context, ID string) (Submission, error) {
+	var submission Submission
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/submissions/"+url.PathEscape(ID), nil, nil)
+	if err == nil {
+		err = c.do(req, &submission)
+	}
+	return submission, err
+}
+
+// CreateRejudge : Sends the matching submissions back to the judge. Needs
+// the admin token.
+func (c *Client) CreateRejudge(ctx context.Context, request RejudgeRequest) (RejudgeCreated, error) {
+	var created RejudgeCreated
+	req, err := c.jsonRequest(ctx, http.MethodPost, "/v2/rejudges", nil, request)
+	if err == nil {
+		err = c.do(req, &created)
+	}
+	return created, err
+}
+
+// GetRejudge : Progress of a rejudge. Needs the admin token.
+func (c *Client) GetRejudge(ctx context.Context, ID string) (RejudgeStatus, error) {
+	var status RejudgeStatus
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/rejudges/"+url.PathEscape(ID), nil, nil)
+	if err == nil {
+		err = c.do(req, &status)
+	}
+	return status, err
+}
+
+// ListWorkers : Live workers and the languages no worker can run
+func (c *Client) ListWorkers(ctx context.Context) (Workers, error) {
+	var workers Workers
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/workers", nil, nil)
+	if err == nil {
+		err = c.do(req, &workers)
+	}
+	return workers, err
+}
+
+// Health : Liveness of the server process
+func (c *Client) Health(ctx context.Context) (Health, error) {
+	var health Health
+	req, err := c.newRequest(ctx, http.MethodGet, "/healthz", nil, nil)
+	if err == nil {
+		err = c.do(req, &health)
+	}
+	return health, err
+}
+
+// Ready : Readiness of the server and its dependencies, an *Error with
+// status 503 when it is not ready
+func (c *Client) Ready(ctx context.Context) (Health, error) {
+	var health Health
+	req, err := c.newRequest(ctx, http.MethodGet, "/readyz", nil, nil)
+	if err != nil {
+		return health, err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return health, err
+	}
+	defer res.Body.Close()
+	if err = json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return health, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return health, &Error{StatusCode: res.StatusCode, Code: CodeUnavailable, Message: "server is not ready"}
+	}
+	return health, nil
+}
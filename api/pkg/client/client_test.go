@@ -0,0 +1,374 @@
+package client_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"judge-two/internal/api"
+	"judge-two/internal/config"
+	"judge-two/pkg/client"
+)
+
+// testToken : Admin token of the test servers
+const testToken = "test-token"
+
+// newServer : Clients, anonymous and admin, of a server on the memory
+// stores running until the end of the test
+func newServer(t *testing.T) (*client.Client, *client.Client) {
+	dir, err := ioutil.TempDir("", "judge-client-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.AdminToken = testToken
+	cfg.Storage.Path = dir
+	cfg.Log.Level = "error"
+	server := httptest.NewServer(api.NewMemoryAPI(cfg).Handler())
+	t.Cleanup(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+	return client.New(server.URL), client.New(server.URL+"/", client.WithToken(testToken), client.WithHTTPClient(server.Client()))
+}
+
+// testcases : Zip of a testcase per input in the standard layout, each
+// expecting its input back
+func testcases(t *testing.T, inputs ...string) client.Archive {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for i, input := range inputs {
+		for _, name := range []string{"input/input", "output/output"} {
+			f, err := w.Create(name + strconv.Itoa(i+1) + ".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte(input))
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return client.Archive{Name: "testcases.zip", Content: &buf}
+}
+
+// expectError : Fails the test unless err is an *client.Error with the
+// status and code
+func expectError(t *testing.T, err error, status int, code string) *client.Error {
+	t.Helper()
+	var clientErr *client.Error
+	if !errors.As(err, &clientErr) {
+		t.Fatalf("error %v, expected %d %s", err, status, code)
+	}
+	if clientErr.StatusCode != status || clientErr.Code != code {
+		t.Fatalf("error %v, expected %d %s", clientErr, status, code)
+	}
+	return clientErr
+}
+
+func TestLanguages(t *testing.T) {
+	ctx := context.Background()
+	anonymous, admin := newServer(t)
+	input := client.LanguageInput{Name: "Python", Time: 1, Filename: "main.py", Execute: client.Command{Argv: []string{"python3", "{src}"}}}
+
+	_, err := anonymous.CreateLanguage(ctx, input)
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	_, err = admin.CreateLanguage(ctx, client.LanguageInput{Name: "Python", Filename: "../main.py"})
+	if clientErr := expectError(t, err, http.StatusUnprocessableEntity, client.CodeValidationFailed); len(clientErr.Fields) == 0 {
+		t.Fatal("no invalid fields")
+	}
+	language, err := admin.CreateLanguage(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	languages, err := anonymous.ListLanguages(ctx)
+	if err != nil || len(languages) != 1 || languages[0].ID != language.ID {
+		t.Fatalf("listed %+v, %v", languages, err)
+	}
+	name := "Python 3"
+	if language, err = admin.UpdateLanguage(ctx, language.ID, client.LanguagePatch{Name: &name}); err != nil || language.Name != name {
+		t.Fatalf("updated to %+v, %v", language, err)
+	}
+	if fetched, err := anonymous.GetLanguage(ctx, language.ID); err != nil || fetched.Name != name {
+		t.Fatalf("got %+v, %v", fetched, err)
+	}
+	_, err = anonymous.GetLanguage(ctx, "nope")
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+
+	if err = admin.DeleteLanguage(ctx, language.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = anonymous.GetLanguage(ctx, language.ID)
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+	if deleted, err := admin.ListDeletedLanguages(ctx); err != nil || len(deleted) != 1 || deleted[0].DeletedAt == nil {
+		t.Fatalf("deleted %+v, %v", deleted, err)
+	}
+	if _, err = admin.RestoreLanguage(ctx, language.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.PurgeLanguage(ctx, language.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = admin.RestoreLanguage(ctx, language.ID)
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+}
+
+func TestQuestions(t *testing.T) {
+	ctx := context.Background()
+	anonymous, admin := newServer(t)
+
+	question, report, err := anonymous.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: testcases(t, "1", "2"), DryRun: true})
+	if err != nil || question != nil || report == nil || !report.Valid || report.Testcases != 2 {
+		t.Fatalf("dry run gave %+v, %+v, %v", question, report, err)
+	}
+	_, _, err = anonymous.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: client.Archive{Name: "testcases.zip", Content: strings.NewReader("nope")}})
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	_, _, err = anonymous.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: client.Archive{Name: "testcases.rar", Content: strings.NewReader("nope")}})
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	question, report, err = anonymous.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: testcases(t, "1", "2")})
+	if err != nil || question == nil || question.NumTestcases != 2 || report == nil {
+		t.Fatalf("created %+v, %+v, %v", question, report, err)
+	}
+
+	questions, err := anonymous.ListQuestions(ctx)
+	if err != nil || len(questions) != 1 || questions[0].ID != question.ID {
+		t.Fatalf("listed %+v, %v", questions, err)
+	}
+	name := "Echo back"
+	_, err = anonymous.UpdateQuestion(ctx, question.ID, client.QuestionPatch{Name: &name})
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	if updated, err := admin.UpdateQuestion(ctx, question.ID, client.QuestionPatch{Name: &name}); err != nil || updated.Name != name {
+		t.Fatalf("updated to %+v, %v", updated, err)
+	}
+	if fetched, err := anonymous.GetQuestion(ctx, question.ID); err != nil || fetched.Name != name {
+		t.Fatalf("got %+v, %v", fetched, err)
+	}
+
+	// The export is an archive the question can be created again from
+	_, err = anonymous.ExportQuestion(ctx, question.ID)
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	export, err := admin.ExportQuestion(ctx, question.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ioutil.ReadAll(export)
+	export.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, _, err := admin.CreateQuestion(ctx, client.NewQuestion{Archive: client.Archive{Name: "export.zip", Content: bytes.NewReader(exported)}})
+	if err != nil || copied.Name != name || copied.Time != 2 || copied.NumTestcases != 2 {
+		t.Fatalf("created from the export %+v, %v", copied, err)
+	}
+
+	err = anonymous.DeleteQuestion(ctx, question.ID)
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	if err = admin.DeleteQuestion(ctx, question.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = anonymous.GetQuestion(ctx, question.ID)
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+	_, err = anonymous.ListDeletedQuestions(ctx)
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	if deleted, err := admin.ListDeletedQuestions(ctx); err != nil || len(deleted) != 1 {
+		t.Fatalf("deleted %+v, %v", deleted, err)
+	}
+	if _, err = admin.RestoreQuestion(ctx, question.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.PurgeQuestion(ctx, question.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = anonymous.GetQuestion(ctx, question.ID)
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+}
+
+func TestTestcases(t *testing.T) {
+	ctx := context.Background()
+	_, admin := newServer(t)
+	question, _, err := admin.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: testcases(t, "1", "2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := admin.AddTestcase(ctx, question.ID, client.TestcaseFiles{Input: strings.NewReader("3"), Output: strings.NewReader("3"), Name: "large"}, false)
+	if err != nil || len(list) != 3 || list[2].Name != "large" {
+		t.Fatalf("added %+v, %v", list, err)
+	}
+	_, err = admin.AddTestcase(ctx, question.ID, client.TestcaseFiles{Input: strings.NewReader("4")}, false)
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	if list, err = admin.UpdateTestcase(ctx, question.ID, 1, client.TestcaseFiles{Output: strings.NewReader("one")}, false); err != nil || len(list) != 3 {
+		t.Fatalf("updated %+v, %v", list, err)
+	}
+	if list, err = admin.ReorderTestcases(ctx, question.ID, []int{3, 1, 2}, false); err != nil || list[0].Name != "large" {
+		t.Fatalf("reordered %+v, %v", list, err)
+	}
+	_, err = admin.ReorderTestcases(ctx, question.ID, []int{1, 1, 2}, false)
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+
+	// The updated output moved to the second place
+	download, err := admin.DownloadTestcase(ctx, question.ID, 2, "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(download)
+	download.Close()
+	if err != nil || string(content) != "one" {
+		t.Fatalf("downloaded %q, %v", content, err)
+	}
+	if _, err = admin.DownloadTestcase(ctx, question.ID, 2, "answer"); err == nil {
+		t.Fatal("downloaded an answer file")
+	}
+	_, err = admin.DownloadTestcase(ctx, question.ID, 9, "input")
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+
+	if list, err = admin.DeleteTestcase(ctx, question.ID, 1, false); err != nil || len(list) != 2 {
+		t.Fatalf("deleted down to %+v, %v", list, err)
+	}
+	list, report, err := admin.ReplaceTestcases(ctx, question.ID, testcases(t, "a", "b", "c", "d"), true, false)
+	if err != nil || list != nil || report == nil || report.Testcases != 4 {
+		t.Fatalf("dry run gave %+v, %+v, %v", list, report, err)
+	}
+	if list, _, err = admin.ReplaceTestcases(ctx, question.ID, testcases(t, "a", "b", "c", "d"), false, false); err != nil || len(list) != 4 {
+		t.Fatalf("replaced by %+v, %v", list, err)
+	}
+	if fetched, err := admin.GetQuestion(ctx, question.ID); err != nil || fetched.NumTestcases != 4 {
+		t.Fatalf("got %+v, %v", fetched, err)
+	}
+}
+
+func TestSubmissions(t *testing.T) {
+	ctx := context.Background()
+	anonymous, admin := newServer(t)
+	python, err := admin.CreateLanguage(ctx, client.LanguageInput{Name: "Python", Time: 1, Filename: "main.py", Execute: client.Command{Argv: []string{"python3", "{src}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruby, err := admin.CreateLanguage(ctx, client.LanguageInput{Name: "Ruby", Time: 2, Filename: "main.rb", Execute: client.Command{Argv: []string{"ruby", "{src}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	question, _, err := admin.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: testcases(t, "1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	languages := []string{python.ID}
+	if _, err = admin.UpdateQuestion(ctx, question.ID, client.QuestionPatch{Languages: &languages}); err != nil {
+		t.Fatal(err)
+	}
+	allowed, err := anonymous.QuestionLanguages(ctx, question.ID, "")
+	if err != nil || len(allowed) != 1 || allowed[0].ID != python.ID || allowed[0].Time != 2 {
+		t.Fatalf("languages %+v, %v", allowed, err)
+	}
+	if _, err = anonymous.CheckQuestionLanguage(ctx, question.ID, python.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err = anonymous.CheckQuestionLanguage(ctx, question.ID, ruby.ID, "")
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	_, err = anonymous.CreateSubmission(ctx, client.SubmissionInput{QuestionID: question.ID, LanguageID: ruby.ID, Source: "puts gets"})
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+
+	submission, err := anonymous.CreateSubmission(ctx, client.SubmissionInput{QuestionID: question.ID, LanguageID: python.ID, Source: "print(input())"})
+	if err != nil || submission.Verdict != client.VerdictQueued {
+		t.Fatalf("submitted %+v, %v", submission, err)
+	}
+	if fetched, err := anonymous.GetSubmission(ctx, submission.ID); err != nil || fetched.ID != submission.ID {
+		t.Fatalf("got %+v, %v", fetched, err)
+	}
+	_, err = anonymous.GetSubmission(ctx, "nope")
+	expectError(t, err, http.StatusNotFound, client.CodeNotFound)
+
+	// Rejudges and workers are left to admins
+	_, err = anonymous.CreateRejudge(ctx, client.RejudgeRequest{QuestionID: question.ID})
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	_, err = admin.CreateRejudge(ctx, client.RejudgeRequest{})
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	// Submissions still waiting for a worker are not sent back again
+	created, err := admin.CreateRejudge(ctx, client.RejudgeRequest{QuestionID: question.ID})
+	if err != nil || created.Submissions != 0 {
+		t.Fatalf("rejudging %+v, %v", created, err)
+	}
+	status, err := admin.GetRejudge(ctx, created.ID)
+	if err != nil || status.Rejudge.ID != created.ID || status.Pending != 0 {
+		t.Fatalf("rejudge %+v, %v", status, err)
+	}
+	_, err = anonymous.ListWorkers(ctx)
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	workers, err := admin.ListWorkers(ctx)
+	if err != nil || len(workers.Workers) != 0 || len(workers.Uncovered) != 2 {
+		t.Fatalf("workers %+v, %v", workers, err)
+	}
+}
+
+func TestContests(t *testing.T) {
+	ctx := context.Background()
+	anonymous, admin := newServer(t)
+	python, err := admin.CreateLanguage(ctx, client.LanguageInput{Name: "Python", Time: 1, Filename: "main.py", Execute: client.Command{Argv: []string{"python3", "{src}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruby, err := admin.CreateLanguage(ctx, client.LanguageInput{Name: "Ruby", Time: 2, Filename: "main.rb", Execute: client.Command{Argv: []string{"ruby", "{src}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	question, _, err := admin.CreateQuestion(ctx, client.NewQuestion{Name: "Echo", Time: 2, Archive: testcases(t, "1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := client.ContestInput{Name: "Qualifier", Questions: []string{question.ID}, Languages: []string{python.ID}}
+	_, err = anonymous.CreateContest(ctx, input)
+	expectError(t, err, http.StatusUnauthorized, client.CodeUnauthorized)
+	contest, err := admin.CreateContest(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contests, err := anonymous.ListContests(ctx); err != nil || len(contests) != 1 || contests[0].ID != contest.ID {
+		t.Fatalf("contests %+v, %v", contests, err)
+	}
+
+	allowed, err := anonymous.QuestionLanguages(ctx, question.ID, contest.ID)
+	if err != nil || len(allowed) != 1 || allowed[0].ID != python.ID {
+		t.Fatalf("languages %+v, %v", allowed, err)
+	}
+	_, err = anonymous.CheckQuestionLanguage(ctx, question.ID, ruby.ID, contest.ID)
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	_, err = anonymous.CreateSubmission(ctx, client.SubmissionInput{QuestionID: question.ID, LanguageID: ruby.ID, ContestID: contest.ID, Source: "puts gets"})
+	expectError(t, err, http.StatusBadRequest, client.CodeInvalidRequest)
+	submission, err := anonymous.CreateSubmission(ctx, client.SubmissionInput{QuestionID: question.ID, LanguageID: python.ID, ContestID: contest.ID, Source: "print(input())"})
+	if err != nil || submission.ContestID != contest.ID {
+		t.Fatalf("submitted %+v, %v", submission, err)
+	}
+
+	name := "Final"
+	if updated, err := admin.UpdateContest(ctx, contest.ID, client.ContestPatch{Name: &name}); err != nil || updated.Name != name {
+		t.Fatalf("updated %+v, %v", updated, err)
+	}
+	err = admin.DeleteContest(ctx, contest.ID)
+	expectError(t, err, http.StatusConflict, client.CodeConflict)
+}
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	anonymous, _ := newServer(t)
+	if health, err := anonymous.Health(ctx); err != nil || health.Status == "" {
+		t.Fatalf("health %+v, %v", health, err)
+	}
+
+	// Memory stores have no database to be ready with
+	health, err := anonymous.Ready(ctx)
+	expectError(t, err, http.StatusServiceUnavailable, client.CodeUnavailable)
+	if len(health.Components) == 0 {
+		t.Fatal("no components checked")
+	}
+}
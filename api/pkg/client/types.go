@@ -0,0 +1,352 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Language : Programming language submissions can be written in. Command
+// arguments may use the {src}, {workdir}, {binary} and {memlimit}
+// placeholders.
+type Language struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Time      int            `json:"time"`
+	Filename  string         `json:"filename"`
+	Compile   *Command       `json:"compile"`
+	Execute   Command        `json:"execute"`
+	Artifacts []string       `json:"artifacts"`
+	Limits    LanguageLimits `json:"limits"`
+	Probe     *LanguageProbe `json:"probe,omitempty"`
+	SelfTest  *SelfTest      `json:"self_test,omitempty"`
+	Disabled  bool           `json:"disabled"`
+	Archived  bool           `json:"archived"`
+	DeletedAt *time.Time     `json:"deleted_at,omitempty"`
+}
+
+// Command : Argument list run without a shell and extra environment
+type Command struct {
+	Argv []string          `json:"argv"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// LanguageLimits : Time in seconds and memory in megabytes of the compile
+// step, and memory and output in megabytes of the run step. Zero picks the
+// default.
+type LanguageLimits struct {
+	CompileTime   int `json:"compile_time"`
+	CompileMemory int `json:"compile_memory"`
+	Memory        int `json:"memory"`
+	Output        int `json:"output"`
+}
+
+// LanguageProbe : Sample program checking that a language works, it must
+// print back whatever it reads on its standard input
+type LanguageProbe struct {
+	Source  string   `json:"source"`
+	Version []string `json:"version,omitempty"`
+}
+
+// SelfTest : Outcome of compiling and running the probe of a language
+type SelfTest struct {
+	Passed    bool      `json:"passed"`
+	Verdict   string    `json:"verdict"`
+	Version   string    `json:"version,omitempty"`
+	Output    string    `json:"output,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// LanguageInput : Fields of a new language, Compile is nil for
+// interpreted languages. A language failing its probe is created
+// disabled.
+type LanguageInput struct {
+	Name      string         `json:"name"`
+	Time      int            `json:"time"`
+	Filename  string         `json:"filename"`
+	Compile   *Command       `json:"compile,omitempty"`
+	Execute   Command        `json:"execute"`
+	Artifacts []string       `json:"artifacts,omitempty"`
+	Limits    LanguageLimits `json:"limits"`
+	Probe     *LanguageProbe `json:"probe,omitempty"`
+	Disabled  bool           `json:"disabled,omitempty"`
+}
+
+// LanguagePatch : Fields of a language to change, nil ones are kept. A
+// compile command with no arguments removes the compile step.
+type LanguagePatch struct {
+	Name      *string         `json:"name,omitempty"`
+	Time      *int            `json:"time,omitempty"`
+	Filename  *string         `json:"filename,omitempty"`
+	Compile   *Command        `json:"compile,omitempty"`
+	Execute   *Command        `json:"execute,omitempty"`
+	Artifacts *[]string       `json:"artifacts,omitempty"`
+	Limits    *LanguageLimits `json:"limits,omitempty"`
+	Probe     *LanguageProbe  `json:"probe,omitempty"`
+	Disabled  *bool           `json:"disabled,omitempty"`
+	Archived  *bool           `json:"archived,omitempty"`
+}
+
+// Question : Problem with its time limit in seconds and its testcases
+type Question struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Time         int        `json:"time"`
+	NumTestcases int        `json:"num_testcases"`
+	Testcases    []Testcase `json:"testcases"`
+	Archived     bool       `json:"archived"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+
+	// Languages submissions may use, every enabled language when empty,
+	// and time limits replacing Time times the language multiplier
+	Languages     []string       `json:"languages,omitempty"`
+	TimeOverrides []TimeOverride `json:"time_overrides,omitempty"`
+}
+
+// TimeOverride : Time limit in seconds of a question for one language
+type TimeOverride struct {
+	LanguageID string `json:"lang_id"`
+	Time       int    `json:"time"`
+}
+
+// QuestionPatch : Fields of a question to change, nil ones are kept. An
+// empty Languages allows every language again.
+type QuestionPatch struct {
+	Name          *string         `json:"name,omitempty"`
+	Time          *int            `json:"time,omitempty"`
+	Archived      *bool           `json:"archived,omitempty"`
+	Languages     *[]string       `json:"languages,omitempty"`
+	TimeOverrides *[]TimeOverride `json:"time_overrides,omitempty"`
+}
+
+// QuestionLanguage : Language a question accepts, with the time limit in
+// seconds solutions in it get per testcase
+type QuestionLanguage struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Filename string `json:"filename"`
+	Time     int    `json:"time"`
+	Override bool   `json:"override"`
+}
+
+// Contest : Questions given together, whose submissions may only use the
+// languages of the contest, every language when empty
+type Contest struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Questions []string `json:"questions"`
+	Languages []string `json:"languages,omitempty"`
+}
+
+// ContestInput : Fields of a new contest
+type ContestInput struct {
+	Name      string   `json:"name"`
+	Questions []string `json:"questions,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+}
+
+// ContestPatch : Fields of a contest to change, nil ones are kept
+type ContestPatch struct {
+	Name      *string   `json:"name,omitempty"`
+	Questions *[]string `json:"questions,omitempty"`
+	Languages *[]string `json:"languages,omitempty"` // Empty to allow every language again
+}
+
+// Testcase : Position and labels of a single testcase
+type Testcase struct {
+	Index       int    `json:"index"`
+	Number      int    `json:"number"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ArchiveIssue : Problem found with one entry of a testcase archive
+type ArchiveIssue struct {
+	Entry  string `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// ArchiveReport : Outcome of validating a testcase archive
+type ArchiveReport struct {
+	Valid     bool           `json:"valid"`
+	Layout    string         `json:"layout"`
+	Root      string         `json:"root,omitempty"`
+	Testcases int            `json:"testcases"`
+	Mapping   []Testcase     `json:"mapping"`
+	Errors    []ArchiveIssue `json:"errors"`
+	Warnings  []ArchiveIssue `json:"warnings"`
+}
+
+// Archive : Testcase archive to upload. Name only needs the right
+// extension, .zip, .tar.gz or .tar.xz.
+type Archive struct {
+	Name    string
+	Content io.Reader
+}
+
+// NewQuestion : Question to create from an archive. Name and Time may be
+// left empty when the archive has a manifest carrying them.
+type NewQuestion struct {
+	Name    string
+	Time    int
+	Archive Archive
+	DryRun  bool // Only validate the archive
+}
+
+// TestcaseFiles : Files and labels of a single testcase. Nil files and
+// empty labels are kept as they are when updating a testcase.
+type TestcaseFiles struct {
+	Input       io.Reader
+	Output      io.Reader
+	Name        string
+	Description string
+}
+
+// SubmissionInput : Solution to a question in one of its languages,
+// within a contest holding the question when ContestID is set
+type SubmissionInput struct {
+	QuestionID string `json:"ques_id"`
+	LanguageID string `json:"lang_id"`
+	ContestID  string `json:"contest_id,omitempty"`
+	Source     string `json:"source"`
+}
+
+// Submission : Solution and its verdict, QU while it waits for a worker
+type Submission struct {
+	ID         string           `json:"id"`
+	LanguageID string           `json:"lang_id"`
+	QuestionID string           `json:"ques_id"`
+	ContestID  string           `json:"contest_id,omitempty"`
+	Verdict    string           `json:"verdict"`
+	Testcases  map[int]string   `json:"testcases"` // Verdict by display index
+	Results    []TestcaseResult `json:"results,omitempty"`
+}
+
+// TestcaseResult : Outcome of a submission on one testcase
+type TestcaseResult struct {
+	Index   int    `json:"index"`
+	Number  int    `json:"number"`
+	Verdict string `json:"verdict"`
+	Time    int64  `json:"time_ms"`
+}
+
+// VerdictQueued : Verdict of a submission no worker judged yet
+const VerdictQueued = "QU"
+
+// RejudgeRequest : Filter picking the submissions to rejudge, at least one
+// field is required
+type RejudgeRequest struct {
+	SubmissionID string     `json:"submission_id,omitempty"`
+	QuestionID   string     `json:"question_id,omitempty"`
+	Verdict      string     `json:"verdict,omitempty"`
+	Since        *time.Time `json:"since,omitempty"`
+}
+
+// RejudgeCreated : Rejudge that was just started
+type RejudgeCreated struct {
+	ID          string `json:"id"`
+	Submissions int    `json:"submissions"`
+}
+
+// Rejudge : Batch of submissions sent back to the judge together
+type Rejudge struct {
+	ID          string         `json:"id"`
+	Filter      RejudgeRequest `json:"filter"`
+	Submissions []string       `json:"submissions"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Queued      bool           `json:"queued"`
+}
+
+// VerdictChange : Submission whose verdict differs after a rejudge
+type VerdictChange struct {
+	SubmissionID string `json:"submission_id"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+}
+
+// RejudgeStatus : Progress of a rejudge and the verdicts it changed
+type RejudgeStatus struct {
+	Rejudge   Rejudge         `json:"rejudge"`
+	Pending   int             `json:"pending"`
+	Unchanged int             `json:"unchanged"`
+	Changed   []VerdictChange `json:"changed"`
+}
+
+// Worker : Judge process and the languages it passed the self-test of
+type Worker struct {
+	ID          string    `json:"id"`
+	Host        string    `json:"host"`
+	Slots       int       `json:"slots"`
+	Languages   []string  `json:"languages"`
+	Failed      []string  `json:"failed"` // Languages whose probe failed on the worker
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// LanguageCoverage : Live workers able to run a language
+type LanguageCoverage struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Disabled bool     `json:"disabled"`
+	Probe    bool     `json:"probe"`
+	Workers  []string `json:"workers"`
+	Failing  []string `json:"failing"` // Live workers the probe failed on
+}
+
+// Workers : Live workers and the languages they cover
+type Workers struct {
+	Workers   []Worker           `json:"workers"`
+	Languages []LanguageCoverage `json:"languages"`
+	Uncovered []LanguageCoverage `json:"uncovered"`
+}
+
+// ComponentHealth : Result of a single health check
+type ComponentHealth struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Health : Status of the server and of its dependencies
+type Health struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components,omitempty"`
+}
+
+// Error : Failure reported by the server
+type Error struct {
+	StatusCode int               `json:"-"`
+	Code       string            `json:"code"`
+	Message    string            `json:"message"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Report     *ArchiveReport    `json:"report,omitempty"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	if len(e.Fields) > 0 {
+		var fields []string
+		for field, reason := range e.Fields {
+			fields = append(fields, field+": "+reason)
+		}
+		sort.Strings(fields)
+		message += " (" + strings.Join(fields, "; ") + ")"
+	}
+	return message
+}
+
+// Error codes sent by the server
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)